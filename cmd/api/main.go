@@ -19,8 +19,10 @@ import (
 	"ecommerce-gin/internal/cache"
 	"ecommerce-gin/internal/config"
 	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/middleware"
 	"ecommerce-gin/internal/routes"
 	"ecommerce-gin/internal/services"
+	"ecommerce-gin/internal/utils"
 	"fmt"
 
 	"github.com/gin-gonic/gin"
@@ -35,20 +37,36 @@ func main() {
 	// Load configuration
 	config.LoadConfig()
 
+	// Load JWT signing keys
+	utils.InitKeyring()
+
+	// Load password policy
+	services.InitPasswordPolicy()
+
 	// Connect DB and automigrate
 	database.Connect()
 
+	// Build the product search index
+	services.InitSearch()
+
 	// Initialize S3 client
 	services.InitS3()
 
+	// Initialize mailer
+	services.InitMailer()
+
 	// Connect Redis
 	cache.Connect()
 
+	// Configure social login providers
+	services.InitOIDC()
+
 	// Gin setup
 	if config.Cfg.AppEnv == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	r := gin.Default()
+	r.Use(middleware.RequestID())
 
 	r.GET("/pay-gateway", func(c *gin.Context) {
 		intent := c.Query("intent")
@@ -60,12 +78,23 @@ func main() {
 		})
 	})
 
+	// Built-in OIDC provider for trying social login offline
+	if services.MockOIDC != nil {
+		r.Any("/oidc-mock/*path", gin.WrapH(services.MockOIDC))
+	}
+
 	// Setup routes
 	api := routes.SetupRoutes(r)
 	routes.RegisterProductRoutes(r, api)
+	routes.RegisterCategoryRoutes(r, api)
 	routes.RegisterCartRoutes(r, api)
 	routes.RegisterOrderRoutes(r, api)
+	routes.RegisterReviewRoutes(r, api)
 	routes.RegisterAdminOrderRoutes(r, api)
+	routes.RegisterAdminUserRoutes(r, api)
+	routes.RegisterAdminRoleRoutes(r, api)
+	routes.RegisterAdminAPIKeyRoutes(r, api)
+	routes.RegisterAdminAuditRoutes(r, api)
 	routes.RegisterPaymentRoutes(r, api)
 	routes.RegisterUploadRoutes(r, api)
 
@@ -4,30 +4,78 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	AppEnv             string
-	Port               string
-	DBHost             string
-	DBPort             string
-	DBUser             string
-	DBPassword         string
-	DBName             string
-	JWTSecret          string
-	AccessTokenMinutes int
-	RefreshTokenDays   int
-	S3Endpoint         string `mapstructure:"S3_ENDPOINT"`
-	S3Key              string `mapstructure:"S3_KEY"`
-	S3Secret           string `mapstructure:"S3_SECRET"`
-	S3Bucket           string `mapstructure:"S3_BUCKET"`
-	S3Region           string `mapstructure:"S3_REGION"`
-	S3PublicURL        string `mapstructure:"S3_PUBLIC_URL"`
-	RedisHost          string `mapstructure:"REDIS_HOST"`
-	RedisPort          string `mapstructure:"REDIS_PORT"`
-	RedisPassword      string `mapstructure:"REDIS_PASSWORD"`
+	AppEnv               string
+	Port                 string
+	DBHost               string
+	DBPort               string
+	DBUser               string
+	DBPassword           string
+	DBName               string
+	JWTSecret            string
+	JWTAlgorithm         string
+	JWTKeysDir           string
+	JWTActiveKID         string
+	JWTLegacyHS256       bool
+	AccessTokenMinutes   int
+	RefreshTokenDays     int
+	S3Endpoint           string `mapstructure:"S3_ENDPOINT"`
+	S3Key                string `mapstructure:"S3_KEY"`
+	S3Secret             string `mapstructure:"S3_SECRET"`
+	S3Bucket             string `mapstructure:"S3_BUCKET"`
+	S3Region             string `mapstructure:"S3_REGION"`
+	S3PublicURL          string `mapstructure:"S3_PUBLIC_URL"`
+	RedisHost            string `mapstructure:"REDIS_HOST"`
+	RedisPort            string `mapstructure:"REDIS_PORT"`
+	RedisPassword        string `mapstructure:"REDIS_PASSWORD"`
+	AppBaseURL           string
+	FrontendBaseURL      string
+	MailDriver           string
+	MailFrom             string
+	MailOutboxDir        string
+	SMTPHost             string
+	SMTPPort             string
+	SMTPUsername         string
+	SMTPPassword         string
+	VerifyEmailHours     int
+	PasswordResetMins    int
+	RequireAdmin2FA      bool
+	TOTPIssuer           string
+	LoginMaxFailures     int
+	LoginLockMinutes     int
+	LoginBackoffAfter    int
+	LoginIPBackoffAfter  int
+	OIDCProviders        []OIDCProvider
+	OIDCMockEnabled      bool
+	PasswordHasher       string
+	BcryptCost           int
+	Argon2Memory         int // KiB
+	Argon2Iterations     int
+	Argon2Parallelism    int
+	PasswordMinLength    int
+	PasswordBreachedFile string
+	ImpersonationMinutes int
+	MagicLinkMinutes     int
+	WebAuthnRPID         string
+	WebAuthnRPName       string
+	WebAuthnOrigin       string
+	SearchRefreshMinutes int
+	ImportMaxMB          int
+	ImportSyncRows       int
+}
+
+// OIDCProvider is an external identity provider used for social login
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
 }
 
 var Cfg Config
@@ -48,32 +96,128 @@ func LoadConfig() {
 	}
 
 	Cfg = Config{
-		AppEnv:             getEnv("APP_ENV", "development"),
-		Port:               getEnv("PORT", "8080"),
-		DBHost:             getEnv("DB_HOST", "127.0.0.1"),
-		DBPort:             getEnv("DB_PORT", "3306"),
-		DBUser:             getEnv("DB_USER", "root"),
-		DBPassword:         getEnv("DB_PASSWORD", ""),
-		DBName:             getEnv("DB_NAME", "ecommerce"),
-		JWTSecret:          getEnv("JWT_SECRET", "super-secret"),
-		AccessTokenMinutes: accessMin,
-		RefreshTokenDays:   refreshDays,
-		S3Endpoint:         getEnv("S3_ENDPOINT", ""),
-		S3Key:              getEnv("S3_KEY", ""),
-		S3Secret:           getEnv("S3_SECRET", ""),
-		S3Bucket:           getEnv("S3_BUCKET", ""),
-		S3Region:           getEnv("S3_REGION", "auto"),
-		S3PublicURL:        getEnv("S3_PUBLIC_URL", ""),
-		RedisHost:          getEnv("REDIS_HOST", "localhost"),
-		RedisPort:          getEnv("REDIS_PORT", "6379"),
-		RedisPassword:      getEnv("REDIS_PASSWORD", ""),
+		AppEnv:               getEnv("APP_ENV", "development"),
+		Port:                 getEnv("PORT", "8080"),
+		DBHost:               getEnv("DB_HOST", "127.0.0.1"),
+		DBPort:               getEnv("DB_PORT", "3306"),
+		DBUser:               getEnv("DB_USER", "root"),
+		DBPassword:           getEnv("DB_PASSWORD", ""),
+		DBName:               getEnv("DB_NAME", "ecommerce"),
+		JWTSecret:            getEnv("JWT_SECRET", "super-secret"),
+		JWTAlgorithm:         getEnv("JWT_ALGORITHM", "HS256"),
+		JWTKeysDir:           getEnv("JWT_KEYS_DIR", ""),
+		JWTActiveKID:         getEnv("JWT_ACTIVE_KID", ""),
+		JWTLegacyHS256:       getEnv("JWT_LEGACY_HS256", "false") == "true",
+		AccessTokenMinutes:   accessMin,
+		RefreshTokenDays:     refreshDays,
+		S3Endpoint:           getEnv("S3_ENDPOINT", ""),
+		S3Key:                getEnv("S3_KEY", ""),
+		S3Secret:             getEnv("S3_SECRET", ""),
+		S3Bucket:             getEnv("S3_BUCKET", ""),
+		S3Region:             getEnv("S3_REGION", "auto"),
+		S3PublicURL:          getEnv("S3_PUBLIC_URL", ""),
+		RedisHost:            getEnv("REDIS_HOST", "localhost"),
+		RedisPort:            getEnv("REDIS_PORT", "6379"),
+		RedisPassword:        getEnv("REDIS_PASSWORD", ""),
+		AppBaseURL:           getEnv("APP_BASE_URL", "http://localhost:8080"),
+		FrontendBaseURL:      strings.TrimSuffix(getEnv("FRONTEND_BASE_URL", "http://localhost:3000"), "/"),
+		MailDriver:           getEnv("MAIL_DRIVER", "outbox"),
+		MailFrom:             getEnv("MAIL_FROM", "no-reply@ecommerce.local"),
+		MailOutboxDir:        getEnv("MAIL_OUTBOX_DIR", "./outbox"),
+		SMTPHost:             getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:             getEnv("SMTP_PORT", "1025"),
+		SMTPUsername:         getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:         getEnv("SMTP_PASSWORD", ""),
+		VerifyEmailHours:     getEnvInt("VERIFY_EMAIL_HOURS", 48),
+		PasswordResetMins:    getEnvInt("PASSWORD_RESET_MINUTES", 30),
+		RequireAdmin2FA:      getEnv("REQUIRE_ADMIN_2FA", "false") == "true",
+		TOTPIssuer:           getEnv("TOTP_ISSUER", "E-Commerce"),
+		LoginMaxFailures:     getEnvInt("LOGIN_MAX_FAILURES", 10),
+		LoginLockMinutes:     getEnvInt("LOGIN_LOCK_MINUTES", 15),
+		LoginBackoffAfter:    getEnvInt("LOGIN_BACKOFF_AFTER", 3),
+		LoginIPBackoffAfter:  getEnvInt("LOGIN_IP_BACKOFF_AFTER", 20),
+		OIDCProviders:        loadOIDCProviders(),
+		OIDCMockEnabled:      getEnv("OIDC_MOCK_ENABLED", "false") == "true",
+		PasswordHasher:       getEnv("PASSWORD_HASHER", "argon2id"),
+		BcryptCost:           getEnvInt("BCRYPT_COST", 10),
+		Argon2Memory:         getEnvInt("ARGON2_MEMORY_KIB", 64*1024),
+		Argon2Iterations:     getEnvInt("ARGON2_ITERATIONS", 3),
+		Argon2Parallelism:    getEnvInt("ARGON2_PARALLELISM", 2),
+		PasswordMinLength:    getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordBreachedFile: getEnv("PASSWORD_BREACHED_FILE", ""),
+		ImpersonationMinutes: getEnvInt("IMPERSONATION_MINUTES", 10),
+		MagicLinkMinutes:     getEnvInt("MAGIC_LINK_MINUTES", 15),
+		WebAuthnRPID:         getEnv("WEBAUTHN_RP_ID", ""),
+		WebAuthnRPName:       getEnv("WEBAUTHN_RP_NAME", "E-Commerce"),
+		WebAuthnOrigin:       getEnv("WEBAUTHN_ORIGIN", ""),
+		SearchRefreshMinutes: getEnvInt("SEARCH_REFRESH_MINUTES", 10),
+		ImportMaxMB:          getEnvInt("IMPORT_MAX_MB", 20),
+		ImportSyncRows:       getEnvInt("IMPORT_SYNC_ROWS", 500),
 	}
+	validatePasswordHashing(&Cfg)
 	log.Println("Config loaded")
 }
 
+// validatePasswordHashing stops startup on hashing settings that would make every
+// signup and login fail, or silently wrap when cast for argon2
+func validatePasswordHashing(cfg *Config) {
+	if cfg.PasswordHasher != "argon2id" && cfg.PasswordHasher != "bcrypt" {
+		log.Fatalf("PASSWORD_HASHER must be argon2id or bcrypt, got %q", cfg.PasswordHasher)
+	}
+	if cfg.BcryptCost < 4 || cfg.BcryptCost > 31 {
+		log.Fatalf("BCRYPT_COST must be from 4 to 31, got %d", cfg.BcryptCost)
+	}
+	if cfg.Argon2Parallelism < 1 || cfg.Argon2Parallelism > 255 {
+		log.Fatalf("ARGON2_PARALLELISM must be from 1 to 255, got %d", cfg.Argon2Parallelism)
+	}
+	if cfg.Argon2Iterations < 1 || cfg.Argon2Iterations > 1000 {
+		log.Fatalf("ARGON2_ITERATIONS must be from 1 to 1000, got %d", cfg.Argon2Iterations)
+	}
+	// argon2 needs 8 KiB per lane; 4 GiB keeps a single hash from exhausting the host
+	if cfg.Argon2Memory < 8*cfg.Argon2Parallelism || cfg.Argon2Memory > 4*1024*1024 {
+		log.Fatalf("ARGON2_MEMORY_KIB must be from %d (8 per lane) to 4194304, got %d", 8*cfg.Argon2Parallelism, cfg.Argon2Memory)
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if v, ok := os.LookupEnv(key); ok && v != "" {
 		return v
 	}
 	return defaultValue
 }
+
+// loadOIDCProviders reads OIDC_PROVIDERS=google,azure and for each name
+// OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET and OIDC_<NAME>_SCOPES
+func loadOIDCProviders() []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		issuer := getEnv(prefix+"ISSUER", "")
+		clientID := getEnv(prefix+"CLIENT_ID", "")
+		if issuer == "" || clientID == "" {
+			log.Printf("OIDC provider %s needs %sISSUER and %sCLIENT_ID, skipping", name, prefix, prefix)
+			continue
+		}
+		providers = append(providers, OIDCProvider{
+			Name:         name,
+			Issuer:       strings.TrimSuffix(issuer, "/"),
+			ClientID:     clientID,
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		})
+	}
+	return providers
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	v, err := strconv.Atoi(getEnv(key, strconv.Itoa(defaultValue)))
+	if err != nil {
+		log.Printf("Invalid %s, using %d", key, defaultValue)
+		return defaultValue
+	}
+	return v
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminListAPIKeysHandler godoc
+// @Summary List API keys (Admin only)
+// @Description Lists all API keys including revoked ones. The secret part is never returned.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} APIKeyResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/admin/api-keys [get]
+func AdminListAPIKeysHandler(c *gin.Context) {
+	keys, err := database.ListAPIKeys()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load api keys"})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// AdminCreateAPIKeyHandler godoc
+// @Summary Create API key (Admin only)
+// @Description Creates a key for a server-to-server integration. Scopes are permission names and may only include permissions the caller holds. The key is shown once in the response.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key body CreateAPIKeyInput true "API key"
+// @Success 201 {object} CreatedAPIKeyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/admin/api-keys [post]
+func AdminCreateAPIKeyHandler(c *gin.Context) {
+	var body struct {
+		Name          string   `json:"name" binding:"required,max=100"`
+		Scopes        []string `json:"scopes" binding:"required,min=1"`
+		ExpiresInDays int      `json:"expires_in_days" binding:"min=0"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, ok := lookupPermissions(c, body.Scopes); !ok {
+		return
+	}
+	// nobody can hand out more than they have themselves
+	granted, err := services.PermissionsForRole(c.GetString("role"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load permissions"})
+		return
+	}
+	for _, s := range body.Scopes {
+		if !granted[s] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "cannot grant scope " + s})
+			return
+		}
+	}
+
+	uid, _ := contextUint(c, "user_id")
+	raw, prefix, hash, err := services.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate api key"})
+		return
+	}
+	key := &models.APIKey{
+		Name:        body.Name,
+		Prefix:      prefix,
+		KeyHash:     hash,
+		Scopes:      body.Scopes,
+		CreatedByID: uid,
+	}
+	if body.ExpiresInDays > 0 {
+		exp := time.Now().AddDate(0, 0, body.ExpiresInDays)
+		key.ExpiresAt = &exp
+	}
+	if err := database.CreateAPIKey(key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create api key"})
+		return
+	}
+
+	recordAudit(c, auditEntry{
+		Action:     "api_key.create",
+		EntityType: "api_key",
+		EntityID:   key.ID,
+		After:      gin.H{"name": key.Name, "scopes": key.Scopes, "expires_at": key.ExpiresAt},
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"key": raw, "api_key": key})
+}
+
+// AdminRevokeAPIKeyHandler godoc
+// @Summary Revoke API key (Admin only)
+// @Description Revokes an API key; requests using it are rejected right away
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "API key ID"
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/api-keys/{id} [delete]
+func AdminRevokeAPIKeyHandler(c *gin.Context) {
+	ok, err := database.RevokeAPIKey(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke api key"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+		return
+	}
+	recordAudit(c, auditEntry{Action: "api_key.revoke", EntityType: "api_key", EntityID: c.Param("id")})
+
+	c.JSON(http.StatusOK, gin.H{"message": "api key revoked"})
+}
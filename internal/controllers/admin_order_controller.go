@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -150,6 +151,19 @@ func AdminUpdateOrderStatusHandler(c *gin.Context) {
 		}
 	}
 
+	// reviews the customer already wrote of these products become verified purchases
+	if next == "delivered" {
+		productIDs := make([]uint, 0, len(order.OrderItems))
+		for _, item := range order.OrderItems {
+			productIDs = append(productIDs, item.ProductID)
+		}
+		if err := database.MarkVerifiedPurchases(tx, order.UserID, productIDs); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update reviews"})
+			return
+		}
+	}
+
 	// Update order status
 	if err := database.UpdateOrderStatus(tx, id, next); err != nil {
 		tx.Rollback()
@@ -162,6 +176,22 @@ func AdminUpdateOrderStatusHandler(c *gin.Context) {
 		return
 	}
 
+	if next == "cancelled" {
+		productIDs := make([]uint, 0, len(order.OrderItems))
+		for _, item := range order.OrderItems {
+			productIDs = append(productIDs, item.ProductID)
+		}
+		services.ReindexProducts(productIDs...)
+	}
+
+	recordAudit(c, auditEntry{
+		Action:     "order.status_change",
+		EntityType: "order",
+		EntityID:   id,
+		Before:     gin.H{"status": current},
+		After:      gin.H{"status": next},
+	})
+
 	// (Optional) send notification to user here (email / push)
 
 	c.JSON(http.StatusOK, gin.H{"message": "status updated", "status": next})
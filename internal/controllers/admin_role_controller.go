@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"net/http"
+	"regexp"
+
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,63}$`)
+
+// AdminListRolesHandler godoc
+// @Summary List roles (Admin only)
+// @Description Lists all roles with their permissions
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} RoleResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/admin/roles [get]
+func AdminListRolesHandler(c *gin.Context) {
+	roles, err := database.ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load roles"})
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// AdminListPermissionsHandler godoc
+// @Summary List permissions (Admin only)
+// @Description Lists every permission that can be granted to a role
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} PermissionResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/admin/permissions [get]
+func AdminListPermissionsHandler(c *gin.Context) {
+	perms, err := database.ListPermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load permissions"})
+		return
+	}
+	c.JSON(http.StatusOK, perms)
+}
+
+// AdminCreateRoleHandler godoc
+// @Summary Create role (Admin only)
+// @Description Creates a custom role with a set of permissions
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param role body CreateRoleInput true "Role"
+// @Success 201 {object} RoleResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/admin/roles [post]
+func AdminCreateRoleHandler(c *gin.Context) {
+	var body struct {
+		Name        string   `json:"name" binding:"required"`
+		Description string   `json:"description"`
+		Permissions []string `json:"permissions"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !roleNamePattern.MatchString(body.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role name must be lowercase letters, digits, - or _"})
+		return
+	}
+
+	existing, err := database.GetRoleByName(body.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "role already exists"})
+		return
+	}
+
+	perms, ok := lookupPermissions(c, body.Permissions)
+	if !ok {
+		return
+	}
+
+	role := &models.Role{Name: body.Name, Description: body.Description, Permissions: perms}
+	if err := database.CreateRole(role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create role"})
+		return
+	}
+
+	recordAudit(c, auditEntry{Action: "role.create", EntityType: "role", EntityID: role.Name, After: gin.H{"permissions": body.Permissions}})
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// AdminSetRolePermissionsHandler godoc
+// @Summary Set role permissions (Admin only)
+// @Description Replaces the permissions of a role. The admin role always has every permission.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param name path string true "Role name"
+// @Param permissions body SetRolePermissionsInput true "Permissions"
+// @Success 200 {object} RoleResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/roles/{name}/permissions [put]
+func AdminSetRolePermissionsHandler(c *gin.Context) {
+	var body struct {
+		Permissions []string `json:"permissions"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := c.Param("name")
+	if name == "admin" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the admin role cannot be changed"})
+		return
+	}
+
+	role, err := database.GetRoleByName(name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if role == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
+		return
+	}
+
+	perms, ok := lookupPermissions(c, body.Permissions)
+	if !ok {
+		return
+	}
+	if err := database.SetRolePermissions(role, perms); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update role"})
+		return
+	}
+	services.InvalidateRolePermissions()
+
+	oldPerms := make([]string, 0, len(role.Permissions))
+	for _, p := range role.Permissions {
+		oldPerms = append(oldPerms, p.Name)
+	}
+	recordAudit(c, auditEntry{
+		Action:     "role.update",
+		EntityType: "role",
+		EntityID:   role.Name,
+		Before:     gin.H{"permissions": oldPerms},
+		After:      gin.H{"permissions": body.Permissions},
+	})
+
+	role.Permissions = perms
+	c.JSON(http.StatusOK, role)
+}
+
+// lookupPermissions resolves permission names, answering 400 for unknown ones
+func lookupPermissions(c *gin.Context, names []string) ([]models.Permission, bool) {
+	if len(names) == 0 {
+		return []models.Permission{}, true
+	}
+	perms, err := database.GetPermissionsByNames(names)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return nil, false
+	}
+	known := map[string]bool{}
+	for _, p := range perms {
+		known[p.Name] = true
+	}
+	for _, n := range names {
+		if !known[n] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown permission " + n})
+			return nil, false
+		}
+	}
+	return perms, true
+}
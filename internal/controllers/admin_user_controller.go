@@ -0,0 +1,385 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"ecommerce-gin/internal/config"
+
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
+	"ecommerce-gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminListUsersHandler godoc
+// @Summary List users (Admin only)
+// @Description Gets a paginated list of users, optionally searched by email or name and filtered by role
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param search query string false "Search in email and name"
+// @Param role query string false "Filter by role"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} AdminUserListResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/admin/users [get]
+func AdminListUsersHandler(c *gin.Context) {
+	limit := parseIntQuery(c, "limit", 20)
+	if limit > 100 {
+		limit = 100
+	}
+	page := parseIntQuery(c, "page", 1)
+	offset := (page - 1) * limit
+
+	users, total, err := database.AdminListUsers(limit, offset, c.Query("search"), c.Query("role"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": users,
+		"meta": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// AdminGetUserHandler godoc
+// @Summary Get user by ID (Admin only)
+// @Description Shows a user with their order history, lifetime spend and login lock status
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} AdminUserDetailResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/users/{id} [get]
+func AdminGetUserHandler(c *gin.Context) {
+	user, err := database.GetUserByID(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	orders, err := database.GetOrdersForUser(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load orders"})
+		return
+	}
+	count, spend, err := database.GetUserOrderStats(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load order stats"})
+		return
+	}
+	lockedFor, _ := services.AccountLockedFor(user.Email)
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":               user,
+		"orders":             orders,
+		"order_count":        count,
+		"lifetime_spend":     spend,
+		"locked_for_seconds": int(lockedFor.Seconds()),
+	})
+}
+
+// AdminDisableUserHandler godoc
+// @Summary Disable a user account (Admin only)
+// @Description Blocks the account from logging in and logs it out everywhere, until it is enabled again
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/users/{id}/disable [post]
+func AdminDisableUserHandler(c *gin.Context) {
+	user, err := database.GetUserByID(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if uid, ok := contextUint(c, "user_id"); ok && uid == user.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "you cannot disable your own account"})
+		return
+	}
+
+	now := time.Now()
+	if err := database.SetUserDisabled(user.ID, &now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to disable account"})
+		return
+	}
+	if err := services.MarkUserDisabled(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to disable account"})
+		return
+	}
+	if err := revokeUserAccess(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke tokens"})
+		return
+	}
+
+	database.CreateSecurityEvent(&models.SecurityEvent{
+		UserID:    user.ID,
+		Type:      "account_disabled",
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	recordAudit(c, auditEntry{Action: "user.disable", EntityType: "user", EntityID: user.ID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "account disabled"})
+}
+
+// AdminEnableUserHandler godoc
+// @Summary Enable a user account (Admin only)
+// @Description Lets a disabled account log in again
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/users/{id}/enable [post]
+func AdminEnableUserHandler(c *gin.Context) {
+	user, err := database.GetUserByID(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	if err := database.SetUserDisabled(user.ID, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enable account"})
+		return
+	}
+	if err := services.MarkUserEnabled(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enable account"})
+		return
+	}
+
+	database.CreateSecurityEvent(&models.SecurityEvent{
+		UserID:    user.ID,
+		Type:      "account_enabled",
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	recordAudit(c, auditEntry{Action: "user.enable", EntityType: "user", EntityID: user.ID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "account enabled"})
+}
+
+// AdminRevokeUserTokensHandler godoc
+// @Summary Force logout a user (Admin only)
+// @Description Immediately invalidates every access token and session of a user
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/users/{id}/logout [post]
+// @Router /api/admin/users/{id}/revoke-tokens [post]
+func AdminRevokeUserTokensHandler(c *gin.Context) {
+	user, err := database.GetUserByID(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	if err := revokeUserAccess(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke tokens"})
+		return
+	}
+	recordAudit(c, auditEntry{Action: "user.force_logout", EntityType: "user", EntityID: user.ID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "tokens revoked"})
+}
+
+// AdminUnlockUserHandler godoc
+// @Summary Unlock a user account (Admin only)
+// @Description Lifts a lockout caused by too many failed logins and resets the failure counter
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/users/{id}/unlock [post]
+func AdminUnlockUserHandler(c *gin.Context) {
+	user, err := database.GetUserByID(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	if err := services.UnlockAccount(user.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unlock account"})
+		return
+	}
+	recordAudit(c, auditEntry{Action: "user.unlock", EntityType: "user", EntityID: user.ID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "account unlocked"})
+}
+
+// AdminImpersonateUserHandler godoc
+// @Summary Impersonate a customer (Admin only)
+// @Description Issues a short-lived access token to see the shop as the customer does. The token carries an act claim naming the admin, cannot check out, pay or change account settings, and every request made with it is audited.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param body body ImpersonateInput true "Reason"
+// @Success 200 {object} ImpersonationResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/users/{id}/impersonate [post]
+func AdminImpersonateUserHandler(c *gin.Context) {
+	var body struct {
+		Reason string `json:"reason" binding:"required,max=500"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actorID, _ := contextUint(c, "user_id")
+	user, err := database.GetUserByID(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if user.ID == actorID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "you cannot impersonate yourself"})
+		return
+	}
+	if user.DisabledAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "account disabled"})
+		return
+	}
+
+	// only plain customers; acting as staff would hand out their permissions
+	perms, err := services.PermissionsForRole(user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load permissions"})
+		return
+	}
+	if len(perms) > 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "staff accounts cannot be impersonated"})
+		return
+	}
+
+	ttl := time.Duration(config.Cfg.ImpersonationMinutes) * time.Minute
+	token, err := utils.GenerateAccessToken(utils.AccessClaims{
+		UserID:  user.ID,
+		Role:    user.Role,
+		ActorID: actorID,
+		TTL:     ttl,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate access token"})
+		return
+	}
+
+	database.CreateSecurityEvent(&models.SecurityEvent{
+		UserID:    user.ID,
+		Type:      "impersonation_started",
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Details:   fmt.Sprintf("by user %d: %s", actorID, body.Reason),
+	})
+	recordAudit(c, auditEntry{
+		Action:     "user.impersonate",
+		EntityType: "user",
+		EntityID:   user.ID,
+		After:      gin.H{"reason": body.Reason, "expires_in": int(ttl.Seconds())},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": token,
+		"expires_in":   int(ttl.Seconds()),
+		"user": gin.H{
+			"id":    user.ID,
+			"email": user.Email,
+			"name":  user.Name,
+		},
+	})
+}
+
+// AdminAssignRoleHandler godoc
+// @Summary Assign role to user (Admin only)
+// @Description Changes a user's role. Every token the user holds is revoked so the new role applies immediately.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param role body AssignRoleInput true "Role"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/users/{id}/role [put]
+func AdminAssignRoleHandler(c *gin.Context) {
+	var body struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := database.GetUserByID(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	role, err := database.GetRoleByName(body.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if role == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown role"})
+		return
+	}
+
+	if err := database.UpdateUserRole(user.ID, role.Name); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update role"})
+		return
+	}
+	if err := revokeUserAccess(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke tokens"})
+		return
+	}
+
+	database.CreateSecurityEvent(&models.SecurityEvent{
+		UserID:    user.ID,
+		Type:      "role_changed",
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Details:   user.Role + " -> " + role.Name,
+	})
+	recordAudit(c, auditEntry{
+		Action:     "user.role_change",
+		EntityType: "user",
+		EntityID:   user.ID,
+		Before:     gin.H{"role": user.Role},
+		After:      gin.H{"role": role.Name},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "role updated", "role": role.Name})
+}
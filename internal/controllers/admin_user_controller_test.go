@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminUnlockUserIsAudited(t *testing.T) {
+	store := testutil.UseDB(t, &database.DB)
+	testutil.UseRedis(t, &cache.Rdb)
+	user := &models.User{Email: "jane@example.com", Name: "Jane"}
+	if err := database.DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Rdb.Set(cache.Ctx, "login:lock:jane@example.com", 1, 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/users/:id/unlock", func(c *gin.Context) { c.Set("user_id", uint(9)) }, AdminUnlockUserHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/1/unlock", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST unlock: %d %s", w.Code, w.Body)
+	}
+
+	if n, _ := cache.Rdb.Exists(cache.Ctx, "login:lock:jane@example.com").Result(); n != 0 {
+		t.Error("account is still locked")
+	}
+	entries := store.Rows("audit_logs")
+	if len(entries) != 1 || entries[0]["action"] != "user.unlock" || entries[0]["entity_id"] != "1" || entries[0]["actor_id"] != int64(9) {
+		t.Errorf("audit entries = %v, want user.unlock of user 1 by admin 9", entries)
+	}
+}
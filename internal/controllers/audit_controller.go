@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"time"
+
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditEntry describes one audited action; recordAudit fills in who, from where
+type auditEntry struct {
+	ActorID    uint // only needed when the actor is not the authenticated caller, e.g. at login
+	Action     string
+	EntityType string
+	EntityID   interface{}
+	Before     interface{}
+	After      interface{}
+}
+
+// recordAudit appends an entry to the audit log. Failures are logged, they never
+// fail the request that is being audited.
+func recordAudit(c *gin.Context, e auditEntry) {
+	entry := &models.AuditLog{
+		ActorType:  "anonymous",
+		Action:     e.Action,
+		EntityType: e.EntityType,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		RequestID:  c.GetString("request_id"),
+	}
+	if e.EntityID != nil {
+		entry.EntityID = fmt.Sprint(e.EntityID)
+	}
+
+	if e.ActorID != 0 {
+		entry.ActorType, entry.ActorID = "user", &e.ActorID
+	} else if actor, ok := contextUint(c, "actor_id"); ok {
+		// impersonation: the staff member is the actor
+		uid, _ := contextUint(c, "user_id")
+		entry.ActorType, entry.ActorID, entry.OnBehalfOf = "user", &actor, &uid
+	} else if uid, ok := contextUint(c, "user_id"); ok {
+		entry.ActorType, entry.ActorID = "user", &uid
+	} else if kid, ok := contextUint(c, "api_key_id"); ok {
+		entry.ActorType, entry.ActorID = "api_key", &kid
+	}
+
+	entry.Before, entry.After = auditDiff(e.Before, e.After)
+
+	if err := database.CreateAuditLog(entry); err != nil {
+		log.Println("failed to write audit log:", err)
+	}
+}
+
+// auditDiff turns before/after values into JSON. When both are given only the
+// fields that are present in after and actually changed are kept.
+func auditDiff(before, after interface{}) (string, string) {
+	if before == nil || after == nil {
+		return auditJSON(before), auditJSON(after)
+	}
+
+	b, a := auditFields(before), auditFields(after)
+	if b == nil || a == nil {
+		return auditJSON(before), auditJSON(after)
+	}
+
+	changedBefore := map[string]interface{}{}
+	changedAfter := map[string]interface{}{}
+	for k, av := range a {
+		if k == "updated_at" || k == "UpdatedAt" {
+			continue
+		}
+		if bv := b[k]; !reflect.DeepEqual(bv, av) {
+			changedBefore[k] = bv
+			changedAfter[k] = av
+		}
+	}
+	if len(changedAfter) == 0 {
+		return "", ""
+	}
+	return auditJSON(changedBefore), auditJSON(changedAfter)
+}
+
+// auditFields converts a struct or map to its JSON field map
+func auditFields(v interface{}) map[string]interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if json.Unmarshal(data, &m) != nil {
+		return nil
+	}
+	return m
+}
+
+func auditJSON(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// auditFilterFromQuery reads the shared filters of the audit log endpoints
+func auditFilterFromQuery(c *gin.Context) (database.AuditLogFilter, bool) {
+	f := database.AuditLogFilter{
+		ActorType:  c.Query("actor_type"),
+		ActorID:    parseUint(c.Query("actor_id")),
+		Action:     c.Query("action"),
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+	}
+	for _, p := range []struct {
+		key string
+		dst **time.Time
+	}{{"from", &f.From}, {"to", &f.To}} {
+		v := c.Query(p.key)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": p.key + " must be an RFC 3339 timestamp"})
+			return f, false
+		}
+		*p.dst = &t
+	}
+	return f, true
+}
+
+// AdminListAuditLogsHandler godoc
+// @Summary Query audit log (Admin only)
+// @Description Lists audit log entries, newest first, filtered by actor, action, entity and time range
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param actor_type query string false "user, api_key or anonymous"
+// @Param actor_id query int false "Actor ID"
+// @Param action query string false "Action, e.g. product.update"
+// @Param entity_type query string false "Entity type, e.g. order"
+// @Param entity_id query string false "Entity ID"
+// @Param from query string false "Start time (RFC 3339)"
+// @Param to query string false "End time (RFC 3339)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(50)
+// @Success 200 {object} AuditLogListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/admin/audit-logs [get]
+func AdminListAuditLogsHandler(c *gin.Context) {
+	f, ok := auditFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	limit := parseIntQuery(c, "limit", 50)
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	page := parseIntQuery(c, "page", 1)
+	if page < 1 {
+		page = 1
+	}
+
+	entries, total, err := database.ListAuditLogs(f, limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": entries,
+		"meta": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// AdminExportAuditLogsHandler godoc
+// @Summary Export audit log (Admin only)
+// @Description Streams every matching entry as newline-delimited JSON, oldest first. Takes the same filters as the list endpoint.
+// @Tags Admin
+// @Security BearerAuth
+// @Produce application/x-ndjson
+// @Param actor_type query string false "user, api_key or anonymous"
+// @Param actor_id query int false "Actor ID"
+// @Param action query string false "Action"
+// @Param entity_type query string false "Entity type"
+// @Param entity_id query string false "Entity ID"
+// @Param from query string false "Start time (RFC 3339)"
+// @Param to query string false "End time (RFC 3339)"
+// @Success 200 {string} string "NDJSON stream"
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/admin/audit-logs/export [get]
+func AdminExportAuditLogsHandler(c *gin.Context) {
+	f, ok := auditFilterFromQuery(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-log-%s.ndjson"`, time.Now().UTC().Format("20060102-150405")))
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	err := database.EachAuditLog(f, func(e models.AuditLog) error {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		// headers are gone already, all we can do is stop the stream
+		log.Println("audit log export aborted:", err)
+	}
+}
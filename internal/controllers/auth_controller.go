@@ -1,12 +1,16 @@
 package controllers
 
 import (
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"ecommerce-gin/internal/config"
 	"ecommerce-gin/internal/database"
 	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
 	"ecommerce-gin/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -14,7 +18,7 @@ import (
 
 // Signup godoc
 // @Summary Register new user
-// @Description Creates a new user account
+// @Description Creates a new user account and sends an email verification link
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -27,13 +31,16 @@ func Signup(c *gin.Context) {
 	var body struct {
 		Name     string `json:"name" binding:"required"`
 		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required,min=6"`
-		Role     string `json:"role"` // optional
+		Password string `json:"password" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
 		return
 	}
+	if err := services.ValidatePassword(body.Password, body.Email); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Check exists
 	existing, err := database.GetUserByEmail(body.Email)
@@ -52,16 +59,11 @@ func Signup(c *gin.Context) {
 		return
 	}
 
-	role := body.Role
-	if role == "" {
-		role = "customer"
-	}
-
 	user := &models.User{
 		Name:     body.Name,
 		Email:    body.Email,
 		Password: hashed,
-		Role:     role,
+		Role:     "customer", // self-registration never grants staff roles
 	}
 
 	if err := database.CreateUser(user); err != nil {
@@ -69,48 +71,173 @@ func Signup(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "user created"})
+	recordAudit(c, auditEntry{ActorID: user.ID, Action: "auth.signup", EntityType: "user", EntityID: user.ID})
+
+	if err := sendVerificationEmail(user, user.Email); err != nil {
+		log.Println("failed to send verification email:", err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "user created, check your email to verify your address"})
 }
 
 // Login godoc
 // @Summary User login
-// @Description Authenticates user and returns access token with refresh cookie
+// @Description Authenticates user, opens a new device session and returns access token with refresh cookie. Accounts with 2FA get an mfa_token to complete at /auth/login/2fa instead.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param credentials body LoginInput true "User Credentials"
 // @Success 200 {object} LoginResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 422 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Router /auth/login [post]
 func Login(c *gin.Context) {
 	var req struct {
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required"`
+		Email      string `json:"email" binding:"required,email"`
+		Password   string `json:"password" binding:"required"`
+		DeviceName string `json:"device_name"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
 		return
 	}
 
+	// throttled callers are turned away before the password is even looked at,
+	// so a locked account does not reveal whether a guess was right
+	if wait, err := services.LoginRetryAfter(req.Email, c.ClientIP()); err == nil && wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed attempts, try again later"})
+		return
+	}
+
 	user, err := database.GetUserByEmail(req.Email)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
-	if user == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+	if user == nil || !utils.CheckPassword(user.Password, req.Password) {
+		loginFailed(c, req.Email, user)
 		return
 	}
+	services.RecordLoginSuccess(req.Email)
 
-	if !utils.CheckPassword(user.Password, req.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+	// upgrade hashes made with an older algorithm or weaker settings while we have the password
+	if utils.NeedsRehash(user.Password) {
+		if hashed, err := utils.HashPassword(req.Password); err == nil {
+			if err := database.UpdateUserPassword(user.ID, hashed); err != nil {
+				log.Println("failed to rehash password:", err)
+			}
+		}
+	}
+
+	beginLogin(c, user, req.DeviceName)
+}
+
+// beginLogin is called once the first factor (password, social login) checked out.
+// Accounts with 2FA get an mfa_token for /auth/login/2fa, everyone else a session.
+func beginLogin(c *gin.Context, user *models.User, deviceName string) {
+	if user.DisabledAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "account disabled"})
 		return
 	}
+	if user.TOTPEnabledAt != nil {
+		mfaToken, err := utils.GenerateSignedToken("mfa_pending", user.ID,
+			map[string]interface{}{"device_name": deviceName}, mfaPendingTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate mfa token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+			"expires_in":   int(mfaPendingTTL.Seconds()),
+		})
+		return
+	}
+
+	startSession(c, user, deviceName, false)
+}
 
-	accessToken, err := utils.GenerateAccessToken(user.ID, user.Role)
+// loginFailed counts the failed attempt and answers with the generic error.
+// user is nil when the email is unknown.
+func loginFailed(c *gin.Context, email string, user *models.User) {
+	locked, err := services.RecordLoginFailure(email, c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate access token"})
+		log.Println("failed to record login failure:", err)
+	}
+	if locked && user != nil {
+		database.CreateSecurityEvent(&models.SecurityEvent{
+			UserID:    user.ID,
+			Type:      "account_locked",
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Details:   "too many failed login attempts",
+		})
+	}
+	entry := auditEntry{Action: "auth.login_failed", EntityType: "user", After: gin.H{"email": email}}
+	if user != nil {
+		entry.EntityID = user.ID
+	}
+	recordAudit(c, entry)
+
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+}
+
+// LoginTwoFactor godoc
+// @Summary Complete login with 2FA
+// @Description Second login step for accounts with two-factor authentication. Takes the mfa_token from /auth/login and either a TOTP code or a recovery code.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body LoginTwoFactorInput true "MFA token and code"
+// @Success 200 {object} LoginResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Router /auth/login/2fa [post]
+func LoginTwoFactor(c *gin.Context) {
+	var req struct {
+		MFAToken     string `json:"mfa_token" binding:"required"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := utils.ParseSignedToken("mfa_pending", req.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired mfa token"})
+		return
+	}
+	sub, _ := claims["sub"].(float64)
+	deviceName, _ := claims["device_name"].(string)
+
+	user, err := database.GetUserByID(uint(sub))
+	if err != nil || user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired mfa token"})
+		return
+	}
+
+	if !checkSecondFactor(c, user, req.Code, req.RecoveryCode) {
+		return
+	}
+
+	startSession(c, user, deviceName, true)
+}
+
+// mfaPendingTTL is how long the user has to enter the second factor after the password
+const mfaPendingTTL = 5 * time.Minute
+
+// startSession opens a new device session for the user, sets the refresh cookie
+// and writes the login response. Every login flow should end here; mfa tells
+// whether the user proved a second factor.
+func startSession(c *gin.Context, user *models.User, deviceName string, mfa bool) {
+	if user.DisabledAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "account disabled"})
 		return
 	}
 
@@ -128,33 +255,78 @@ func Login(c *gin.Context) {
 
 	expiry := time.Now().Add(time.Hour * 24 * time.Duration(config.Cfg.RefreshTokenDays))
 
-	if err := database.SaveRefreshToken(user.ID, hashedRT, expiry); err != nil {
+	session := &models.Session{
+		UserID:     user.ID,
+		TokenHash:  hashedRT,
+		DeviceName: deviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		MFA:        mfa,
+		LastUsedAt: time.Now(),
+		ExpiresAt:  expiry,
+	}
+	if err := database.CreateSession(session); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save refresh token"})
 		return
 	}
 
-	// set cookie; in production set Secure=true
-	c.SetCookie("refresh_token", refreshToken, int(time.Until(expiry).Seconds()), "/", "", false, true)
+	accessToken, err := utils.GenerateAccessToken(utils.AccessClaims{
+		UserID:    user.ID,
+		Role:      user.Role,
+		SessionID: session.ID,
+		MFA:       mfa,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate access token"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
+	setRefreshCookie(c, refreshToken, expiry)
+
+	recordAudit(c, auditEntry{
+		ActorID:    user.ID,
+		Action:     "auth.login",
+		EntityType: "session",
+		EntityID:   session.ID,
+		After:      gin.H{"device_name": deviceName, "mfa": mfa},
+	})
+
+	resp := gin.H{
 		"access_token": accessToken,
 		"expires_in":   config.Cfg.AccessTokenMinutes * 60, // seconds
+		"session_id":   session.ID,
 		"user": gin.H{
-			"id":    user.ID,
-			"email": user.Email,
-			"role":  user.Role,
-			"name":  user.Name,
+			"id":       user.ID,
+			"email":    user.Email,
+			"role":     user.Role,
+			"name":     user.Name,
+			"verified": user.VerifiedAt != nil,
 		},
-	})
+	}
+	if requiresTwoFactor(user) && user.TOTPEnabledAt == nil {
+		// admin routes stay closed until 2FA is set up via /api/auth/2fa/setup
+		resp["mfa_enrollment_required"] = true
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// setRefreshCookie stores the refresh token; in production set Secure=true
+func setRefreshCookie(c *gin.Context, refreshToken string, expiry time.Time) {
+	c.SetCookie("refresh_token", refreshToken, int(time.Until(expiry).Seconds()), "/", "", false, true)
+}
+
+func clearRefreshCookie(c *gin.Context) {
+	c.SetCookie("refresh_token", "", -1, "/", "", false, true)
 }
 
 // Refresh godoc
 // @Summary Refresh access token
-// @Description Rotates refresh token and issues new access token
+// @Description Rotates the refresh token of the current session and issues new access token. Replaying an already rotated token revokes the session.
 // @Tags Auth
 // @Produce json
 // @Success 200 {object} RefreshResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Router /auth/refresh [post]
 func Refresh(c *gin.Context) {
 	rt, err := c.Cookie("refresh_token")
@@ -163,15 +335,29 @@ func Refresh(c *gin.Context) {
 		return
 	}
 
-	user, err := database.FindUserByActiveRefreshToken(rt)
+	hashed, _ := utils.HashToken(rt)
+	session, err := database.GetActiveSessionByTokenHash(hashed)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
-	if user == nil {
+	if session == nil {
+		if handleRefreshTokenReuse(c, hashed) {
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		return
+	}
+
+	user, err := database.GetUserByID(session.UserID)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
 		return
 	}
+	if user.DisabledAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "account disabled"})
+		return
+	}
 
 	// rotate refresh token
 	newRT, err := utils.GenerateRefreshToken()
@@ -182,15 +368,25 @@ func Refresh(c *gin.Context) {
 	newHashed, _ := utils.HashToken(newRT)
 	newExpiry := time.Now().Add(time.Hour * 24 * time.Duration(config.Cfg.RefreshTokenDays))
 
-	if err := database.SaveRefreshToken(user.ID, newHashed, newExpiry); err != nil {
+	rotated, err := database.RotateSessionToken(session.ID, hashed, newHashed, newExpiry)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save refresh token"})
 		return
 	}
+	if !rotated {
+		// another request rotated this token first
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		return
+	}
 
-	// set cookie
-	c.SetCookie("refresh_token", newRT, int(time.Until(newExpiry).Seconds()), "/", "", false, true)
+	setRefreshCookie(c, newRT, newExpiry)
 
-	accessToken, err := utils.GenerateAccessToken(user.ID, user.Role)
+	accessToken, err := utils.GenerateAccessToken(utils.AccessClaims{
+		UserID:    user.ID,
+		Role:      user.Role,
+		SessionID: session.ID,
+		MFA:       session.MFA,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate access token"})
 		return
@@ -202,9 +398,44 @@ func Refresh(c *gin.Context) {
 	})
 }
 
+// handleRefreshTokenReuse checks whether an unknown refresh token is one that was already
+// rotated away. Replaying a superseded token means it was most likely stolen, so the
+// whole rotation family (the session) is revoked and a security event is recorded.
+// Returns true if the response has been written.
+func handleRefreshTokenReuse(c *gin.Context, hashed string) bool {
+	rotated, err := database.GetRotatedRefreshToken(hashed)
+	if err != nil || rotated == nil {
+		return false
+	}
+
+	session, err := database.GetSessionByID(rotated.SessionID)
+	if err != nil {
+		return false
+	}
+
+	if err := database.RevokeSessionFamily(session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return true
+	}
+	services.RevokeSessionTokens(session.ID)
+
+	database.CreateSecurityEvent(&models.SecurityEvent{
+		UserID:    session.UserID,
+		SessionID: session.ID,
+		Type:      "refresh_token_reuse",
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Details:   "superseded refresh token presented, session revoked",
+	})
+
+	clearRefreshCookie(c)
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+	return true
+}
+
 // Logout godoc
 // @Summary Logout user
-// @Description Clears refresh token and removes cookie
+// @Description Revokes the current session and access token and removes the refresh cookie
 // @Tags Auth
 // @Security BearerAuth
 // @Produce json
@@ -212,34 +443,53 @@ func Refresh(c *gin.Context) {
 // @Failure 401 {object} ErrorResponse
 // @Router /api/auth/logout [post]
 func Logout(c *gin.Context) {
-	uidRaw, exists := c.Get("user_id")
-	if !exists {
+	uid, ok := contextUint(c, "user_id")
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	// tokens from jwt are numeric (float64) — handle accordingly
-	var uid uint
-	switch v := uidRaw.(type) {
+	// kill the access token this request was made with right away
+	jti := c.GetString("jti")
+	if exp, ok := c.Get("token_exp"); ok {
+		if expUnix, ok := exp.(float64); ok {
+			if err := services.RevokeAccessToken(jti, time.Unix(int64(expUnix), 0)); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke access token"})
+				return
+			}
+		}
+	}
+
+	if sid, ok := contextUint(c, "session_id"); ok {
+		if _, err := database.RevokeSession(uid, sid); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to clear refresh token"})
+			return
+		}
+		services.RevokeSessionTokens(sid)
+		recordAudit(c, auditEntry{Action: "auth.logout", EntityType: "session", EntityID: sid})
+	}
+
+	clearRefreshCookie(c)
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// contextUint reads a numeric value set by the JWT middleware.
+// tokens from jwt are numeric (float64) — handle accordingly
+func contextUint(c *gin.Context, key string) (uint, bool) {
+	raw, exists := c.Get(key)
+	if !exists {
+		return 0, false
+	}
+	switch v := raw.(type) {
 	case float64:
-		uid = uint(v)
+		return uint(v), true
 	case float32:
-		uid = uint(v)
+		return uint(v), true
 	case int:
-		uid = uint(v)
+		return uint(v), true
 	case uint:
-		uid = v
+		return v, true
 	default:
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user id type"})
-		return
-	}
-
-	if err := database.ClearRefreshToken(uid); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to clear refresh token"})
-		return
+		return 0, false
 	}
-
-	// clear cookie
-	c.SetCookie("refresh_token", "", -1, "/", "", false, true)
-	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
 }
@@ -11,7 +11,7 @@ import (
 
 // AddToCart godoc
 // @Summary Add item to cart
-// @Description Adds a product to the user's shopping cart
+// @Description Adds a product to the user's shopping cart. Products with variants need the variant_id.
 // @Tags Cart
 // @Security BearerAuth
 // @Accept json
@@ -24,8 +24,9 @@ import (
 // @Router /api/cart/add [post]
 func AddToCart(c *gin.Context) {
 	var body struct {
-		ProductID uint `json:"product_id" binding:"required"`
-		Quantity  int  `json:"quantity" binding:"required,min=1"`
+		ProductID uint  `json:"product_id" binding:"required"`
+		VariantID *uint `json:"variant_id"`
+		Quantity  int   `json:"quantity" binding:"required,min=1"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -42,19 +43,35 @@ func AddToCart(c *gin.Context) {
 		return
 	}
 
+	// Products with variants are sold per variant
+	stock := product.Quantity
+	variantCount, _ := database.CountProductVariants(product.ID)
+	if variantCount > 0 || body.VariantID != nil {
+		if body.VariantID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "variant_id is required for this product"})
+			return
+		}
+		variant, err := database.GetProductVariant(product.ID, *body.VariantID)
+		if err != nil || !variant.IsActive {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "variant not found or inactive"})
+			return
+		}
+		stock = variant.Quantity
+	}
+
 	// Enough stock?
-	if body.Quantity > product.Quantity {
+	if body.Quantity > stock {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "not enough stock"})
 		return
 	}
 
 	// Already in cart?
-	existing, _ := database.GetCartItem(userID, body.ProductID)
+	existing, _ := database.GetCartItem(userID, body.ProductID, body.VariantID)
 	if existing != nil {
 		// update quantity
 		newQty := existing.Quantity + body.Quantity
 
-		if newQty > product.Quantity {
+		if newQty > stock {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "exceeds available stock"})
 			return
 		}
@@ -68,6 +85,7 @@ func AddToCart(c *gin.Context) {
 	item := &models.CartItem{
 		UserID:    userID,
 		ProductID: body.ProductID,
+		VariantID: body.VariantID,
 		Quantity:  body.Quantity,
 	}
 
@@ -103,7 +121,7 @@ func GetCart(c *gin.Context) {
 	// Calculate totals
 	var total float64 = 0
 	for _, item := range items {
-		total += float64(item.Quantity) * item.Product.Price
+		total += float64(item.Quantity) * item.UnitPrice()
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -146,9 +164,8 @@ func UpdateCartQuantity(c *gin.Context) {
 		return
 	}
 
-	// Check product stock
-	product, _ := database.GetProductByID(item.ProductID)
-	if body.Quantity > product.Quantity {
+	// Check product or variant stock
+	if body.Quantity > item.Stock() {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "not enough stock"})
 		return
 	}
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
+	"ecommerce-gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type categoryInput struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	Slug     string `json:"slug" binding:"max=255"` // generated from the name when empty
+	ParentID *uint  `json:"parent_id"`
+	Position int    `json:"position"`
+}
+
+// ListCategories godoc
+// @Summary Category tree
+// @Description Returns the root categories with their subcategories nested, siblings in display order
+// @Tags Categories
+// @Produce json
+// @Success 200 {array} CategoryResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /categories [get]
+func ListCategories(c *gin.Context) {
+	tree, err := database.CategoryTree()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load categories"})
+		return
+	}
+	c.JSON(http.StatusOK, tree)
+}
+
+// GetCategory godoc
+// @Summary Get category by slug
+// @Description Returns a category with its direct subcategories and the breadcrumb path from the root
+// @Tags Categories
+// @Produce json
+// @Param slug path string true "Category Slug"
+// @Success 200 {object} CategoryDetailResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /categories/{slug} [get]
+func GetCategory(c *gin.Context) {
+	category, err := database.GetCategoryBySlug(c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		return
+	}
+
+	tree, err := database.CategoryTree()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load categories"})
+		return
+	}
+	children := findCategoryChildren(tree, category.ID)
+	for i := range children {
+		children[i].Children = nil
+	}
+	category.Children = children
+
+	breadcrumbs, err := database.CategoryBreadcrumbs(category.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"category":    category,
+		"breadcrumbs": breadcrumbs,
+	})
+}
+
+func findCategoryChildren(nodes []models.Category, id uint) []models.Category {
+	for _, n := range nodes {
+		if n.ID == id {
+			return n.Children
+		}
+		if found := findCategoryChildren(n.Children, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// CreateCategory godoc
+// @Summary Create a category (Admin only)
+// @Description Creates a category, optionally below a parent. The slug is generated from the name unless given.
+// @Tags Categories
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body CategoryInput true "Category"
+// @Success 201 {object} CategoryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/admin/categories [post]
+func CreateCategory(c *gin.Context) {
+	var body categoryInput
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category := &models.Category{
+		Name:     strings.TrimSpace(body.Name),
+		ParentID: body.ParentID,
+		Position: body.Position,
+	}
+	parent, ok := loadParentCategory(c, body.ParentID)
+	if !ok {
+		return
+	}
+	if !assignCategorySlug(c, category, body.Slug, parent) {
+		return
+	}
+
+	if err := database.CreateCategory(category); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create category"})
+		return
+	}
+
+	recordAudit(c, auditEntry{Action: "category.create", EntityType: "category", EntityID: category.ID, After: category})
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory godoc
+// @Summary Update a category (Admin only)
+// @Description Renames, moves or reorders a category. A category cannot be moved below one of its own subcategories.
+// @Tags Categories
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID"
+// @Param body body CategoryInput true "Category"
+// @Success 200 {object} CategoryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/admin/categories/{id} [put]
+func UpdateCategory(c *gin.Context) {
+	var body categoryInput
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := database.GetCategoryByID(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		return
+	}
+	before := *category
+
+	parent, ok := loadParentCategory(c, body.ParentID)
+	if !ok {
+		return
+	}
+	if parent != nil {
+		subtree, err := database.CategoryDescendantIDs(category.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load categories"})
+			return
+		}
+		for _, id := range subtree {
+			if id == parent.ID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "a category cannot be moved below itself"})
+				return
+			}
+		}
+	}
+
+	category.Name = strings.TrimSpace(body.Name)
+	category.ParentID = body.ParentID
+	category.Position = body.Position
+	// the slug only changes when asked for, so links keep working after a rename
+	if body.Slug != "" && body.Slug != category.Slug {
+		if !assignCategorySlug(c, category, body.Slug, parent) {
+			return
+		}
+	}
+
+	if err := database.UpdateCategory(category); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	// product documents carry the category path by name
+	if err := services.RebuildSearchIndex(); err != nil {
+		log.Println("failed to rebuild search index:", err)
+	}
+
+	recordAudit(c, auditEntry{
+		Action:     "category.update",
+		EntityType: "category",
+		EntityID:   category.ID,
+		Before:     before,
+		After:      category,
+	})
+
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory godoc
+// @Summary Delete a category (Admin only)
+// @Description Deletes an empty category. Categories that still have subcategories or products are refused.
+// @Tags Categories
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Category ID"
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/admin/categories/{id} [delete]
+func DeleteCategory(c *gin.Context) {
+	category, err := database.GetCategoryByID(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		return
+	}
+
+	children, products, err := database.CountCategoryUsage(category.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if children > 0 || products > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         "category is not empty",
+			"subcategories": children,
+			"products":      products,
+		})
+		return
+	}
+
+	if err := database.DeleteCategory(category.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+
+	recordAudit(c, auditEntry{Action: "category.delete", EntityType: "category", EntityID: category.ID, Before: category})
+
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// loadParentCategory loads the parent named in the input, if any. Returns false if
+// the response has been written.
+func loadParentCategory(c *gin.Context, parentID *uint) (*models.Category, bool) {
+	if parentID == nil {
+		return nil, true
+	}
+	parent, err := database.GetCategoryByID(*parentID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "parent category not found"})
+		return nil, false
+	}
+	return parent, true
+}
+
+// assignCategorySlug sets the requested slug, or generates one when it is empty.
+// Returns false if the response has been written.
+func assignCategorySlug(c *gin.Context, category *models.Category, requested string, parent *models.Category) bool {
+	if requested == "" {
+		slug, err := database.NewCategorySlug(database.DB, category.Name, parent)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return false
+		}
+		if slug == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name needs at least one letter or digit"})
+			return false
+		}
+		category.Slug = slug
+		return true
+	}
+
+	slug := utils.Slugify(requested)
+	if slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid slug"})
+		return false
+	}
+	taken, err := database.CategorySlugTaken(slug, category.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return false
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "slug already in use"})
+		return false
+	}
+	category.Slug = slug
+	return true
+}
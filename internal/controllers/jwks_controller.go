@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"net/http"
+
+	"ecommerce-gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys (active and retired) that access tokens can be verified with
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} JWKSResponse
+// @Router /.well-known/jwks.json [get]
+func JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": utils.Keys.JWKS()})
+}
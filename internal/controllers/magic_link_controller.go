@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/config"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
+	"ecommerce-gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestMagicLink godoc
+// @Summary Request a magic login link
+// @Description Emails a single-use, time-limited login link if the account exists. Always answers the same way.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body MagicLinkInput true "Email"
+// @Success 200 {object} MessageResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /auth/magic-link [post]
+func RequestMagicLink(c *gin.Context) {
+	var body struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	// same answer whether or not the account exists
+	resp := gin.H{"message": "if the account exists, a login link has been sent"}
+
+	// at most one email per address per minute
+	key := "magic:link:" + strings.ToLower(body.Email)
+	if ok, err := cache.Rdb.SetNX(cache.Ctx, key, "1", time.Minute).Result(); err != nil || !ok {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	user, err := database.GetUserByEmail(body.Email)
+	if err != nil || user == nil || user.DisabledAt != nil {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	if err := sendMagicLinkEmail(user); err != nil {
+		log.Println("failed to send magic link email:", err)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// sendMagicLinkEmail mails a signed login link. The signature binds it to the user
+// and address; the nonce inside is stored hashed so the link works only once.
+func sendMagicLinkEmail(user *models.User) error {
+	nonce, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return err
+	}
+	hashed, _ := utils.HashToken(nonce)
+
+	// only the newest link works
+	if err := database.InvalidateUserTokens(user.ID, "magic_link"); err != nil {
+		return err
+	}
+	ttl := time.Minute * time.Duration(config.Cfg.MagicLinkMinutes)
+	if err := database.CreateUserToken(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   "magic_link",
+		TokenHash: hashed,
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateSignedToken("magic_link", user.ID,
+		map[string]interface{}{"email": user.Email, "nonce": nonce}, ttl)
+	if err != nil {
+		return err
+	}
+
+	// the frontend page posts the token to /auth/magic-link/verify, so link
+	// scanners that prefetch the URL cannot use it up
+	link := config.Cfg.FrontendBaseURL + "/magic-login?token=" + url.QueryEscape(token)
+	return services.SendMail(services.Mail{
+		To:      user.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf("Hi %s,\n\nUse this link to log in:\n\n%s\n\nThe link expires in %d minutes and can only be used once. If you did not ask for this, ignore this email.\n",
+			user.Name, link, config.Cfg.MagicLinkMinutes),
+	})
+}
+
+// VerifyMagicLink godoc
+// @Summary Log in with a magic link
+// @Description Exchanges the token from a magic link email for a session. Accounts with 2FA get an mfa_token for /auth/login/2fa instead.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body VerifyMagicLinkInput true "Magic link token"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /auth/magic-link/verify [post]
+func VerifyMagicLink(c *gin.Context) {
+	var body struct {
+		Token      string `json:"token" binding:"required"`
+		DeviceName string `json:"device_name"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := utils.ParseSignedToken("magic_link", body.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired login link"})
+		return
+	}
+	sub, _ := claims["sub"].(float64)
+	email, _ := claims["email"].(string)
+	nonce, _ := claims["nonce"].(string)
+
+	hashed, _ := utils.HashToken(nonce)
+	token, err := database.ConsumeUserToken("magic_link", hashed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if token == nil || token.UserID != uint(sub) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired login link"})
+		return
+	}
+
+	// a link sent before an email change is void
+	user, err := database.GetUserByID(token.UserID)
+	if err != nil || !strings.EqualFold(user.Email, email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired login link"})
+		return
+	}
+
+	// following the emailed link proves ownership of the address
+	if user.VerifiedAt == nil {
+		now := time.Now()
+		if err := database.MarkUserVerified(user.ID, now); err == nil {
+			user.VerifiedAt = &now
+		}
+	}
+
+	beginLogin(c, user, body.DeviceName)
+}
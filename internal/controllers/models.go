@@ -1,6 +1,11 @@
 package controllers
 
-import "time"
+import (
+	"time"
+
+	"ecommerce-gin/internal/utils"
+	"ecommerce-gin/internal/webauthn"
+)
 
 // Swagger Model Definitions
 
@@ -8,34 +13,101 @@ import "time"
 type SignupInput struct {
 	Name     string `json:"name" binding:"required" example:"John Doe"`
 	Email    string `json:"email" binding:"required,email" example:"john@example.com"`
-	Password string `json:"password" binding:"required,min=6" example:"password123"`
-	Role     string `json:"role" example:"customer"`
+	Password string `json:"password" binding:"required" example:"password123"`
 }
 
 // LoginInput represents the login request body
 type LoginInput struct {
-	Email    string `json:"email" binding:"required,email" example:"john@example.com"`
-	Password string `json:"password" binding:"required" example:"password123"`
+	Email      string `json:"email" binding:"required,email" example:"john@example.com"`
+	Password   string `json:"password" binding:"required" example:"password123"`
+	DeviceName string `json:"device_name" example:"John's iPhone"`
 }
 
 // LoginResponse represents the login response
 type LoginResponse struct {
-	AccessToken string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
-	ExpiresIn   int    `json:"expires_in" example:"3600"`
+	AccessToken string       `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	ExpiresIn   int          `json:"expires_in" example:"3600"`
+	SessionID   uint         `json:"session_id" example:"1"`
 	User        UserResponse `json:"user"`
 }
 
 // UserResponse represents user data in responses
 type UserResponse struct {
-	ID    uint   `json:"id" example:"1"`
-	Email string `json:"email" example:"john@example.com"`
-	Role  string `json:"role" example:"customer"`
-	Name  string `json:"name" example:"John Doe"`
+	ID       uint   `json:"id" example:"1"`
+	Email    string `json:"email" example:"john@example.com"`
+	Role     string `json:"role" example:"customer"`
+	Name     string `json:"name" example:"John Doe"`
+	Verified bool   `json:"verified" example:"true"`
 }
 
 // ResponseUser represents signup response
 type ResponseUser struct {
-	Message string `json:"message" example:"user created"`
+	Message string `json:"message" example:"user created, check your email to verify your address"`
+}
+
+// ResendVerificationInput represents the resend verification request body
+type ResendVerificationInput struct {
+	Email string `json:"email" binding:"required,email" example:"john@example.com"`
+}
+
+// MFARequiredResponse is returned by login when a second factor is needed
+type MFARequiredResponse struct {
+	MFARequired bool   `json:"mfa_required" example:"true"`
+	MFAToken    string `json:"mfa_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	ExpiresIn   int    `json:"expires_in" example:"300"`
+}
+
+// OIDCProvidersResponse lists the configured social login providers
+type OIDCProvidersResponse struct {
+	Providers []string `json:"providers" example:"google,mock"`
+}
+
+// LoginTwoFactorInput represents the second login step
+type LoginTwoFactorInput struct {
+	MFAToken     string `json:"mfa_token" binding:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	Code         string `json:"code" example:"123456"`
+	RecoveryCode string `json:"recovery_code" example:"abcde-fghij"`
+}
+
+// TwoFactorSetupResponse represents a pending TOTP enrollment
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	OtpauthURI string `json:"otpauth_uri" example:"otpauth://totp/E-Commerce:john@example.com?secret=JBSWY3DPEHPK3PXP&issuer=E-Commerce"`
+}
+
+// TwoFactorCodeInput represents a request carrying a TOTP code
+type TwoFactorCodeInput struct {
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+// TwoFactorDisableInput represents the disable 2FA request body
+type TwoFactorDisableInput struct {
+	Password     string `json:"password" binding:"required" example:"password123"`
+	Code         string `json:"code" example:"123456"`
+	RecoveryCode string `json:"recovery_code" example:"abcde-fghij"`
+}
+
+// RecoveryCodesResponse represents freshly issued recovery codes
+type RecoveryCodesResponse struct {
+	Message       string   `json:"message" example:"two-factor authentication enabled, log in again to use it"`
+	RecoveryCodes []string `json:"recovery_codes" example:"abcde-fghij,klmno-pqrst"`
+}
+
+// ForgotPasswordInput represents the forgot password request body
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required,email" example:"john@example.com"`
+}
+
+// ResetPasswordInput represents the reset password request body
+type ResetPasswordInput struct {
+	Token    string `json:"token" binding:"required" example:"q1w2e3..."`
+	Password string `json:"password" binding:"required" example:"newpassword123"`
+}
+
+// ChangePasswordInput represents the change password request body
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" binding:"required" example:"password123"`
+	NewPassword     string `json:"new_password" binding:"required" example:"newpassword123"`
 }
 
 // RefreshResponse represents refresh token response
@@ -44,6 +116,55 @@ type RefreshResponse struct {
 	ExpiresIn   int    `json:"expires_in" example:"3600"`
 }
 
+// SessionResponse represents an active device session
+type SessionResponse struct {
+	ID         uint      `json:"id" example:"1"`
+	CreatedAt  time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	DeviceName string    `json:"device_name" example:"John's iPhone"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0"`
+	IP         string    `json:"ip" example:"203.0.113.10"`
+	LastUsedAt time.Time `json:"last_used_at" example:"2024-01-01T00:00:00Z"`
+	ExpiresAt  time.Time `json:"expires_at" example:"2024-01-08T00:00:00Z"`
+	Current    bool      `json:"current" example:"true"`
+}
+
+// JWKSResponse represents the public JSON Web Key Set
+type JWKSResponse struct {
+	Keys []utils.JWK `json:"keys"`
+}
+
+// PermissionResponse represents a permission
+type PermissionResponse struct {
+	ID          uint   `json:"id" example:"1"`
+	Name        string `json:"name" example:"orders:update"`
+	Description string `json:"description" example:"Change order status"`
+}
+
+// RoleResponse represents a role with its permissions
+type RoleResponse struct {
+	ID          uint                 `json:"id" example:"1"`
+	Name        string               `json:"name" example:"warehouse"`
+	Description string               `json:"description" example:"Fulfilment staff"`
+	Permissions []PermissionResponse `json:"permissions"`
+}
+
+// CreateRoleInput represents role creation request
+type CreateRoleInput struct {
+	Name        string   `json:"name" binding:"required" example:"marketing"`
+	Description string   `json:"description" example:"Marketing team"`
+	Permissions []string `json:"permissions" example:"products:write"`
+}
+
+// SetRolePermissionsInput represents role permissions update request
+type SetRolePermissionsInput struct {
+	Permissions []string `json:"permissions" example:"orders:read,orders:update"`
+}
+
+// AssignRoleInput represents role assignment request
+type AssignRoleInput struct {
+	Role string `json:"role" binding:"required" example:"support"`
+}
+
 // MessageResponse represents a generic message response
 type MessageResponse struct {
 	Message string `json:"message" example:"success"`
@@ -60,18 +181,18 @@ type CreateProductInput struct {
 	Description string  `json:"description" example:"High-performance laptop"`
 	Price       float64 `json:"price" binding:"required" example:"999.99"`
 	Quantity    int     `json:"quantity" binding:"required" example:"10"`
-	Category    string  `json:"category" example:"Electronics"`
-	ImageURL    string  `json:"image_url" example:"https://example.com/image.jpg"`
+	CategoryID  *uint   `json:"category_id" example:"3"`
+	ImageURL    string  `json:"image_url" example:"/uploads/20240101120000.jpg"` // from the upload endpoints
 }
 
 // UpdateProductInput represents product update request
 type UpdateProductInput struct {
 	Name        string  `json:"name" example:"Laptop"`
+	Slug        string  `json:"slug" example:"laptop-pro"`
 	Description string  `json:"description" example:"High-performance laptop"`
 	Price       float64 `json:"price" example:"999.99"`
 	Quantity    int     `json:"quantity" example:"10"`
-	Category    string  `json:"category" example:"Electronics"`
-	ImageURL    string  `json:"image_url" example:"https://example.com/image.jpg"`
+	CategoryID  *uint   `json:"category_id" example:"3"`
 	IsActive    bool    `json:"is_active" example:"true"`
 }
 
@@ -85,9 +206,174 @@ type Product struct {
 	Description string    `json:"description" example:"High-performance laptop"`
 	Price       float64   `json:"price" example:"999.99"`
 	Quantity    int       `json:"quantity" example:"10"`
-	Category    string    `json:"category" example:"Electronics"`
+	CategoryID  *uint     `json:"category_id" example:"3"`
 	ImageURL    string    `json:"image_url" example:"https://example.com/image.jpg"`
 	IsActive    bool      `json:"is_active" example:"true"`
+	SalesCount  int       `json:"sales_count" example:"42"`
+
+	RatingAverage float64 `json:"rating_average" example:"4.35"`
+	RatingCount   int     `json:"rating_count" example:"17"`
+
+	Category    *CategoryResponse `json:"category,omitempty"`
+	Breadcrumbs []CategoryCrumb   `json:"breadcrumbs,omitempty"`
+
+	// the whole gallery for a single product, only the primary image in lists
+	Images []ProductImage `json:"images,omitempty"`
+
+	Options  []ProductOption  `json:"options,omitempty"`
+	Variants []ProductVariant `json:"variants,omitempty"`
+}
+
+// ProductImage represents an image in a product's gallery
+type ProductImage struct {
+	ID        uint   `json:"id" example:"7"`
+	ProductID uint   `json:"product_id" example:"1"`
+	URL       string `json:"url" example:"https://cdn.example.com/1700000000.jpg"`
+	AltText   string `json:"alt_text" example:"Laptop, front view"`
+	Width     int    `json:"width" example:"1200"`
+	Height    int    `json:"height" example:"800"`
+	Position  int    `json:"position" example:"0"`
+	IsPrimary bool   `json:"is_primary" example:"true"`
+}
+
+// ProductImageInput represents an image to attach to a product
+type ProductImageInput struct {
+	URL     string `json:"url" binding:"required" example:"https://cdn.example.com/1700000000.jpg"`
+	AltText string `json:"alt_text" example:"Laptop, front view"`
+	Width   int    `json:"width" example:"1200"`
+	Height  int    `json:"height" example:"800"`
+	Primary bool   `json:"primary" example:"false"`
+}
+
+// UpdateProductImageInput represents a product image update; omitted fields are kept
+type UpdateProductImageInput struct {
+	AltText *string `json:"alt_text" example:"Laptop, side view"`
+	Primary *bool   `json:"primary" example:"true"`
+}
+
+// ReorderProductImagesInput represents a new gallery order
+type ReorderProductImagesInput struct {
+	ImageIDs []uint `json:"image_ids" binding:"required" example:"9,7,8"`
+}
+
+// Review represents a customer's review of a product
+type Review struct {
+	ID               uint       `json:"id" example:"12"`
+	CreatedAt        time.Time  `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt        time.Time  `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	ProductID        uint       `json:"product_id" example:"1"`
+	UserID           uint       `json:"user_id" example:"5"`
+	AuthorName       string     `json:"author_name" example:"Jane"`
+	Rating           int        `json:"rating" example:"4"`
+	Title            string     `json:"title" example:"Fast and quiet"`
+	Body             string     `json:"body" example:"Battery lasts a full day."`
+	VerifiedPurchase bool       `json:"verified_purchase" example:"true"`
+	Status           string     `json:"status" example:"approved"`
+	ModerationNote   string     `json:"moderation_note,omitempty" example:""`
+	ModeratedBy      *uint      `json:"moderated_by,omitempty" example:"1"`
+	ModeratedAt      *time.Time `json:"moderated_at,omitempty"`
+	Product          *Product   `json:"product,omitempty"`
+}
+
+// ReviewInput represents a new or edited review
+type ReviewInput struct {
+	Rating int    `json:"rating" binding:"required" example:"4"`
+	Title  string `json:"title" example:"Fast and quiet"`
+	Body   string `json:"body" example:"Battery lasts a full day."`
+}
+
+// ReviewListResponse represents a page of a product's reviews with its rating
+type ReviewListResponse struct {
+	Items         []Review         `json:"items"`
+	Meta          MetaInfo         `json:"meta"`
+	RatingAverage float64          `json:"rating_average" example:"4.35"`
+	RatingCount   int              `json:"rating_count" example:"17"`
+	Breakdown     map[string]int64 `json:"breakdown"` // reviews per star, "1" to "5"
+}
+
+// ReviewModerationListResponse represents a page of the moderation queue
+type ReviewModerationListResponse struct {
+	Items []Review `json:"items"`
+	Meta  MetaInfo `json:"meta"`
+}
+
+// ModerateReviewInput represents a moderation decision
+type ModerateReviewInput struct {
+	Status string `json:"status" binding:"required" example:"approved"`
+	Note   string `json:"note" example:"Thanks for your review"`
+}
+
+// ImportSummaryResponse represents the outcome of a product import
+type ImportSummaryResponse struct {
+	Rows       int              `json:"rows" example:"120"`
+	Created    int              `json:"created" example:"30"`
+	Updated    int              `json:"updated" example:"90"`
+	DryRun     bool             `json:"dry_run" example:"false"`
+	Applied    bool             `json:"applied" example:"true"`
+	ErrorCount int              `json:"error_count" example:"0"`
+	Errors     []ImportRowError `json:"errors"`
+}
+
+// ImportRowError represents a problem with one line of an import
+type ImportRowError struct {
+	Line    int    `json:"line" example:"14"`
+	Field   string `json:"field,omitempty" example:"price"`
+	Message string `json:"message" example:"must be a number"`
+}
+
+// ImportJobStartedResponse represents an import queued as a background job
+type ImportJobStartedResponse struct {
+	JobID     string `json:"job_id" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	Status    string `json:"status" example:"queued"`
+	Total     int    `json:"total" example:"5000"`
+	StatusURL string `json:"status_url" example:"/api/admin/products/import/9f86d081884c7d659a2feaa0c55ad015"`
+}
+
+// ImportJobResponse represents the progress of a background import
+type ImportJobResponse struct {
+	ID         string                 `json:"id" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	Status     string                 `json:"status" example:"running"`
+	DryRun     bool                   `json:"dry_run" example:"false"`
+	Total      int                    `json:"total" example:"5000"`
+	Processed  int                    `json:"processed" example:"1800"`
+	Result     *ImportSummaryResponse `json:"result,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	UserID     uint                   `json:"user_id" example:"1"`
+	CreatedAt  time.Time              `json:"created_at"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+}
+
+// ProductOption represents an option type of a product and its values
+type ProductOption struct {
+	Name   string   `json:"name" example:"Size"`
+	Values []string `json:"values" example:"S,M,L"`
+}
+
+// ProductVariant represents a purchasable combination of option values
+type ProductVariant struct {
+	ID        uint              `json:"id" example:"1"`
+	ProductID uint              `json:"product_id" example:"1"`
+	SKU       string            `json:"sku" example:"TEE-M-RED"`
+	Price     *float64          `json:"price" example:"24.99"` // null: product price
+	Quantity  int               `json:"quantity" example:"5"`
+	ImageURL  string            `json:"image_url" example:"https://example.com/tee-red.jpg"`
+	IsActive  bool              `json:"is_active" example:"true"`
+	Options   map[string]string `json:"options"`
+}
+
+// SetProductOptionsInput replaces the option types of a product
+type SetProductOptionsInput struct {
+	Options []ProductOption `json:"options"`
+}
+
+// ProductVariantInput represents a variant to create or replace
+type ProductVariantInput struct {
+	SKU      string            `json:"sku" binding:"required" example:"TEE-M-RED"`
+	Price    *float64          `json:"price" example:"24.99"`
+	Quantity int               `json:"quantity" example:"5"`
+	ImageURL string            `json:"image_url" example:"https://example.com/tee-red.jpg"`
+	IsActive *bool             `json:"is_active" example:"true"`
+	Options  map[string]string `json:"options"`
 }
 
 // ProductListResponse represents paginated product list
@@ -99,10 +385,56 @@ type ProductListResponse struct {
 	TotalPages int64     `json:"totalPages" example:"10"`
 }
 
+// QueryErrorResponse represents rejected query parameters
+type QueryErrorResponse struct {
+	Error   string       `json:"error" example:"invalid query parameters"`
+	Details []ParamError `json:"details"`
+}
+
+// ParamError represents one rejected query parameter
+type ParamError struct {
+	Param   string `json:"param" example:"sort"`
+	Message string `json:"message" example:"unknown sort key color, use price, newest, name, popularity or rating"`
+}
+
+// ProductSearchResponse represents ranked search results with facets
+type ProductSearchResponse struct {
+	Items      []Product    `json:"items"`
+	Total      int64        `json:"total" example:"42"`
+	Page       int          `json:"page" example:"1"`
+	Limit      int          `json:"limit" example:"10"`
+	TotalPages int64        `json:"totalPages" example:"5"`
+	Facets     SearchFacets `json:"facets"`
+}
+
+// SearchFacets represents match counts per category, price range and stock state
+type SearchFacets struct {
+	Categories []CategoryFacet `json:"categories"`
+	Price      []PriceFacet    `json:"price"`
+	InStock    int             `json:"in_stock" example:"30"`
+	OutOfStock int             `json:"out_of_stock" example:"12"`
+}
+
+// CategoryFacet represents the matches in a category, subcategories included
+type CategoryFacet struct {
+	ID    uint   `json:"id" example:"3"`
+	Name  string `json:"name" example:"Shoes"`
+	Slug  string `json:"slug" example:"shoes"`
+	Count int    `json:"count" example:"17"`
+}
+
+// PriceFacet represents the matches with min <= price < max; max is null for the last range
+type PriceFacet struct {
+	Min   float64  `json:"min" example:"25"`
+	Max   *float64 `json:"max" example:"50"`
+	Count int      `json:"count" example:"8"`
+}
+
 // AddToCartInput represents add to cart request
 type AddToCartInput struct {
-	ProductID uint `json:"product_id" binding:"required" example:"1"`
-	Quantity  int  `json:"quantity" binding:"required,min=1" example:"2"`
+	ProductID uint  `json:"product_id" binding:"required" example:"1"`
+	VariantID *uint `json:"variant_id" example:"3"`
+	Quantity  int   `json:"quantity" binding:"required,min=1" example:"2"`
 }
 
 // UpdateCartQuantityInput represents update cart quantity request
@@ -112,13 +444,15 @@ type UpdateCartQuantityInput struct {
 
 // CartItem represents a cart item
 type CartItem struct {
-	ID        uint      `json:"id" example:"1"`
-	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
-	UpdatedAt time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
-	UserID    uint      `json:"user_id" example:"1"`
-	ProductID uint      `json:"product_id" example:"1"`
-	Quantity  int       `json:"quantity" example:"2"`
-	Product   Product   `json:"product"`
+	ID        uint            `json:"id" example:"1"`
+	CreatedAt time.Time       `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt time.Time       `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	UserID    uint            `json:"user_id" example:"1"`
+	ProductID uint            `json:"product_id" example:"1"`
+	VariantID *uint           `json:"variant_id" example:"3"`
+	Quantity  int             `json:"quantity" example:"2"`
+	Product   Product         `json:"product"`
+	Variant   *ProductVariant `json:"variant,omitempty"`
 }
 
 // CartResponse represents the cart response
@@ -140,15 +474,18 @@ type Order struct {
 
 // OrderItem represents an order item
 type OrderItem struct {
-	ID        uint      `json:"id" example:"1"`
-	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
-	UpdatedAt time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
-	OrderID   uint      `json:"order_id" example:"1"`
-	ProductID uint      `json:"product_id" example:"1"`
-	Quantity  int       `json:"quantity" example:"2"`
-	Price     float64   `json:"price" example:"999.99"`
-	Subtotal  float64   `json:"subtotal" example:"1999.98"`
-	Product   Product   `json:"product"`
+	ID        uint            `json:"id" example:"1"`
+	CreatedAt time.Time       `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt time.Time       `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	OrderID   uint            `json:"order_id" example:"1"`
+	ProductID uint            `json:"product_id" example:"1"`
+	VariantID *uint           `json:"variant_id" example:"3"`
+	SKU       string          `json:"sku" example:"TEE-M-RED"`
+	Quantity  int             `json:"quantity" example:"2"`
+	Price     float64         `json:"price" example:"999.99"`
+	Subtotal  float64         `json:"subtotal" example:"1999.98"`
+	Product   Product         `json:"product"`
+	Variant   *ProductVariant `json:"variant,omitempty"`
 }
 
 // CheckoutResponse represents checkout response
@@ -175,6 +512,8 @@ type PaymentWebhookResponse struct {
 type UploadResponse struct {
 	Message string `json:"message" example:"uploaded"`
 	URL     string `json:"url" example:"/uploads/20240101120000.jpg"`
+	Width   int    `json:"width" example:"1200"`
+	Height  int    `json:"height" example:"800"`
 }
 
 // UpdateOrderStatusInput represents order status update request
@@ -188,6 +527,21 @@ type AdminOrderListResponse struct {
 	Meta  MetaInfo `json:"meta"`
 }
 
+// AdminUserListResponse represents admin user list response
+type AdminUserListResponse struct {
+	Items []ProfileResponse `json:"items"`
+	Meta  MetaInfo          `json:"meta"`
+}
+
+// AdminUserDetailResponse represents a user with order history for admins
+type AdminUserDetailResponse struct {
+	User             ProfileResponse `json:"user"`
+	Orders           []Order         `json:"orders"`
+	OrderCount       int64           `json:"order_count" example:"3"`
+	LifetimeSpend    float64         `json:"lifetime_spend" example:"249.97"`
+	LockedForSeconds int             `json:"locked_for_seconds" example:"0"`
+}
+
 // MetaInfo represents pagination metadata
 type MetaInfo struct {
 	Page  int   `json:"page" example:"1"`
@@ -200,3 +554,189 @@ type OrderStatsResponse struct {
 	Counts  map[string]int64 `json:"counts" example:"pending:10,confirmed:20"`
 	Revenue float64          `json:"revenue" example:"50000.00"`
 }
+
+// APIKeyResponse represents an API key without its secret
+type APIKeyResponse struct {
+	ID          uint       `json:"ID" example:"1"`
+	Name        string     `json:"name" example:"ERP sync"`
+	Prefix      string     `json:"prefix" example:"eck_Q2x1dGhy"`
+	Scopes      []string   `json:"scopes" example:"products:write,orders:read"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedByID uint       `json:"created_by_id" example:"1"`
+}
+
+// CreateAPIKeyInput represents a new API key request
+type CreateAPIKeyInput struct {
+	Name          string   `json:"name" binding:"required" example:"ERP sync"`
+	Scopes        []string `json:"scopes" binding:"required" example:"products:write,orders:read"`
+	ExpiresInDays int      `json:"expires_in_days" example:"90"`
+}
+
+// CreatedAPIKeyResponse carries the raw key, which is only ever shown here
+type CreatedAPIKeyResponse struct {
+	Key    string         `json:"key" example:"eck_Q2x1dGhyb2FkLXNlY3JldC1leGFtcGxl"`
+	APIKey APIKeyResponse `json:"api_key"`
+}
+
+// ProfileResponse represents the logged-in user's account
+type ProfileResponse struct {
+	ID            uint       `json:"ID" example:"1"`
+	Name          string     `json:"name" example:"John Doe"`
+	Email         string     `json:"email" example:"john@example.com"`
+	Role          string     `json:"role" example:"customer"`
+	VerifiedAt    *time.Time `json:"verified_at"`
+	PendingEmail  string     `json:"pending_email,omitempty" example:"john.new@example.com"`
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at"`
+	DisabledAt    *time.Time `json:"disabled_at"`
+}
+
+// UpdateProfileInput represents profile changes; omitted fields stay as they are
+type UpdateProfileInput struct {
+	Name            string `json:"name" example:"John Doe"`
+	Email           string `json:"email" example:"john.new@example.com"`
+	CurrentPassword string `json:"current_password" example:"password123"`
+}
+
+// DeleteProfileInput confirms account deletion
+type DeleteProfileInput struct {
+	Password string `json:"password" example:"password123"`
+}
+
+// ProfileExportResponse is the GDPR data export
+type ProfileExportResponse struct {
+	ExportedAt time.Time         `json:"exported_at"`
+	Profile    ProfileResponse   `json:"profile"`
+	Cart       []CartItem        `json:"cart"`
+	Orders     []Order           `json:"orders"`
+	Sessions   []SessionResponse `json:"sessions"`
+	Reviews    []Review          `json:"reviews"`
+}
+
+// AuditLogEntry represents one audit log record
+type AuditLogEntry struct {
+	ID         uint      `json:"id" example:"42"`
+	CreatedAt  time.Time `json:"created_at"`
+	ActorType  string    `json:"actor_type" example:"user"`
+	ActorID    *uint     `json:"actor_id" example:"1"`
+	OnBehalfOf *uint     `json:"on_behalf_of,omitempty" example:"12"`
+	Action     string    `json:"action" example:"product.update"`
+	EntityType string    `json:"entity_type" example:"product"`
+	EntityID   string    `json:"entity_id" example:"7"`
+	Before     string    `json:"before,omitempty" example:"{\"price\":19.99}"`
+	After      string    `json:"after,omitempty" example:"{\"price\":24.99}"`
+	IP         string    `json:"ip" example:"203.0.113.7"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0"`
+	RequestID  string    `json:"request_id" example:"9f86d081884c7d659a2feaa0c55ad015"`
+}
+
+// AuditLogListResponse represents a page of audit log entries
+type AuditLogListResponse struct {
+	Items []AuditLogEntry `json:"items"`
+	Meta  MetaInfo        `json:"meta"`
+}
+
+// ImpersonateInput gives the reason for impersonating a customer, kept in the audit log
+type ImpersonateInput struct {
+	Reason string `json:"reason" binding:"required" example:"ticket #4711: items vanish from cart"`
+}
+
+// ImpersonationResponse carries the short-lived impersonation token
+type ImpersonationResponse struct {
+	AccessToken string       `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	ExpiresIn   int          `json:"expires_in" example:"600"`
+	User        UserResponse `json:"user"`
+}
+
+// MagicLinkInput represents a magic login link request
+type MagicLinkInput struct {
+	Email string `json:"email" binding:"required,email" example:"user@example.com"`
+}
+
+// VerifyMagicLinkInput represents the token from a magic login link
+type VerifyMagicLinkInput struct {
+	Token      string `json:"token" binding:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	DeviceName string `json:"device_name" example:"iPhone"`
+}
+
+// PasskeyCreationOptions wraps the options for navigator.credentials.create
+type PasskeyCreationOptions struct {
+	PublicKey webauthn.CreationOptions `json:"publicKey"`
+}
+
+// PasskeyRequestOptions wraps the options for navigator.credentials.get
+type PasskeyRequestOptions struct {
+	PublicKey webauthn.RequestOptions `json:"publicKey"`
+}
+
+// PasskeyCredentialInput is a PublicKeyCredential serialized by the browser, binary fields base64url encoded
+type PasskeyCredentialInput struct {
+	ID       string `json:"id" example:"kq3Bx1..."`
+	Type     string `json:"type" example:"public-key"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject,omitempty"`
+		AuthenticatorData string `json:"authenticatorData,omitempty"`
+		Signature         string `json:"signature,omitempty"`
+		UserHandle        string `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+// PasskeyRegisterInput represents a finished passkey registration
+type PasskeyRegisterInput struct {
+	Name       string                 `json:"name" example:"MacBook Touch ID"`
+	Credential PasskeyCredentialInput `json:"credential"`
+}
+
+// PasskeyLoginBeginInput optionally limits the login to the passkeys of one account
+type PasskeyLoginBeginInput struct {
+	Email string `json:"email" example:"user@example.com"`
+}
+
+// PasskeyLoginInput represents a passkey assertion
+type PasskeyLoginInput struct {
+	Credential PasskeyCredentialInput `json:"credential"`
+	DeviceName string                 `json:"device_name" example:"iPhone"`
+}
+
+// PasskeyResponse represents a registered passkey
+type PasskeyResponse struct {
+	ID           uint       `json:"id" example:"1"`
+	Name         string     `json:"name" example:"MacBook Touch ID"`
+	CredentialID string     `json:"credential_id" example:"kq3Bx1..."`
+	AAGUID       string     `json:"aaguid" example:"adce000235bcc60a648b0b25f1f05503"`
+	LastUsedAt   *time.Time `json:"last_used_at" example:"2024-01-01T00:00:00Z"`
+	CreatedAt    time.Time  `json:"created_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// CategoryResponse represents a category; the tree endpoint nests children
+type CategoryResponse struct {
+	ID       uint               `json:"id" example:"3"`
+	Name     string             `json:"name" example:"Phones"`
+	Slug     string             `json:"slug" example:"phones"`
+	ParentID *uint              `json:"parent_id" example:"1"`
+	Position int                `json:"position" example:"0"`
+	Children []CategoryResponse `json:"children,omitempty"`
+}
+
+// CategoryCrumb is one step of the path from the root category
+type CategoryCrumb struct {
+	ID   uint   `json:"id" example:"1"`
+	Name string `json:"name" example:"Electronics"`
+	Slug string `json:"slug" example:"electronics"`
+}
+
+// CategoryDetailResponse represents a category page
+type CategoryDetailResponse struct {
+	Category    CategoryResponse `json:"category"`
+	Breadcrumbs []CategoryCrumb  `json:"breadcrumbs"`
+}
+
+// CategoryInput represents a category to create or update
+type CategoryInput struct {
+	Name     string `json:"name" binding:"required" example:"Phones"`
+	Slug     string `json:"slug" example:"phones"`
+	ParentID *uint  `json:"parent_id" example:"1"`
+	Position int    `json:"position" example:"0"`
+}
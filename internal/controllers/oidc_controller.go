@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListOIDCProviders godoc
+// @Summary List social login providers
+// @Description Returns the names of the configured OIDC providers
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} OIDCProvidersResponse
+// @Router /auth/oidc/providers [get]
+func ListOIDCProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": services.OIDCProviderNames()})
+}
+
+// OIDCLogin godoc
+// @Summary Start social login
+// @Description Redirects to the provider's authorization page (authorization code flow with PKCE)
+// @Tags Auth
+// @Param provider path string true "Provider name"
+// @Param login_hint query string false "Email to pre-fill at the provider"
+// @Success 302
+// @Failure 404 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /auth/oidc/{provider}/login [get]
+func OIDCLogin(c *gin.Context) {
+	authURL, err := services.OIDCAuthorizationURL(c.Param("provider"), c.Query("login_hint"))
+	if errors.Is(err, services.ErrUnknownOIDCProvider) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown provider"})
+		return
+	}
+	if err != nil {
+		log.Println("oidc login:", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider unavailable"})
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback godoc
+// @Summary Finish social login
+// @Description Provider redirect target. Links the external identity to an existing account by verified email or creates a new customer, then logs in like /auth/login.
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /auth/oidc/{provider}/callback [get]
+func OIDCCallback(c *gin.Context) {
+	provider := c.Param("provider")
+	if e := c.Query("error"); e != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider returned " + e})
+		return
+	}
+	if c.Query("code") == "" || c.Query("state") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code and state are required"})
+		return
+	}
+
+	identity, err := services.OIDCExchangeCode(provider, c.Query("state"), c.Query("code"))
+	if errors.Is(err, services.ErrUnknownOIDCProvider) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown provider"})
+		return
+	}
+	if err != nil {
+		log.Println("oidc callback:", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "social login failed"})
+		return
+	}
+
+	user, status, msg := resolveOIDCUser(identity)
+	if user == nil {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	beginLogin(c, user, provider)
+}
+
+// resolveOIDCUser finds the local account for an external identity: an existing link,
+// else an account with the same verified email, else a new customer.
+// On failure the user is nil and status/msg describe the error.
+func resolveOIDCUser(identity *services.OIDCIdentity) (*models.User, int, string) {
+	link, err := database.GetUserIdentity(identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "db error"
+	}
+	if link != nil {
+		user, err := database.GetUserByID(link.UserID)
+		if err != nil {
+			return nil, http.StatusInternalServerError, "db error"
+		}
+		return user, 0, ""
+	}
+
+	// without a verified email we can't know whose account this is
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+	if email == "" || !identity.EmailVerified {
+		return nil, http.StatusUnauthorized, "provider did not return a verified email"
+	}
+
+	newLink := &models.UserIdentity{Provider: identity.Provider, Subject: identity.Subject, Email: email}
+
+	user, err := database.GetUserByEmail(email)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "db error"
+	}
+	if user != nil {
+		// the mock vouches for any address it is asked for
+		if services.IsMockOIDC(identity.Provider) {
+			return nil, http.StatusConflict, "mock provider identities cannot be linked to existing accounts"
+		}
+		// linking to an account nobody proved to own would let whoever signed up
+		// with that address first share it with the provider account
+		if user.VerifiedAt == nil {
+			return nil, http.StatusConflict, "an unverified account with this email exists, verify it or log in with your password first"
+		}
+		newLink.UserID = user.ID
+		if err := database.CreateUserIdentity(newLink); err != nil {
+			return nil, http.StatusInternalServerError, "failed to link identity"
+		}
+		return user, 0, ""
+	}
+
+	name := identity.Name
+	if name == "" {
+		name = strings.SplitN(email, "@", 2)[0]
+	}
+	now := time.Now()
+	user = &models.User{
+		Name:       name,
+		Email:      email,
+		Role:       "customer",
+		VerifiedAt: &now, // the provider vouched for the address
+	}
+	if err := database.CreateUserWithIdentity(user, newLink); err != nil {
+		return nil, http.StatusInternalServerError, "failed to create user"
+	}
+	return user, 0, ""
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -8,6 +9,7 @@ import (
 
 	"ecommerce-gin/internal/database"
 	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
 )
 
 // Checkout godoc
@@ -44,7 +46,24 @@ func Checkout(c *gin.Context) {
 
 	// 3. Validate stock + prepare order items
 	for _, ci := range cartItems {
-		if ci.Quantity > ci.Product.Quantity {
+		// added before the product got variants
+		if ci.VariantID == nil {
+			if n, _ := database.CountProductVariants(ci.ProductID); n > 0 {
+				tx.Rollback()
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "choose a variant of product " + ci.Product.Name,
+				})
+				return
+			}
+		}
+		if ci.Variant != nil && !ci.Variant.IsActive {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "variant " + ci.Variant.SKU + " is no longer available",
+			})
+			return
+		}
+		if ci.Quantity > ci.Stock() {
 			tx.Rollback()
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "not enough stock for product " + ci.Product.Name,
@@ -52,11 +71,18 @@ func Checkout(c *gin.Context) {
 			return
 		}
 
-		price := ci.Product.Price
+		price := ci.UnitPrice()
 		subtotal := float64(ci.Quantity) * price
 
+		sku := ""
+		if ci.Variant != nil {
+			sku = ci.Variant.SKU
+		}
+
 		orderItems = append(orderItems, models.OrderItem{
 			ProductID: ci.ProductID,
+			VariantID: ci.VariantID,
+			SKU:       sku,
 			Quantity:  ci.Quantity,
 			Price:     price,
 			Subtotal:  subtotal,
@@ -65,7 +91,14 @@ func Checkout(c *gin.Context) {
 		total += subtotal
 
 		// Deduct stock
-		err := database.DeductProductStock(tx, ci.ProductID, ci.Quantity)
+		err := database.DeductProductStock(tx, ci.ProductID, ci.VariantID, ci.Quantity)
+		if errors.Is(err, database.ErrInsufficientStock) {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "not enough stock for product " + ci.Product.Name,
+			})
+			return
+		}
 		if err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "stock update failed"})
@@ -111,6 +144,20 @@ func Checkout(c *gin.Context) {
 		return
 	}
 
+	// stock changed, keep the in-stock facet right
+	productIDs := make([]uint, 0, len(cartItems))
+	for _, ci := range cartItems {
+		productIDs = append(productIDs, ci.ProductID)
+	}
+	services.ReindexProducts(productIDs...)
+
+	recordAudit(c, auditEntry{
+		Action:     "order.create",
+		EntityType: "order",
+		EntityID:   order.ID,
+		After:      gin.H{"total_price": total, "items": len(orderItems), "status": order.Status},
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "order placed",
 		"order_id": order.ID,
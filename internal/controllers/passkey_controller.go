@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
+	"ecommerce-gin/internal/webauthn"
+
+	"github.com/gin-gonic/gin"
+)
+
+// passkeyCredential is a PublicKeyCredential as serialized by the browser,
+// binary fields base64url encoded
+type passkeyCredential struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+		AttestationObject string `json:"attestationObject"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// decode returns the named response fields, or an error if one is not valid base64url
+func (p *passkeyCredential) decode(fields ...string) ([][]byte, error) {
+	values := map[string]string{
+		"clientDataJSON":    p.Response.ClientDataJSON,
+		"attestationObject": p.Response.AttestationObject,
+		"authenticatorData": p.Response.AuthenticatorData,
+		"signature":         p.Response.Signature,
+		"userHandle":        p.Response.UserHandle,
+	}
+	out := make([][]byte, len(fields))
+	for i, f := range fields {
+		b, err := webauthn.DecodeBase64(values[f])
+		if err != nil {
+			return nil, errors.New("invalid " + f)
+		}
+		out[i] = b
+	}
+	return out, nil
+}
+
+// PasskeyRegisterBegin godoc
+// @Summary Start passkey registration
+// @Description Returns the options for navigator.credentials.create. Binary fields are base64url encoded.
+// @Tags Auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} PasskeyCreationOptions
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/auth/passkeys/register/begin [post]
+func PasskeyRegisterBegin(c *gin.Context) {
+	user := currentUser(c)
+	if user == nil {
+		return
+	}
+
+	options, err := services.BeginPasskeyRegistration(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start passkey registration"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// PasskeyRegisterFinish godoc
+// @Summary Finish passkey registration
+// @Description Verifies the credential created by the authenticator and adds it to the account
+// @Tags Auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body PasskeyRegisterInput true "Name and credential"
+// @Success 201 {object} PasskeyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /api/auth/passkeys/register/finish [post]
+func PasskeyRegisterFinish(c *gin.Context) {
+	var body struct {
+		Name       string            `json:"name" binding:"max=100"`
+		Credential passkeyCredential `json:"credential"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	fields, err := body.Credential.decode("clientDataJSON", "attestationObject")
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	if user == nil {
+		return
+	}
+
+	passkey, err := services.FinishPasskeyRegistration(user, body.Name, fields[0], fields[1])
+	if errors.Is(err, services.ErrPasskeyExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	database.CreateSecurityEvent(&models.SecurityEvent{
+		UserID:    user.ID,
+		Type:      "passkey_added",
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Details:   passkey.Name,
+	})
+	recordAudit(c, auditEntry{Action: "passkey.create", EntityType: "passkey", EntityID: passkey.ID, After: passkey})
+
+	c.JSON(http.StatusCreated, passkey)
+}
+
+// ListPasskeys godoc
+// @Summary List passkeys
+// @Description Lists the passkeys registered for the logged in user
+// @Tags Auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} PasskeyResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/auth/passkeys [get]
+func ListPasskeys(c *gin.Context) {
+	uid, ok := contextUint(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	passkeys, err := database.ListPasskeys(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load passkeys"})
+		return
+	}
+	c.JSON(http.StatusOK, passkeys)
+}
+
+// DeletePasskey godoc
+// @Summary Delete a passkey
+// @Description Removes a passkey from the account; it can no longer be used to log in
+// @Tags Auth
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Passkey ID"
+// @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/auth/passkeys/{id} [delete]
+func DeletePasskey(c *gin.Context) {
+	uid, ok := contextUint(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id := parseUint(c.Param("id"))
+
+	deleted, err := database.DeletePasskey(uid, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete passkey"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "passkey not found"})
+		return
+	}
+
+	database.CreateSecurityEvent(&models.SecurityEvent{
+		UserID:    uid,
+		Type:      "passkey_removed",
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	recordAudit(c, auditEntry{Action: "passkey.delete", EntityType: "passkey", EntityID: id})
+
+	c.JSON(http.StatusOK, gin.H{"message": "passkey deleted"})
+}
+
+// PasskeyLoginBegin godoc
+// @Summary Start passkey login
+// @Description Returns the options for navigator.credentials.get. Without an email the browser offers any passkey saved for this site.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body PasskeyLoginBeginInput false "Optional email"
+// @Success 200 {object} PasskeyRequestOptions
+// @Failure 422 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/passkeys/login/begin [post]
+func PasskeyLoginBegin(c *gin.Context) {
+	var body struct {
+		Email string `json:"email" binding:"omitempty,email"`
+	}
+	// the body is optional
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	// an unknown email gets the same answer as a known one without passkeys
+	var userID uint
+	if body.Email != "" {
+		if user, err := database.GetUserByEmail(body.Email); err == nil && user != nil {
+			userID = user.ID
+		}
+	}
+
+	options, err := services.BeginPasskeyLogin(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start passkey login"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// PasskeyLoginFinish godoc
+// @Summary Log in with a passkey
+// @Description Verifies the assertion from navigator.credentials.get and opens a session. A passkey that verified the user (PIN, biometrics) counts as two factors; otherwise accounts with 2FA get an mfa_token for /auth/login/2fa.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body PasskeyLoginInput true "Credential"
+// @Success 200 {object} LoginResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /auth/passkeys/login/finish [post]
+func PasskeyLoginFinish(c *gin.Context) {
+	var body struct {
+		Credential passkeyCredential `json:"credential"`
+		DeviceName string            `json:"device_name"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	fields, err := body.Credential.decode("clientDataJSON", "authenticatorData", "signature", "userHandle")
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	passkey, userVerified, err := services.FinishPasskeyLogin(body.Credential.ID, fields[0], fields[1], fields[2], fields[3])
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "passkey login failed"})
+		return
+	}
+
+	user, err := database.GetUserByID(passkey.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "passkey login failed"})
+		return
+	}
+
+	if userVerified {
+		startSession(c, user, body.DeviceName, true)
+		return
+	}
+	beginLogin(c, user, body.DeviceName)
+}
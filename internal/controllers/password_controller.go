@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/config"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
+	"ecommerce-gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ForgotPassword godoc
+// @Summary Request password reset
+// @Description Emails a single-use, time-limited reset link if the account exists. Always answers the same way.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body ForgotPasswordInput true "Email"
+// @Success 200 {object} MessageResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /auth/password/forgot [post]
+func ForgotPassword(c *gin.Context) {
+	var body struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	// same answer whether or not the account exists
+	resp := gin.H{"message": "if the account exists, a reset link has been sent"}
+
+	// at most one email per address per minute
+	key := "password:forgot:" + strings.ToLower(body.Email)
+	if ok, err := cache.Rdb.SetNX(cache.Ctx, key, "1", time.Minute).Result(); err != nil || !ok {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	user, err := database.GetUserByEmail(body.Email)
+	if err != nil || user == nil {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	if err := sendPasswordResetEmail(user); err != nil {
+		log.Println("failed to send password reset email:", err)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func sendPasswordResetEmail(user *models.User) error {
+	raw, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return err
+	}
+	hashed, _ := utils.HashToken(raw)
+
+	// only the newest link works
+	if err := database.InvalidateUserTokens(user.ID, "password_reset"); err != nil {
+		return err
+	}
+	token := &models.UserToken{
+		UserID:    user.ID,
+		Purpose:   "password_reset",
+		TokenHash: hashed,
+		ExpiresAt: time.Now().Add(time.Minute * time.Duration(config.Cfg.PasswordResetMins)),
+	}
+	if err := database.CreateUserToken(token); err != nil {
+		return err
+	}
+
+	// a client page that asks for the new password and posts it to /auth/password/reset
+	link := config.Cfg.FrontendBaseURL + "/reset-password?token=" + url.QueryEscape(raw)
+	return services.SendMail(services.Mail{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nYou can choose a new password using this link:\n\n%s\n\nThe link expires in %d minutes and can only be used once. If you did not ask for this, ignore this email.\n",
+			user.Name, link, config.Cfg.PasswordResetMins),
+	})
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Sets a new password using a reset token and logs the user out everywhere
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body ResetPasswordInput true "Reset token and new password"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /auth/password/reset [post]
+func ResetPassword(c *gin.Context) {
+	var body struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	// checked before the token is used up, so a rejected password can be retried
+	if err := services.ValidatePassword(body.Password, ""); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashed, _ := utils.HashToken(body.Token)
+	token, err := database.ConsumeUserToken("password_reset", hashed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if token == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired reset token"})
+		return
+	}
+
+	user, err := database.GetUserByID(token.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired reset token"})
+		return
+	}
+
+	if !setPassword(c, user, body.Password, "password_reset") {
+		return
+	}
+
+	// following the emailed link proves ownership of the address
+	if user.VerifiedAt == nil {
+		database.MarkUserVerified(user.ID, time.Now())
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password reset, please log in again"})
+}
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Changes the password of the logged in user and logs them out everywhere
+// @Tags Auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body ChangePasswordInput true "Current and new password"
+// @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /api/auth/password/change [post]
+func ChangePassword(c *gin.Context) {
+	var body struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	uid, ok := contextUint(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	user, err := database.GetUserByID(uid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if !utils.CheckPassword(user.Password, body.CurrentPassword) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "current password is incorrect"})
+		return
+	}
+	if err := services.ValidatePassword(body.NewPassword, user.Email); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !setPassword(c, user, body.NewPassword, "password_changed") {
+		return
+	}
+
+	clearRefreshCookie(c)
+	c.JSON(http.StatusOK, gin.H{"message": "password changed, please log in again"})
+}
+
+// setPassword hashes and stores a new password, then revokes every session and
+// access token of the user. Returns false if the response has been written.
+func setPassword(c *gin.Context, user *models.User, password, event string) bool {
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		return false
+	}
+	if err := database.UpdateUserPassword(user.ID, hashed); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update password"})
+		return false
+	}
+	if err := revokeUserAccess(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return false
+	}
+
+	recordAudit(c, auditEntry{ActorID: user.ID, Action: "auth." + event, EntityType: "user", EntityID: user.ID})
+
+	database.CreateSecurityEvent(&models.SecurityEvent{
+		UserID:    user.ID,
+		Type:      event,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+
+	err = services.SendMail(services.Mail{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body:    fmt.Sprintf("Hi %s,\n\nThe password of your account was just changed and all devices were logged out. If this was not you, reset your password immediately.\n", user.Name),
+	})
+	if err != nil {
+		log.Println("failed to send password changed email:", err)
+	}
+	return true
+}
@@ -39,6 +39,13 @@ func StartPaymentHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, auditEntry{
+		Action:     "payment.start",
+		EntityType: "payment_intent",
+		EntityID:   intent.ID,
+		After:      gin.H{"order_id": order.ID, "amount": intent.Amount},
+	})
+
 	// Fake payment gateway checkout URL
 	redirectURL := "http://localhost:8080/pay-gateway?intent=" + strconv.Itoa(int(intent.ID))
 
@@ -33,6 +33,14 @@ func PaymentWebhook(c *gin.Context) {
 	// update order to confirmed
 	database.UpdateOrderStatus(nil, intent.OrderID, "confirmed")
 
+	recordAudit(c, auditEntry{
+		Action:     "payment.paid",
+		EntityType: "payment_intent",
+		EntityID:   intent.ID,
+		Before:     gin.H{"status": intent.Status},
+		After:      gin.H{"status": "paid", "order_id": intent.OrderID},
+	})
+
 	c.JSON(200, gin.H{
 		"message": "payment successful",
 		"order":   intent.OrderID,
@@ -10,20 +10,16 @@ import (
 	"ecommerce-gin/internal/cache"
 	"ecommerce-gin/internal/database"
 	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/search"
+	"ecommerce-gin/internal/services"
+	"ecommerce-gin/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Helper to create slugs
-func generateSlug(name string) string {
-	slug := strings.ToLower(strings.TrimSpace(name))
-	slug = strings.ReplaceAll(slug, " ", "-")
-	return slug
-}
-
 // CreateProduct godoc
 // @Summary Create a new product (Admin only)
-// @Description Creates a new product in the system
+// @Description Creates a new product in the system. image_url must come from the upload endpoints.
 // @Tags Products
 // @Security BearerAuth
 // @Accept json
@@ -39,7 +35,7 @@ func CreateProduct(c *gin.Context) {
 		Description string  `json:"description"`
 		Price       float64 `json:"price" binding:"required"`
 		Quantity    int     `json:"quantity" binding:"required"`
-		Category    string  `json:"category"`
+		CategoryID  *uint   `json:"category_id"`
 		ImageURL    string  `json:"image_url"`
 	}
 
@@ -48,30 +44,46 @@ func CreateProduct(c *gin.Context) {
 		return
 	}
 
-	slug := generateSlug(body.Name)
+	if body.CategoryID != nil {
+		if _, err := database.GetCategoryByID(*body.CategoryID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "category not found"})
+			return
+		}
+	}
+	if body.ImageURL != "" && !services.IsUploadedImageURL(body.ImageURL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "image_url must point to an uploaded image"})
+		return
+	}
 
+	// the slug is picked from the name when the product is stored
 	product := &models.Product{
 		Name:        body.Name,
-		Slug:        slug,
 		Description: body.Description,
 		Price:       body.Price,
 		Quantity:    body.Quantity,
-		Category:    body.Category,
-		ImageURL:    body.ImageURL,
+		CategoryID:  body.CategoryID,
 		IsActive:    true,
 	}
+	// a single image given with the product starts its gallery
+	if body.ImageURL != "" {
+		product.ImageURL = body.ImageURL
+		product.Images = []models.ProductImage{{URL: body.ImageURL, IsPrimary: true}}
+	}
 
 	if err := database.CreateProduct(product); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create product"})
 		return
 	}
+	services.ReindexProducts(product.ID)
+
+	recordAudit(c, auditEntry{Action: "product.create", EntityType: "product", EntityID: product.ID, After: product})
 
 	c.JSON(http.StatusCreated, product)
 }
 
 // UpdateProduct godoc
 // @Summary Update a product (Admin only)
-// @Description Updates product details
+// @Description Updates product details. A new name gives the product a new slug unless one is given; the old slug keeps redirecting to the product. Ratings and sales counts are computed and cannot be set.
 // @Tags Products
 // @Security BearerAuth
 // @Accept json
@@ -81,10 +93,11 @@ func CreateProduct(c *gin.Context) {
 // @Success 200 {object} MessageResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Router /api/admin/products/{id} [put]
 func UpdateProduct(c *gin.Context) {
 	id := c.Param("id")
-	cache.Delete("product:" + id) // invalidate cache
 
 	var body map[string]any
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -92,17 +105,65 @@ func UpdateProduct(c *gin.Context) {
 		return
 	}
 
+	before, err := database.GetProductByID(parseUint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+
+	// the free-text category is gone, products point into the category tree
+	if _, exists := body["category"]; exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "category is no longer supported, use category_id"})
+		return
+	}
+	if categoryID, exists := body["category_id"]; exists && categoryID != nil {
+		id, ok := categoryID.(float64)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category_id"})
+			return
+		}
+		if _, err := database.GetCategoryByID(uint(id)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "category not found"})
+			return
+		}
+	}
+
+	// the image follows the gallery's primary image
+	if _, exists := body["image_url"]; exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "image_url is set through the product images endpoints"})
+		return
+	}
+
+	// ratings follow the approved reviews and sales follow the orders
+	for _, field := range []string{"rating_average", "rating_count", "sales_count"} {
+		if _, exists := body[field]; exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": field + " is kept up to date by the shop and cannot be set"})
+			return
+		}
+	}
+
+	// stock of products with variants is the sum of the variants' stock
+	if _, exists := body["quantity"]; exists {
+		if n, _ := database.CountProductVariants(parseUint(id)); n > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "quantity of a product with variants is set per variant"})
+			return
+		}
+	}
+
 	body["updated_at"] = time.Now()
 
-	// regenerate slug if name changed
-	if name, exists := body["name"]; exists {
-		body["slug"] = generateSlug(name.(string))
+	if !assignProductSlug(c, before, body) {
+		return
 	}
 
-	if err := database.UpdateProduct(parseUint(id), body); err != nil {
+	if err := database.UpdateProduct(before.ID, body); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
 	}
+	cache.Delete("product:" + before.Slug)
+	services.ReindexProducts(before.ID)
+
+	recordAudit(c, auditEntry{Action: "product.update", EntityType: "product", EntityID: id, Before: before, After: body})
 
 	c.JSON(http.StatusOK, gin.H{"message": "updated"})
 }
@@ -120,22 +181,36 @@ func UpdateProduct(c *gin.Context) {
 // @Router /api/admin/products/{id} [delete]
 func DeleteProduct(c *gin.Context) {
 	id := c.Param("id")
-	cache.Delete("product:" + id) // invalidate cache
+
+	before, _ := database.GetProductByID(parseUint(id))
 
 	if err := database.DeleteProduct(parseUint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
 	}
+	if before != nil {
+		cache.Delete("product:" + before.Slug)
+	}
+	services.ReindexProducts(parseUint(id))
+
+	entry := auditEntry{Action: "product.delete", EntityType: "product", EntityID: id}
+	if before != nil {
+		entry.Before = before
+	}
+	recordAudit(c, entry)
+
 	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
 }
 
 // GetProduct godoc
 // @Summary Get product by slug
-// @Description Retrieves a single product by its slug (cached)
+// @Description Retrieves a single product by its slug (cached), with its options and active variants for the option pickers. A slug the product had before answers with a 301 to its current URL.
 // @Tags Products
 // @Produce json
 // @Param slug path string true "Product Slug"
 // @Success 200 {object} Product
+// @Success 301 {string} string "Moved to /products/{current slug}, see the Location header"
+// @Header 301 {string} Location "URL of the product under its current slug"
 // @Failure 404 {object} ErrorResponse
 // @Router /products/{slug} [get]
 func GetProduct(c *gin.Context) {
@@ -153,9 +228,12 @@ func GetProduct(c *gin.Context) {
 	// Load from DB
 	product, err := database.GetProductBySlug(slug)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		redirectOldProductSlug(c, slug)
 		return
 	}
+	if product.CategoryID != nil {
+		product.Breadcrumbs, _ = database.CategoryBreadcrumbs(*product.CategoryID)
+	}
 
 	jsonData, _ := json.Marshal(product)
 
@@ -166,27 +244,95 @@ func GetProduct(c *gin.Context) {
 	c.JSON(http.StatusOK, product)
 }
 
+// redirectOldProductSlug sends links to a slug a product had before to its
+// current URL, and answers 404 otherwise
+func redirectOldProductSlug(c *gin.Context, slug string) {
+	if productID, err := database.GetProductIDByOldSlug(slug); err == nil && productID != 0 {
+		if product, err := database.GetProductByID(productID); err == nil {
+			c.Redirect(http.StatusMovedPermanently, "/products/"+product.Slug)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+}
+
+// assignProductSlug checks an explicit slug in the update, or generates one when
+// the name changes. Returns false if the response has been written.
+func assignProductSlug(c *gin.Context, product *models.Product, body map[string]any) bool {
+	if raw, exists := body["slug"]; exists {
+		requested, ok := raw.(string)
+		slug := utils.Slugify(requested)
+		if !ok || slug == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid slug"})
+			return false
+		}
+		taken, err := database.ProductSlugTaken(database.DB, slug, product.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return false
+		}
+		if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": "slug already in use"})
+			return false
+		}
+		body["slug"] = slug
+		return true
+	}
+
+	raw, exists := body["name"]
+	if !exists {
+		return true
+	}
+	name, ok := raw.(string)
+	if !ok || strings.TrimSpace(name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid name"})
+		return false
+	}
+	// an unchanged slug stays as it is, e.g. when only the case of the name changes
+	if utils.Slugify(name) == utils.Slugify(product.Name) {
+		return true
+	}
+	slug, err := database.NewProductSlug(database.DB, name, product.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return false
+	}
+	body["slug"] = slug
+	return true
+}
+
 // ListProducts godoc
 // @Summary List all products
-// @Description Gets a paginated list of products with optional filtering
+// @Description Gets a paginated list of products with optional filtering and sorting. Invalid parameters are answered with a 400 listing each of them.
 // @Tags Products
 // @Produce json
 // @Param page query int false "Page number" default(1)
-// @Param limit query int false "Items per page" default(10)
-// @Param search query string false "Search term"
-// @Param category query string false "Filter by category"
+// @Param limit query int false "Items per page, at most 100" default(10)
+// @Param search query string false "Search term; results are then ranked by relevance unless sort is given"
+// @Param category query []string false "Category slugs, repeated or comma separated; products in their subcategories are included" collectionFormat(multi)
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param in_stock query bool false "Only products in stock (true) or out of stock (false)"
+// @Param sort query string false "Comma separated keys among price, newest, name, popularity, rating, each optionally followed by :asc or :desc, e.g. popularity:desc,price"
 // @Success 200 {object} ProductListResponse
+// @Failure 400 {object} QueryErrorResponse
 // @Router /products [get]
 func ListProducts(c *gin.Context) {
+	listing, ok := parseProductListing(c, "search")
+	if !ok {
+		return
+	}
 
-	page := parseIntQuery(c, "page", 1)
-	limit := parseIntQuery(c, "limit", 10)
-	search := c.Query("search")
-	category := c.Query("category")
-
-	offset := (page - 1) * limit
-
-	products, total, err := database.ListProducts(limit, offset, search, category)
+	var products []models.Product
+	var total int64
+	var err error
+	if listing.text != "" {
+		// matches come from the search index, see SearchProducts for facets
+		result := services.SearchProducts(listing.searchQuery())
+		products, total, err = listing.page(result.Hits)
+	} else {
+		products, total, err = database.ListProducts(listing.limit, listing.offset(), listing.filter)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
 		return
@@ -195,12 +341,137 @@ func ListProducts(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"items":      products,
 		"total":      total,
-		"page":       page,
-		"limit":      limit,
-		"totalPages": (total + int64(limit) - 1) / int64(limit),
+		"page":       listing.pageNum,
+		"limit":      listing.limit,
+		"totalPages": (total + int64(listing.limit) - 1) / int64(listing.limit),
 	})
 }
 
+// default direction of each sort key
+var productSortDesc = map[string]bool{"newest": true, "popularity": true, "rating": true}
+
+// productListing is a validated listing request
+type productListing struct {
+	pageNum int
+	limit   int
+	text    string
+	filter  database.ProductFilter
+}
+
+// parseProductListing reads paging, text, filters and sort; textKey names the
+// search text parameter. Returns false if the response has been written.
+func parseProductListing(c *gin.Context, textKey string) (*productListing, bool) {
+	params := newQueryParams(c)
+	l := &productListing{
+		pageNum: params.intRange("page", 1, 1, 1<<20),
+		limit:   params.intRange("limit", 10, 1, 100),
+		text:    strings.TrimSpace(c.Query(textKey)),
+	}
+	l.filter.MinPrice = params.price("min_price")
+	l.filter.MaxPrice = params.price("max_price")
+	if l.filter.MinPrice != nil && l.filter.MaxPrice != nil && *l.filter.MinPrice > *l.filter.MaxPrice {
+		params.fail("max_price", "must not be below min_price")
+	}
+	l.filter.InStock = params.boolean("in_stock")
+
+	for _, key := range params.list("sort") {
+		name, dir, hasDir := strings.Cut(key, ":")
+		if !database.IsProductSortKey(name) {
+			params.fail("sort", "unknown sort key "+name+", use price, newest, name, popularity or rating")
+			continue
+		}
+		desc := productSortDesc[name]
+		if hasDir {
+			switch dir {
+			case "asc":
+				desc = false
+			case "desc":
+				desc = true
+			default:
+				params.fail("sort", "direction of "+name+" must be asc or desc")
+				continue
+			}
+		}
+		l.filter.Sort = append(l.filter.Sort, database.ProductSort{Key: name, Desc: desc})
+	}
+
+	// each category includes everything below it
+	if slugs := params.list("category"); len(slugs) > 0 {
+		seen := map[uint]bool{}
+		l.filter.CategoryIDs = []uint{}
+		for _, slug := range slugs {
+			cat, err := database.GetCategoryBySlug(slug)
+			if err != nil {
+				params.fail("category", "unknown category "+slug)
+				continue
+			}
+			ids, err := database.CategoryDescendantIDs(cat.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+				return nil, false
+			}
+			for _, id := range ids {
+				if !seen[id] {
+					seen[id] = true
+					l.filter.CategoryIDs = append(l.filter.CategoryIDs, id)
+				}
+			}
+		}
+	}
+
+	if params.abort() {
+		return nil, false
+	}
+	return l, true
+}
+
+func (l *productListing) offset() int {
+	return (l.pageNum - 1) * l.limit
+}
+
+func (l *productListing) searchQuery() search.Query {
+	return search.Query{
+		Text:        l.text,
+		CategoryIDs: l.filter.CategoryIDs,
+		InStock:     l.filter.InStock,
+		MinPrice:    l.filter.MinPrice,
+		MaxPrice:    l.filter.MaxPrice,
+	}
+}
+
+// page loads one page of search hits: in relevance order, or with an explicit
+// sort, ordered by the database among the hits. Without text every product
+// matches, so the database alone does.
+func (l *productListing) page(hits []search.Hit) ([]models.Product, int64, error) {
+	if l.text == "" {
+		return database.ListProducts(l.limit, l.offset(), l.filter)
+	}
+	if len(l.filter.Sort) > 0 {
+		filter := l.filter
+		filter.IDs = make([]uint, 0, len(hits))
+		for _, h := range hits {
+			filter.IDs = append(filter.IDs, h.ID)
+		}
+		return database.ListProducts(l.limit, l.offset(), filter)
+	}
+
+	total := int64(len(hits))
+	start := l.offset()
+	if start >= len(hits) {
+		return []models.Product{}, total, nil
+	}
+	end := start + l.limit
+	if end > len(hits) {
+		end = len(hits)
+	}
+	ids := make([]uint, 0, end-start)
+	for _, h := range hits[start:end] {
+		ids = append(ids, h.ID)
+	}
+	products, err := database.GetProductsByIDs(ids)
+	return products, total, err
+}
+
 // helpers
 func parseUint(s string) uint {
 	var id uint
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUpdateProductRejectsComputedFields(t *testing.T) {
+	store := testutil.UseDB(t, &database.DB)
+	testutil.UseRedis(t, &cache.Rdb)
+	product := &models.Product{Name: "Laptop", Slug: "laptop", Price: 900, IsActive: true, SalesCount: 3}
+	if err := database.DB.Create(product).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/products/:id", UpdateProduct)
+	for _, body := range []string{`{"rating_average":5}`, `{"rating_count":100}`, `{"price":800,"sales_count":0}`} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/products/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s: %d %s, want 400", body, w.Code, w.Body)
+		}
+	}
+
+	row := store.Rows("products")[0]
+	if row["price"] != float64(900) || row["sales_count"] != int64(3) {
+		t.Errorf("product = %v, want it unchanged", row)
+	}
+}
+
+func TestCreateProductRequiresUploadedImage(t *testing.T) {
+	store := testutil.UseDB(t, &database.DB)
+	testutil.UseRedis(t, &cache.Rdb)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/products", CreateProduct)
+	create := func(imageURL string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/products",
+			strings.NewReader(`{"name":"Laptop","price":900,"quantity":1,"image_url":"`+imageURL+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := create("https://elsewhere.example.com/laptop.jpg"); code != http.StatusBadRequest {
+		t.Errorf("outside image: %d, want 400", code)
+	}
+	if code := create("/uploads/20240101120000.jpg"); code != http.StatusCreated {
+		t.Fatalf("uploaded image: %d, want 201", code)
+	}
+	images := store.Rows("product_images")
+	if len(images) != 1 || images[0]["url"] != "/uploads/20240101120000.jpg" {
+		t.Errorf("images = %v, want only the uploaded one", images)
+	}
+}
@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type productImageInput struct {
+	URL     string `json:"url" binding:"required,max=1024"`
+	AltText string `json:"alt_text" binding:"max=255"`
+	Width   int    `json:"width" binding:"min=0"`
+	Height  int    `json:"height" binding:"min=0"`
+	Primary bool   `json:"primary"`
+}
+
+// AttachProductImage godoc
+// @Summary Attach an image to a product (Admin only)
+// @Description Adds an image returned by the upload endpoint at the end of the product's gallery. The first image, or one attached with primary set, becomes the primary image shown in lists.
+// @Tags Products
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param body body ProductImageInput true "Image"
+// @Success 201 {object} ProductImage
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/products/{id}/images [post]
+func AttachProductImage(c *gin.Context) {
+	var body productImageInput
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !services.IsUploadedImageURL(body.URL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url must point to an uploaded image"})
+		return
+	}
+
+	product, err := database.GetProductByID(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+
+	img := &models.ProductImage{
+		ProductID: product.ID,
+		URL:       body.URL,
+		AltText:   strings.TrimSpace(body.AltText),
+		Width:     body.Width,
+		Height:    body.Height,
+		IsPrimary: body.Primary,
+	}
+	if err := database.AttachProductImage(img); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to attach image"})
+		return
+	}
+	cache.Delete("product:" + product.Slug)
+
+	recordAudit(c, auditEntry{Action: "product.image_attach", EntityType: "product_image", EntityID: img.ID, After: img})
+
+	c.JSON(http.StatusCreated, img)
+}
+
+// UpdateProductImage godoc
+// @Summary Update a product image (Admin only)
+// @Description Changes the alt text of an image, or makes it the primary image; fields left out are kept. To change the primary image, make another one primary.
+// @Tags Products
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param image_id path string true "Image ID"
+// @Param body body UpdateProductImageInput true "Image"
+// @Success 200 {object} ProductImage
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/products/{id}/images/{image_id} [put]
+func UpdateProductImage(c *gin.Context) {
+	var body struct {
+		AltText *string `json:"alt_text" binding:"omitempty,max=255"`
+		Primary *bool   `json:"primary"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := database.GetProductByID(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+	img, err := database.GetProductImage(product.ID, parseUint(c.Param("image_id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+		return
+	}
+	before := *img
+
+	if body.Primary != nil && !*body.Primary && img.IsPrimary {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "make another image primary instead"})
+		return
+	}
+	if body.AltText != nil {
+		img.AltText = strings.TrimSpace(*body.AltText)
+	}
+	if body.Primary != nil && *body.Primary {
+		img.IsPrimary = true
+	}
+
+	if err := database.UpdateProductImage(img); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update image"})
+		return
+	}
+	cache.Delete("product:" + product.Slug)
+
+	recordAudit(c, auditEntry{
+		Action:     "product.image_update",
+		EntityType: "product_image",
+		EntityID:   img.ID,
+		Before:     before,
+		After:      img,
+	})
+
+	c.JSON(http.StatusOK, img)
+}
+
+// ReorderProductImages godoc
+// @Summary Reorder a product's gallery (Admin only)
+// @Description Sets the display order of the images; image_ids must list every image of the product once
+// @Tags Products
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param body body ReorderProductImagesInput true "New order"
+// @Success 200 {array} ProductImage
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/products/{id}/images/order [put]
+func ReorderProductImages(c *gin.Context) {
+	var body struct {
+		ImageIDs []uint `json:"image_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := database.GetProductByID(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+
+	err = database.ReorderProductImages(product.ID, body.ImageIDs)
+	if errors.Is(err, database.ErrImageOrder) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reorder images"})
+		return
+	}
+	cache.Delete("product:" + product.Slug)
+
+	recordAudit(c, auditEntry{
+		Action:     "product.images_reorder",
+		EntityType: "product",
+		EntityID:   product.ID,
+		After:      gin.H{"image_ids": body.ImageIDs},
+	})
+
+	images, err := database.ListProductImages(product.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load images"})
+		return
+	}
+	c.JSON(http.StatusOK, images)
+}
+
+// DetachProductImage godoc
+// @Summary Detach an image from a product (Admin only)
+// @Description Removes an image from the gallery. When it was the primary image, the first remaining one takes over. The uploaded file itself is kept.
+// @Tags Products
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param image_id path string true "Image ID"
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/products/{id}/images/{image_id} [delete]
+func DetachProductImage(c *gin.Context) {
+	product, err := database.GetProductByID(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+	imageID := parseUint(c.Param("image_id"))
+	before, _ := database.GetProductImage(product.ID, imageID)
+
+	err = database.DetachProductImage(product.ID, imageID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to detach image"})
+		return
+	}
+	cache.Delete("product:" + product.Slug)
+
+	entry := auditEntry{Action: "product.image_detach", EntityType: "product_image", EntityID: imageID}
+	if before != nil {
+		entry.Before = before
+	}
+	recordAudit(c, entry)
+
+	c.JSON(http.StatusOK, gin.H{"message": "detached"})
+}
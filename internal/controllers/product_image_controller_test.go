@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUpdateProductImageKeepsOmittedAltText(t *testing.T) {
+	testutil.UseDB(t, &database.DB)
+	testutil.UseRedis(t, &cache.Rdb)
+
+	product := &models.Product{Name: "Laptop", Slug: "laptop", IsActive: true, Images: []models.ProductImage{
+		{URL: "https://cdn.example.com/front.jpg", AltText: "Laptop, front", IsPrimary: true},
+		{URL: "https://cdn.example.com/side.jpg", AltText: "Laptop, side view", Position: 1},
+	}}
+	if err := database.DB.Create(product).Error; err != nil {
+		t.Fatal(err)
+	}
+	side := product.Images[1]
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/products/:id/images/:image_id", UpdateProductImage)
+	update := func(body string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/products/1/images/2", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("PUT %s: %d %s", body, w.Code, w.Body)
+		}
+	}
+
+	update(`{"primary":true}`)
+	img, err := database.GetProductImage(product.ID, side.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !img.IsPrimary || img.AltText != "Laptop, side view" {
+		t.Errorf("after primary: is_primary = %v, alt_text = %q; want true, unchanged", img.IsPrimary, img.AltText)
+	}
+
+	update(`{"alt_text":""}`)
+	if img, _ = database.GetProductImage(product.ID, side.ID); img.AltText != "" || !img.IsPrimary {
+		t.Errorf("after clearing: is_primary = %v, alt_text = %q; want true, empty", img.IsPrimary, img.AltText)
+	}
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ecommerce-gin/internal/config"
+	"ecommerce-gin/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportProducts godoc
+// @Summary Import products (Admin only)
+// @Description Upserts products by slug and variants by SKU from CSV (with a header line) or NDJSON. Columns: slug, sku, name, description, price, quantity, category (slug), image_url, is_active, options. Rows with a SKU describe a variant of the product named by slug. Empty values leave a field unchanged. Every row is validated and nothing is written if any row fails. Larger files, or any file with async=true, run as a background job.
+// @Tags Products
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Param file formData file false "Import file; the request body itself may be the file instead"
+// @Param format query string false "csv or ndjson; guessed from the file name or content type"
+// @Param dry_run query bool false "Validate and report without writing anything"
+// @Param async query bool false "Run as a background job whatever the size"
+// @Success 200 {object} ImportSummaryResponse
+// @Success 202 {object} ImportJobStartedResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 422 {object} ImportSummaryResponse
+// @Router /api/admin/products/import [post]
+func ImportProducts(c *gin.Context) {
+	params := newQueryParams(c)
+	dryRun := params.boolean("dry_run")
+	async := params.boolean("async")
+	if params.abort() {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(config.Cfg.ImportMaxMB)<<20)
+
+	body, name, err := importFile(c)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file is larger than %d MB", config.Cfg.ImportMaxMB)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing import file"})
+		return
+	}
+	defer body.Close()
+
+	format := importFormat(c, name)
+	if format == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or ndjson"})
+		return
+	}
+
+	rows, rowErrors, err := services.ParseProductImport(body, format)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file is larger than %d MB", config.Cfg.ImportMaxMB)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	isDryRun := dryRun != nil && *dryRun
+
+	if (async != nil && *async) || len(rows) > config.Cfg.ImportSyncRows {
+		userID, _ := contextUint(c, "user_id")
+		// audited once the job wrote the products, like a synchronous import; the
+		// copy keeps the request's actor after the handler returned
+		cc := c.Copy()
+		job, err := services.StartImportJob(rows, rowErrors, isDryRun, userID, func(job *services.ImportJob) {
+			if !job.Result.Applied {
+				return
+			}
+			recordAudit(cc, auditEntry{
+				Action:     "product.import",
+				EntityType: "import_job",
+				EntityID:   job.ID,
+				After: gin.H{"format": format, "rows": job.Result.Rows,
+					"created": job.Result.Created, "updated": job.Result.Updated, "async": true},
+			})
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start import"})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{
+			"job_id":     job.ID,
+			"status":     job.Status,
+			"total":      job.Total,
+			"status_url": "/api/admin/products/import/" + job.ID,
+		})
+		return
+	}
+
+	summary, err := services.RunProductImport(rows, rowErrors, isDryRun, nil)
+	if err != nil {
+		log.Println("product import failed:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "import failed"})
+		return
+	}
+	if summary.Applied {
+		recordAudit(c, auditEntry{
+			Action:     "product.import",
+			EntityType: "product",
+			After:      gin.H{"format": format, "rows": summary.Rows, "created": summary.Created, "updated": summary.Updated},
+		})
+	}
+
+	status := http.StatusOK
+	if summary.ErrorCount > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, summary)
+}
+
+// GetImportJob godoc
+// @Summary Poll a product import job (Admin only)
+// @Description Shows the progress of a background import and, once done, its summary. Jobs are kept for a day.
+// @Tags Products
+// @Security BearerAuth
+// @Produce json
+// @Param job_id path string true "Job ID"
+// @Success 200 {object} ImportJobResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/products/import/{job_id} [get]
+func GetImportJob(c *gin.Context) {
+	job, err := services.GetImportJob(c.Param("job_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load import job"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "import job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// ExportProducts godoc
+// @Summary Export products (Admin only)
+// @Description Streams the whole catalog, inactive products included, in the import format: a line per product followed by a line per variant
+// @Tags Products
+// @Security BearerAuth
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "csv or ndjson" default(csv)
+// @Success 200 {string} string "CSV or NDJSON stream"
+// @Failure 400 {object} ErrorResponse
+// @Router /api/admin/products/export [get]
+func ExportProducts(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	contentType := map[string]string{"csv": "text/csv; charset=utf-8", "ndjson": "application/x-ndjson"}[format]
+	if contentType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or ndjson"})
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="products-%s.%s"`, time.Now().UTC().Format("20060102-150405"), format))
+	c.Status(http.StatusOK)
+
+	if err := services.ExportProducts(c.Writer, format, c.Writer.Flush); err != nil {
+		// headers are gone already, all we can do is stop the stream
+		log.Println("product export aborted:", err)
+	}
+}
+
+// importFile returns the uploaded file, or the request body when the request is
+// not a multipart form, with its file name if known
+func importFile(c *gin.Context) (io.ReadCloser, string, error) {
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", err
+		}
+		f, err := header.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		return f, header.Filename, nil
+	}
+	return c.Request.Body, "", nil
+}
+
+// importFormat takes the format query parameter, else guesses from the file name
+// or the content type; "" when unknown
+func importFormat(c *gin.Context, fileName string) string {
+	if f := c.Query("format"); f != "" {
+		if f == "csv" || f == "ndjson" {
+			return f
+		}
+		return ""
+	}
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return "csv"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	}
+	switch c.ContentType() {
+	case "text/csv":
+		return "csv"
+	case "application/x-ndjson", "application/jsonl":
+		return "ndjson"
+	}
+	return ""
+}
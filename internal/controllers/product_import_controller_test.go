@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/config"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/services"
+	"ecommerce-gin/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestImportProductsAsyncAuditsFinishedImports(t *testing.T) {
+	store := testutil.UseDB(t, &database.DB)
+	testutil.UseRedis(t, &cache.Rdb)
+	prevCfg := config.Cfg
+	t.Cleanup(func() { config.Cfg = prevCfg })
+	config.Cfg.ImportMaxMB = 1
+	config.Cfg.ImportSyncRows = 500
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/import", func(c *gin.Context) { c.Set("user_id", uint(7)) }, ImportProducts)
+	start := func(query string) string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/import?format=csv&async=true"+query,
+			strings.NewReader("slug,name,price\nlamp,Lamp,30\n"))
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("POST /import%s: %d %s", query, w.Code, w.Body)
+		}
+		var res struct {
+			JobID string `json:"job_id"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		return res.JobID
+	}
+	audits := func() []map[string]any {
+		var out []map[string]any
+		for _, row := range store.Rows("audit_logs") {
+			if row["action"] == "product.import" {
+				out = append(out, row)
+			}
+		}
+		return out
+	}
+	wait := func(done func() bool) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if done() {
+				return
+			}
+		}
+		t.Fatal("timed out")
+	}
+
+	// nothing is audited when the job starts, nor for a dry run that finished
+	dryRun := start("&dry_run=true")
+	if n := len(audits()); n != 0 {
+		t.Fatalf("%d audit entries right after the start, want 0", n)
+	}
+	wait(func() bool {
+		job, _ := services.GetImportJob(dryRun)
+		return job != nil && job.Status == "done"
+	})
+
+	applied := start("")
+	wait(func() bool { return len(audits()) > 0 })
+	entries := audits()
+	if len(entries) != 1 || entries[0]["entity_id"] != applied {
+		t.Fatalf("audit entries = %v, want one for job %s", entries, applied)
+	}
+	if after, _ := entries[0]["after"].(string); !strings.Contains(after, `"created":1`) {
+		t.Errorf("audit after = %s, want the import summary", after)
+	}
+	if entries[0]["actor_id"] != int64(7) {
+		t.Errorf("audit actor_id = %v, want the admin who started the import", entries[0]["actor_id"])
+	}
+}
@@ -0,0 +1,307 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type productOptionInput struct {
+	Name   string   `json:"name" binding:"required,max=64"`
+	Values []string `json:"values" binding:"required,min=1,dive,required,max=64"`
+}
+
+type productVariantInput struct {
+	SKU      string            `json:"sku" binding:"required,max=64"`
+	Price    *float64          `json:"price" binding:"omitempty,min=0"`
+	Quantity int               `json:"quantity" binding:"min=0"`
+	ImageURL string            `json:"image_url"`
+	IsActive *bool             `json:"is_active"`
+	Options  map[string]string `json:"options"`
+}
+
+// AdminGetProduct godoc
+// @Summary Get a product with all variants (Admin only)
+// @Description Returns the product with its options and variants, inactive ones included
+// @Tags Products
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Product ID"
+// @Success 200 {object} Product
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/products/{id} [get]
+func AdminGetProduct(c *gin.Context) {
+	product, err := database.GetProductWithVariants(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+	c.JSON(http.StatusOK, product)
+}
+
+// SetProductOptions godoc
+// @Summary Set product option types (Admin only)
+// @Description Replaces the option types (e.g. size, color) and their values. Existing variants must still fit the new options.
+// @Tags Products
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param body body SetProductOptionsInput true "Options"
+// @Success 200 {object} Product
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/admin/products/{id}/options [put]
+func SetProductOptions(c *gin.Context) {
+	var body struct {
+		Options []productOptionInput `json:"options" binding:"dive"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := database.GetProductWithVariants(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+
+	options := make([]models.ProductOption, 0, len(body.Options))
+	names := map[string]bool{}
+	for _, o := range body.Options {
+		name := strings.TrimSpace(o.Name)
+		if names[strings.ToLower(name)] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duplicate option " + name})
+			return
+		}
+		names[strings.ToLower(name)] = true
+
+		values := make([]string, 0, len(o.Values))
+		seen := map[string]bool{}
+		for _, v := range o.Values {
+			v = strings.TrimSpace(v)
+			if seen[strings.ToLower(v)] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("duplicate value %s for option %s", v, name)})
+				return
+			}
+			seen[strings.ToLower(v)] = true
+			values = append(values, v)
+		}
+		options = append(options, models.ProductOption{Name: name, Values: values})
+	}
+
+	for _, v := range product.Variants {
+		if err := models.CheckVariantOptions(options, v.Options); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("variant %s: %s", v.SKU, err.Error())})
+			return
+		}
+	}
+
+	if err := database.ReplaceProductOptions(product.ID, options); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save options"})
+		return
+	}
+	cache.Delete("product:" + product.Slug)
+
+	recordAudit(c, auditEntry{
+		Action:     "product.options_update",
+		EntityType: "product",
+		EntityID:   product.ID,
+		Before:     product.Options,
+		After:      options,
+	})
+
+	product.Options = options
+	c.JSON(http.StatusOK, product)
+}
+
+// CreateProductVariant godoc
+// @Summary Add a product variant (Admin only)
+// @Description Adds a variant with its own SKU, stock, image and optional price override. It needs one value for every option of the product.
+// @Tags Products
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param body body ProductVariantInput true "Variant"
+// @Success 201 {object} ProductVariant
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/admin/products/{id}/variants [post]
+func CreateProductVariant(c *gin.Context) {
+	var body productVariantInput
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := database.GetProductWithVariants(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+
+	variant := &models.ProductVariant{ProductID: product.ID, IsActive: true}
+	if !applyVariantInput(c, product, variant, &body) {
+		return
+	}
+
+	if err := database.CreateProductVariant(variant); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create variant"})
+		return
+	}
+	cache.Delete("product:" + product.Slug)
+	services.ReindexProducts(product.ID)
+
+	recordAudit(c, auditEntry{Action: "product.variant_create", EntityType: "product_variant", EntityID: variant.ID, After: variant})
+
+	c.JSON(http.StatusCreated, variant)
+}
+
+// UpdateProductVariant godoc
+// @Summary Replace a product variant (Admin only)
+// @Description Replaces SKU, price override, stock, image, status and option values of a variant
+// @Tags Products
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param variant_id path string true "Variant ID"
+// @Param body body ProductVariantInput true "Variant"
+// @Success 200 {object} ProductVariant
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/admin/products/{id}/variants/{variant_id} [put]
+func UpdateProductVariant(c *gin.Context) {
+	var body productVariantInput
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := database.GetProductWithVariants(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+	variant, err := database.GetProductVariant(product.ID, parseUint(c.Param("variant_id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "variant not found"})
+		return
+	}
+	before := *variant
+
+	if !applyVariantInput(c, product, variant, &body) {
+		return
+	}
+
+	if err := database.UpdateProductVariant(variant); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update variant"})
+		return
+	}
+	cache.Delete("product:" + product.Slug)
+	services.ReindexProducts(product.ID)
+
+	recordAudit(c, auditEntry{
+		Action:     "product.variant_update",
+		EntityType: "product_variant",
+		EntityID:   variant.ID,
+		Before:     before,
+		After:      variant,
+	})
+
+	c.JSON(http.StatusOK, variant)
+}
+
+// DeleteProductVariant godoc
+// @Summary Delete a product variant (Admin only)
+// @Description Removes a variant from sale and from every cart. Past orders keep referring to it.
+// @Tags Products
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param variant_id path string true "Variant ID"
+// @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/products/{id}/variants/{variant_id} [delete]
+func DeleteProductVariant(c *gin.Context) {
+	product, err := database.GetProductByID(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+	variantID := parseUint(c.Param("variant_id"))
+	before, _ := database.GetProductVariant(product.ID, variantID)
+
+	err = database.DeleteProductVariant(product.ID, variantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "variant not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	cache.Delete("product:" + product.Slug)
+	services.ReindexProducts(product.ID)
+
+	entry := auditEntry{Action: "product.variant_delete", EntityType: "product_variant", EntityID: variantID}
+	if before != nil {
+		entry.Before = before
+	}
+	recordAudit(c, entry)
+
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// applyVariantInput validates the input against the product and copies it onto
+// the variant. Returns false if the response has been written.
+func applyVariantInput(c *gin.Context, product *models.Product, variant *models.ProductVariant, body *productVariantInput) bool {
+	sku := strings.TrimSpace(body.SKU)
+	if owner, err := database.GetVariantBySKU(sku); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return false
+	} else if owner != nil && owner.ID != variant.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "sku already in use"})
+		return false
+	}
+
+	options := models.CanonicalVariantOptions(product.Options, body.Options)
+	if err := models.CheckVariantOptions(product.Options, options); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	candidate := models.ProductVariant{Options: options}
+	for _, other := range product.Variants {
+		if other.ID != variant.ID && other.OptionKey() == candidate.OptionKey() {
+			c.JSON(http.StatusConflict, gin.H{"error": "variant " + other.SKU + " already has these options"})
+			return false
+		}
+	}
+
+	variant.SKU = sku
+	variant.Price = body.Price
+	variant.Quantity = body.Quantity
+	variant.ImageURL = body.ImageURL
+	variant.Options = options
+	if body.IsActive != nil {
+		variant.IsActive = *body.IsActive
+	}
+	return true
+}
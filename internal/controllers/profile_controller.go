@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
+	"ecommerce-gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProfile godoc
+// @Summary Get my profile
+// @Description Returns the account of the logged-in user
+// @Tags Profile
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} ProfileResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /api/profile [get]
+func GetProfile(c *gin.Context) {
+	user := currentUser(c)
+	if user == nil {
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateProfile godoc
+// @Summary Update my profile
+// @Description Changes the name and/or email. A new email is only applied once confirmed through the link sent to it; accounts with a password must send current_password to change it.
+// @Tags Profile
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body UpdateProfileInput true "Profile changes"
+// @Success 200 {object} ProfileResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /api/profile [put]
+func UpdateProfile(c *gin.Context) {
+	var body struct {
+		Name            *string `json:"name" binding:"omitempty,min=1,max=100"`
+		Email           *string `json:"email" binding:"omitempty,email"`
+		CurrentPassword string  `json:"current_password"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	if user == nil {
+		return
+	}
+
+	if body.Name != nil && *body.Name != user.Name {
+		if err := database.UpdateUserName(user.ID, *body.Name); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update profile"})
+			return
+		}
+		user.Name = *body.Name
+	}
+
+	if body.Email != nil && !strings.EqualFold(*body.Email, user.Email) {
+		email := strings.ToLower(strings.TrimSpace(*body.Email))
+
+		// accounts from social login have no password to confirm with
+		if user.Password != "" && !utils.CheckPassword(user.Password, body.CurrentPassword) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "current password is incorrect"})
+			return
+		}
+
+		existing, err := database.GetUserByEmail(email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return
+		}
+		if existing != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "email already registered"})
+			return
+		}
+
+		if err := database.SetPendingEmail(user.ID, email); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update profile"})
+			return
+		}
+		user.PendingEmail = email
+		if err := sendVerificationEmail(user, email); err != nil {
+			log.Println("failed to send verification email:", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// confirmEmailChange switches the user to their verified pending address and tells the old one
+func confirmEmailChange(c *gin.Context, user *models.User) {
+	existing, err := database.GetUserByEmail(user.PendingEmail)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if existing != nil {
+		// someone registered the address in the meantime
+		database.SetPendingEmail(user.ID, "")
+		c.JSON(http.StatusConflict, gin.H{"error": "email already registered"})
+		return
+	}
+
+	oldEmail, newEmail := user.Email, user.PendingEmail
+	ok, err := database.ConfirmPendingEmail(user.ID, newEmail, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to change email"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired verification link"})
+		return
+	}
+
+	// reset links went to the old address
+	if err := database.InvalidateUserTokens(user.ID, "password_reset"); err != nil {
+		log.Println("failed to invalidate reset tokens:", err)
+	}
+
+	database.CreateSecurityEvent(&models.SecurityEvent{
+		UserID:    user.ID,
+		Type:      "email_changed",
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Details:   oldEmail + " -> " + newEmail,
+	})
+
+	err = services.SendMail(services.Mail{
+		To:      oldEmail,
+		Subject: "Your email address was changed",
+		Body:    fmt.Sprintf("Hi %s,\n\nThe email address of your account was changed to %s. If this was not you, contact support immediately.\n", user.Name, newEmail),
+	})
+	if err != nil {
+		log.Println("failed to send email changed notice:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email changed"})
+}
+
+// DeleteProfile godoc
+// @Summary Delete my account
+// @Description Anonymizes the account's personal data and deletes it. Orders are kept for accounting. Accounts with a password must confirm with it.
+// @Tags Profile
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body DeleteProfileInput false "Password confirmation"
+// @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /api/profile [delete]
+func DeleteProfile(c *gin.Context) {
+	var body struct {
+		Password string `json:"password"`
+	}
+	// body is optional for accounts without a password
+	_ = c.ShouldBindJSON(&body)
+
+	user := currentUser(c)
+	if user == nil {
+		return
+	}
+	if user.Password != "" && !utils.CheckPassword(user.Password, body.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "password is incorrect"})
+		return
+	}
+
+	if err := database.AnonymizeUser(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete account"})
+		return
+	}
+	if err := services.RevokeUserTokens(user.ID); err != nil {
+		log.Println("failed to revoke tokens of deleted user:", err)
+	}
+
+	database.CreateSecurityEvent(&models.SecurityEvent{
+		UserID: user.ID,
+		Type:   "account_deleted",
+	})
+
+	clearRefreshCookie(c)
+	c.JSON(http.StatusOK, gin.H{"message": "account deleted"})
+}
+
+// ExportProfile godoc
+// @Summary Export my data
+// @Description Downloads the profile, cart, orders and reviews of the logged-in user as a JSON file
+// @Tags Profile
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} ProfileExportResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /api/profile/export [get]
+func ExportProfile(c *gin.Context) {
+	user := currentUser(c)
+	if user == nil {
+		return
+	}
+
+	cart, err := database.GetCartItems(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load cart"})
+		return
+	}
+	orders, err := database.GetOrdersForUser(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load orders"})
+		return
+	}
+	sessions, err := database.ListActiveSessions(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load sessions"})
+		return
+	}
+	reviews, err := database.ListUserReviews(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load reviews"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="account-export-%d.json"`, user.ID))
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"exported_at": time.Now().UTC(),
+		"profile":     user,
+		"cart":        cart,
+		"orders":      orders,
+		"sessions":    sessions,
+		"reviews":     reviews,
+	})
+}
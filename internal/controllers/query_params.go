@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// paramError is one rejected query parameter
+type paramError struct {
+	Param   string `json:"param"`
+	Message string `json:"message"`
+}
+
+// queryParams reads query parameters strictly and collects every problem, so a
+// client learns about all of them in one 400
+type queryParams struct {
+	c      *gin.Context
+	errors []paramError
+}
+
+func newQueryParams(c *gin.Context) *queryParams {
+	return &queryParams{c: c}
+}
+
+func (p *queryParams) fail(param, message string) {
+	p.errors = append(p.errors, paramError{Param: param, Message: message})
+}
+
+// intRange returns the parameter, def when it is absent
+func (p *queryParams) intRange(key string, def, min, max int) int {
+	v := p.c.Query(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min || n > max {
+		p.fail(key, fmt.Sprintf("must be a whole number from %d to %d", min, max))
+		return def
+	}
+	return n
+}
+
+// price returns nil when the parameter is absent
+func (p *queryParams) price(key string) *float64 {
+	v := p.c.Query(key)
+	if v == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		p.fail(key, "must be a number of at least 0")
+		return nil
+	}
+	return &f
+}
+
+// boolean returns nil when the parameter is absent
+func (p *queryParams) boolean(key string) *bool {
+	v := p.c.Query(key)
+	if v == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		p.fail(key, "must be true or false")
+		return nil
+	}
+	return &b
+}
+
+// list accepts repeated (?k=a&k=b) and comma separated (?k=a,b) values
+func (p *queryParams) list(key string) []string {
+	var values []string
+	for _, raw := range p.c.QueryArray(key) {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// abort writes the 400 if any parameter was rejected and reports whether it did
+func (p *queryParams) abort() bool {
+	if len(p.errors) == 0 {
+		return false
+	}
+	p.c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters", "details": p.errors})
+	return true
+}
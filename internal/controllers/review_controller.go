@@ -0,0 +1,359 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type reviewInput struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Title  string `json:"title" binding:"max=200"`
+	Body   string `json:"body" binding:"max=5000"`
+}
+
+// ListProductReviews godoc
+// @Summary List a product's reviews
+// @Description Gets the approved reviews of a product with its rating and the number of reviews per star
+// @Tags Reviews
+// @Produce json
+// @Param slug path string true "Product slug"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page, 1 to 50" default(10)
+// @Param rating query int false "Only reviews with this rating, 1 to 5"
+// @Param verified query bool false "Only verified purchases"
+// @Param sort query string false "newest, oldest, highest or lowest" default(newest)
+// @Success 200 {object} ReviewListResponse
+// @Failure 400 {object} QueryErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /products/{slug}/reviews [get]
+func ListProductReviews(c *gin.Context) {
+	params := newQueryParams(c)
+	page := params.intRange("page", 1, 1, 1<<20)
+	limit := params.intRange("limit", 10, 1, 50)
+	filter := database.ReviewFilter{
+		Rating: params.intRange("rating", 0, 1, 5),
+		Sort:   c.DefaultQuery("sort", "newest"),
+	}
+	if verified := params.boolean("verified"); verified != nil {
+		filter.VerifiedOnly = *verified
+	}
+	if !database.IsReviewSort(filter.Sort) {
+		params.fail("sort", "must be newest, oldest, highest or lowest")
+	}
+	if params.abort() {
+		return
+	}
+
+	product, err := database.GetProductBySlug(c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+
+	reviews, total, err := database.ListProductReviews(product.ID, limit, (page-1)*limit, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load reviews"})
+		return
+	}
+	breakdown, err := database.ProductRatingBreakdown(product.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load reviews"})
+		return
+	}
+	stars := gin.H{}
+	for rating, count := range breakdown {
+		stars[strconv.Itoa(rating)] = count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": reviews,
+		"meta": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+		"rating_average": product.RatingAverage,
+		"rating_count":   product.RatingCount,
+		"breakdown":      stars,
+	})
+}
+
+// CreateReview godoc
+// @Summary Review a product
+// @Description Rates a product from 1 to 5 with an optional title and text. One review per product; it is shown once a moderator approves it. Reviews of products from a delivered order are marked as verified purchases.
+// @Tags Reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param slug path string true "Product slug"
+// @Param body body ReviewInput true "Review"
+// @Success 201 {object} Review
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/products/{slug}/reviews [post]
+func CreateReview(c *gin.Context) {
+	uid, ok := contextUint(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var body reviewInput
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := database.GetProductBySlug(c.Param("slug"))
+	if err != nil || !product.IsActive {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+
+	review := &models.ProductReview{
+		ProductID: product.ID,
+		UserID:    uid,
+		Rating:    body.Rating,
+		Title:     strings.TrimSpace(body.Title),
+		Body:      strings.TrimSpace(body.Body),
+	}
+	err = database.CreateReview(review)
+	if errors.Is(err, database.ErrReviewExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save review"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, review)
+}
+
+// MyReviews godoc
+// @Summary List my reviews
+// @Description Gets the reviews the user wrote, pending and rejected ones included
+// @Tags Reviews
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} Review
+// @Failure 401 {object} ErrorResponse
+// @Router /api/reviews [get]
+func MyReviews(c *gin.Context) {
+	uid, ok := contextUint(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	reviews, err := database.ListUserReviews(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load reviews"})
+		return
+	}
+	c.JSON(http.StatusOK, reviews)
+}
+
+// UpdateReview godoc
+// @Summary Edit my review
+// @Description Changes the rating or text of a review. The review goes back to moderation and is hidden until it is approved again.
+// @Tags Reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param body body ReviewInput true "Review"
+// @Success 200 {object} Review
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/reviews/{id} [put]
+func UpdateReview(c *gin.Context) {
+	review, ok := ownReview(c)
+	if !ok {
+		return
+	}
+	var body reviewInput
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	wasApproved := review.Status == models.ReviewApproved
+
+	review.Rating = body.Rating
+	review.Title = strings.TrimSpace(body.Title)
+	review.Body = strings.TrimSpace(body.Body)
+	if err := database.UpdateReview(review); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save review"})
+		return
+	}
+	if wasApproved {
+		invalidateReviewedProduct(review)
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// DeleteReview godoc
+// @Summary Delete my review
+// @Description Removes a review for good; the product can then be reviewed again
+// @Tags Reviews
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Review ID"
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/reviews/{id} [delete]
+func DeleteReview(c *gin.Context) {
+	review, ok := ownReview(c)
+	if !ok {
+		return
+	}
+	if err := database.DeleteReview(review); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete review"})
+		return
+	}
+	if review.Status == models.ReviewApproved {
+		invalidateReviewedProduct(review)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "review deleted"})
+}
+
+// AdminListReviewsHandler godoc
+// @Summary Review moderation queue (Admin only)
+// @Description Lists reviews in one moderation state, oldest first
+// @Tags Admin
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "pending, approved, rejected or hidden" default(pending)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page, 1 to 100" default(20)
+// @Success 200 {object} ReviewModerationListResponse
+// @Failure 400 {object} QueryErrorResponse
+// @Router /api/admin/reviews [get]
+func AdminListReviewsHandler(c *gin.Context) {
+	params := newQueryParams(c)
+	page := params.intRange("page", 1, 1, 1<<20)
+	limit := params.intRange("limit", 20, 1, 100)
+	status := c.DefaultQuery("status", models.ReviewPending)
+	if _, known := reviewTransitions[status]; !known {
+		params.fail("status", "must be pending, approved, rejected or hidden")
+	}
+	if params.abort() {
+		return
+	}
+
+	reviews, total, err := database.ListReviewsForModeration(status, limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": reviews,
+		"meta": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// reviewTransitions lists the moderation states a review may move to
+var reviewTransitions = map[string][]string{
+	models.ReviewPending:  {models.ReviewApproved, models.ReviewRejected},
+	models.ReviewApproved: {models.ReviewHidden},
+	models.ReviewRejected: {models.ReviewApproved},
+	models.ReviewHidden:   {models.ReviewApproved},
+}
+
+// AdminModerateReviewHandler godoc
+// @Summary Moderate a review (Admin only)
+// @Description Approves, rejects or hides a review. Pending reviews can be approved or rejected, approved ones hidden, and rejected or hidden ones approved. The note is shown to the author.
+// @Tags Admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param body body ModerateReviewInput true "Decision"
+// @Success 200 {object} Review
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/reviews/{id}/status [put]
+func AdminModerateReviewHandler(c *gin.Context) {
+	var body struct {
+		Status string `json:"status" binding:"required"`
+		Note   string `json:"note" binding:"max=500"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	moderatorID, _ := contextUint(c, "user_id")
+
+	review, err := database.GetReview(parseUint(c.Param("id")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "review not found"})
+		return
+	}
+	current := review.Status
+
+	allowed := false
+	for _, next := range reviewTransitions[current] {
+		if next == body.Status {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot move a " + current + " review to " + body.Status})
+		return
+	}
+
+	if err := database.ModerateReview(review, body.Status, strings.TrimSpace(body.Note), moderatorID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update review"})
+		return
+	}
+	invalidateReviewedProduct(review)
+
+	recordAudit(c, auditEntry{
+		Action:     "review.moderate",
+		EntityType: "product_review",
+		EntityID:   review.ID,
+		Before:     gin.H{"status": current},
+		After:      gin.H{"status": review.Status, "note": review.ModerationNote},
+	})
+
+	c.JSON(http.StatusOK, review)
+}
+
+// ownReview loads the review in the path if the caller wrote it. Returns false if
+// the response has been written.
+func ownReview(c *gin.Context) (*models.ProductReview, bool) {
+	uid, ok := contextUint(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return nil, false
+	}
+	review, err := database.GetReview(parseUint(c.Param("id")))
+	if err != nil || review.UserID != uid {
+		c.JSON(http.StatusNotFound, gin.H{"error": "review not found"})
+		return nil, false
+	}
+	return review, true
+}
+
+// invalidateReviewedProduct drops the cached product page, whose rating changed
+func invalidateReviewedProduct(review *models.ProductReview) {
+	if review.Product != nil {
+		cache.Delete("product:" + review.Product.Slug)
+	}
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"net/http"
+
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type categoryFacet struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Slug  string `json:"slug"`
+	Count int    `json:"count"`
+}
+
+// SearchProducts godoc
+// @Summary Search products
+// @Description Ranks active products by how well name, category and description match every word of q. Words may match as a prefix or with a typo. Facets count the matches per category (subcategories included), price range and stock state; each facet ignores its own filter.
+// @Tags Products
+// @Produce json
+// @Param q query string false "Search text; empty lists everything, newest first"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page, at most 100" default(10)
+// @Param category query []string false "Category slugs, repeated or comma separated; products in their subcategories are included" collectionFormat(multi)
+// @Param in_stock query bool false "Only products in stock (true) or out of stock (false)"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param sort query string false "Order by price, newest, name, popularity or rating instead of relevance, as for the product list"
+// @Success 200 {object} ProductSearchResponse
+// @Failure 400 {object} QueryErrorResponse
+// @Router /products/search [get]
+func SearchProducts(c *gin.Context) {
+	listing, ok := parseProductListing(c, "q")
+	if !ok {
+		return
+	}
+
+	result := services.SearchProducts(listing.searchQuery())
+	products, total, err := listing.page(result.Hits)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "query failed"})
+		return
+	}
+
+	categories, err := database.ListCategories()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load categories"})
+		return
+	}
+	byID := make(map[uint]models.Category, len(categories))
+	for _, cat := range categories {
+		byID[cat.ID] = cat
+	}
+	categoryFacets := make([]categoryFacet, 0, len(result.Facets.Categories))
+	for _, f := range result.Facets.Categories {
+		if cat, ok := byID[f.ID]; ok {
+			categoryFacets = append(categoryFacets, categoryFacet{ID: cat.ID, Name: cat.Name, Slug: cat.Slug, Count: f.Count})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":      products,
+		"total":      total,
+		"page":       listing.pageNum,
+		"limit":      listing.limit,
+		"totalPages": (total + int64(listing.limit) - 1) / int64(listing.limit),
+		"facets": gin.H{
+			"categories":   categoryFacets,
+			"price":        result.Facets.Price,
+			"in_stock":     result.Facets.InStock,
+			"out_of_stock": result.Facets.OutOfStock,
+		},
+	})
+}
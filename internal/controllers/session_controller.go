@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"net/http"
+
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type sessionView struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// ListSessions godoc
+// @Summary List active sessions
+// @Description Lists the devices the user is currently logged in on
+// @Tags Auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} SessionResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/auth/sessions [get]
+func ListSessions(c *gin.Context) {
+	uid, ok := contextUint(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	currentID, _ := contextUint(c, "session_id")
+
+	sessions, err := database.ListActiveSessions(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load sessions"})
+		return
+	}
+
+	items := make([]sessionView, 0, len(sessions))
+	for _, s := range sessions {
+		items = append(items, sessionView{Session: s, Current: s.ID == currentID})
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Logs out one of the user's devices
+// @Tags Auth
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/auth/sessions/{id} [delete]
+func RevokeSession(c *gin.Context) {
+	uid, ok := contextUint(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	sid := parseUint(c.Param("id"))
+
+	revoked, err := database.RevokeSession(uid, sid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+	if !revoked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+
+	services.RevokeSessionTokens(sid)
+
+	if currentID, _ := contextUint(c, "session_id"); currentID == sid {
+		clearRefreshCookie(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+}
+
+// RevokeAllSessions godoc
+// @Summary Revoke all sessions
+// @Description Logs the user out of every device, including the current one
+// @Tags Auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/auth/sessions [delete]
+func RevokeAllSessions(c *gin.Context) {
+	uid, ok := contextUint(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := database.RevokeAllSessions(uid); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+	services.RevokeUserTokens(uid)
+
+	clearRefreshCookie(c)
+	c.JSON(http.StatusOK, gin.H{"message": "all sessions revoked"})
+}
+
+// revokeUserAccess logs the user out everywhere: every session is revoked and every
+// access token issued so far stops working immediately
+func revokeUserAccess(userID uint) error {
+	if err := database.RevokeAllSessions(userID); err != nil {
+		return err
+	}
+	return services.RevokeUserTokens(userID)
+}
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/config"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	recoveryCodeCount  = 10
+	maxSecondFactorTry = 5
+)
+
+// requiresTwoFactor reports whether the account may not use its privileges without 2FA
+func requiresTwoFactor(user *models.User) bool {
+	return config.Cfg.RequireAdmin2FA && user.Role == "admin"
+}
+
+// checkSecondFactor validates a TOTP code or, failing that, a recovery code.
+// Failed attempts are limited per user and a TOTP code is only accepted once.
+// Returns false if the response has been written.
+func checkSecondFactor(c *gin.Context, user *models.User, code, recoveryCode string) bool {
+	failKey := fmt.Sprintf("mfa:fail:%d", user.ID)
+	if n, _ := cache.Rdb.Get(cache.Ctx, failKey).Int(); n >= maxSecondFactorTry {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many attempts, try again later"})
+		return false
+	}
+
+	ok := false
+	switch {
+	case code != "":
+		if step, valid := utils.ValidateTOTP(user.TOTPSecret, code, time.Now()); valid {
+			usedKey := fmt.Sprintf("mfa:used:%d:%d", user.ID, step)
+			ok, _ = cache.Rdb.SetNX(cache.Ctx, usedKey, "1", 3*time.Minute).Result()
+		}
+	case recoveryCode != "":
+		hashed, _ := utils.HashToken(utils.NormalizeRecoveryCode(recoveryCode))
+		consumed, err := database.ConsumeRecoveryCode(user.ID, hashed)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return false
+		}
+		ok = consumed
+		if ok {
+			database.CreateSecurityEvent(&models.SecurityEvent{
+				UserID:    user.ID,
+				Type:      "recovery_code_used",
+				IP:        c.ClientIP(),
+				UserAgent: c.Request.UserAgent(),
+			})
+		}
+	}
+
+	if !ok {
+		if n, _ := cache.Rdb.Incr(cache.Ctx, failKey).Result(); n == 1 {
+			cache.Rdb.Expire(cache.Ctx, failKey, mfaPendingTTL)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid two-factor code"})
+		return false
+	}
+
+	cache.Rdb.Del(cache.Ctx, failKey)
+	return true
+}
+
+// currentUser loads the user behind the access token; writes 401 and returns nil if that fails
+func currentUser(c *gin.Context) *models.User {
+	uid, ok := contextUint(c, "user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return nil
+	}
+	user, err := database.GetUserByID(uid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return nil
+	}
+	return user
+}
+
+// issueRecoveryCodes replaces the user's recovery codes and returns the plain codes
+func issueRecoveryCodes(userID uint) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i], _ = utils.HashToken(code)
+	}
+	if err := database.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// TwoFactorSetup godoc
+// @Summary Start 2FA enrollment
+// @Description Creates a new TOTP secret and returns it with an otpauth:// provisioning URI to render as QR code. 2FA stays off until confirmed with /api/auth/2fa/enable.
+// @Tags Auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} TwoFactorSetupResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/auth/2fa/setup [post]
+func TwoFactorSetup(c *gin.Context) {
+	user := currentUser(c)
+	if user == nil {
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication already enabled"})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate secret"})
+		return
+	}
+	if err := database.SetTOTPSecret(user.ID, secret); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": utils.TOTPProvisioningURI(secret, config.Cfg.TOTPIssuer, user.Email),
+	})
+}
+
+// TwoFactorEnable godoc
+// @Summary Confirm 2FA enrollment
+// @Description Activates 2FA with a code from the authenticator app and returns one-time recovery codes. They are shown only once.
+// @Tags Auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body TwoFactorCodeInput true "TOTP code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /api/auth/2fa/enable [post]
+func TwoFactorEnable(c *gin.Context) {
+	var body struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	if user == nil {
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "call /api/auth/2fa/setup first"})
+		return
+	}
+	if !checkSecondFactor(c, user, body.Code, "") {
+		return
+	}
+
+	if err := database.EnableTOTP(user.ID, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enable 2fa"})
+		return
+	}
+	codes, err := issueRecoveryCodes(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create recovery codes"})
+		return
+	}
+
+	database.CreateSecurityEvent(&models.SecurityEvent{
+		UserID:    user.ID,
+		Type:      "2fa_enabled",
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "two-factor authentication enabled, log in again to use it",
+		"recovery_codes": codes,
+	})
+}
+
+// TwoFactorDisable godoc
+// @Summary Disable 2FA
+// @Description Turns two-factor authentication off. Requires the password and a TOTP or recovery code. Not allowed for accounts that must use 2FA.
+// @Tags Auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body TwoFactorDisableInput true "Password and code"
+// @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/auth/2fa/disable [post]
+func TwoFactorDisable(c *gin.Context) {
+	var body struct {
+		Password     string `json:"password" binding:"required"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	if user == nil {
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "two-factor authentication is not enabled"})
+		return
+	}
+	if requiresTwoFactor(user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "two-factor authentication is mandatory for this account"})
+		return
+	}
+	if !utils.CheckPassword(user.Password, body.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		return
+	}
+	if !checkSecondFactor(c, user, body.Code, body.RecoveryCode) {
+		return
+	}
+
+	if err := database.DisableTOTP(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to disable 2fa"})
+		return
+	}
+
+	database.CreateSecurityEvent(&models.SecurityEvent{
+		UserID:    user.ID,
+		Type:      "2fa_disabled",
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replaces all recovery codes; the old ones stop working
+// @Tags Auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body TwoFactorCodeInput true "TOTP code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /api/auth/2fa/recovery-codes [post]
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var body struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	if user == nil {
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "two-factor authentication is not enabled"})
+		return
+	}
+	if !checkSecondFactor(c, user, body.Code, "") {
+		return
+	}
+
+	codes, err := issueRecoveryCodes(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
@@ -3,6 +3,12 @@ package controllers
 import (
 	"ecommerce-gin/internal/config"
 	"ecommerce-gin/internal/services"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"path/filepath"
 	"time"
@@ -12,7 +18,7 @@ import (
 
 // UploadProductImage godoc
 // @Summary Upload product image (Local)
-// @Description Uploads a product image to local storage
+// @Description Uploads a product image to local storage. Attach the returned URL, width and height to a product's gallery.
 // @Tags Upload
 // @Security BearerAuth
 // @Accept multipart/form-data
@@ -39,6 +45,8 @@ func UploadProductImage(c *gin.Context) {
 		return
 	}
 
+	width, height := uploadedImageSize(file)
+
 	// Generate unique file name
 	newName := time.Now().Format("20060102150405") + ext
 	savePath := "./uploads/" + newName
@@ -49,15 +57,24 @@ func UploadProductImage(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, auditEntry{
+		Action:     "upload.create",
+		EntityType: "upload",
+		EntityID:   "/uploads/" + newName,
+		After:      gin.H{"filename": file.Filename, "size": file.Size, "storage": "local"},
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "uploaded",
 		"url":     "/uploads/" + newName,
+		"width":   width,
+		"height":  height,
 	})
 }
 
 // UploadProductImageS3 godoc
 // @Summary Upload product image (S3)
-// @Description Uploads a product image to AWS S3
+// @Description Uploads a product image to AWS S3. Attach the returned URL, width and height to a product's gallery.
 // @Tags Upload
 // @Security BearerAuth
 // @Accept multipart/form-data
@@ -82,13 +99,39 @@ func UploadProductImageS3(c *gin.Context) {
 	}
 	defer f.Close()
 
+	width, height := uploadedImageSize(file)
+
 	url, err := services.UploadToS3(f, file)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "upload failed"})
 		return
 	}
 
+	recordAudit(c, auditEntry{
+		Action:     "upload.create",
+		EntityType: "upload",
+		EntityID:   url,
+		After:      gin.H{"filename": file.Filename, "size": file.Size, "storage": "s3"},
+	})
+
 	c.JSON(200, gin.H{
-		"url": config.Cfg.S3PublicURL + "/" + url,
+		"url":    config.Cfg.S3PublicURL + "/" + url,
+		"width":  width,
+		"height": height,
 	})
 }
+
+// uploadedImageSize reads the pixel size of a JPEG, PNG or GIF upload; 0, 0 for
+// other formats
+func uploadedImageSize(file *multipart.FileHeader) (int, int) {
+	f, err := file.Open()
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(io.LimitReader(f, 1<<20))
+	if err != nil {
+		return 0, 0
+	}
+	return cfg.Width, cfg.Height
+}
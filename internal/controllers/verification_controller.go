@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/config"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
+	"ecommerce-gin/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sendVerificationEmail mails a signed link proving ownership of email to the user
+func sendVerificationEmail(user *models.User, email string) error {
+	ttl := time.Hour * time.Duration(config.Cfg.VerifyEmailHours)
+	token, err := utils.GenerateSignedToken("verify_email", user.ID, map[string]interface{}{"email": email}, ttl)
+	if err != nil {
+		return err
+	}
+
+	link := config.Cfg.AppBaseURL + "/auth/verify-email?token=" + url.QueryEscape(token)
+	return services.SendMail(services.Mail{
+		To:      email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening this link:\n\n%s\n\nThe link expires in %d hours.\n",
+			user.Name, link, config.Cfg.VerifyEmailHours),
+	})
+}
+
+// VerifyEmail godoc
+// @Summary Verify email address
+// @Description Confirms the email address using the token from the verification email. Also confirms a pending email change.
+// @Tags Auth
+// @Produce json
+// @Param token query string true "Verification token"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /auth/verify-email [get]
+func VerifyEmail(c *gin.Context) {
+	claims, err := utils.ParseSignedToken("verify_email", c.Query("token"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired verification link"})
+		return
+	}
+
+	sub, _ := claims["sub"].(float64)
+	email, _ := claims["email"].(string)
+
+	user, err := database.GetUserByID(uint(sub))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired verification link"})
+		return
+	}
+
+	// link for a requested email change
+	if user.PendingEmail != "" && strings.EqualFold(user.PendingEmail, email) {
+		confirmEmailChange(c, user)
+		return
+	}
+	if !strings.EqualFold(user.Email, email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired verification link"})
+		return
+	}
+
+	if user.VerifiedAt == nil {
+		if err := database.MarkUserVerified(user.ID, time.Now()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify email"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verified"})
+}
+
+// ResendVerification godoc
+// @Summary Resend verification email
+// @Description Sends a new verification link if the address belongs to an unverified account. Always answers the same way.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body ResendVerificationInput true "Email"
+// @Success 200 {object} MessageResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /auth/verify-email/resend [post]
+func ResendVerification(c *gin.Context) {
+	var body struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	// same answer whether or not the account exists
+	resp := gin.H{"message": "if the address needs verification, an email has been sent"}
+
+	// at most one email per address per minute
+	key := "verify:resend:" + strings.ToLower(body.Email)
+	if ok, err := cache.Rdb.SetNX(cache.Ctx, key, "1", time.Minute).Result(); err != nil || !ok {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	user, err := database.GetUserByEmail(body.Email)
+	if err == nil && user != nil && user.VerifiedAt == nil {
+		if err := sendVerificationEmail(user, user.Email); err != nil {
+			log.Println("failed to send verification email:", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
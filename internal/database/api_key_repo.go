@@ -0,0 +1,46 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"ecommerce-gin/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func CreateAPIKey(key *models.APIKey) error {
+	return DB.Create(key).Error
+}
+
+// ListAPIKeys returns all keys, newest first, including revoked ones
+func ListAPIKeys() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := DB.Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// GetAPIKeyByHash returns the unrevoked key with this hash; nil, nil if there is none
+func GetAPIKeyByHash(hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := DB.Where("key_hash = ? AND revoked_at IS NULL", hash).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// RevokeAPIKey marks a key revoked; returns false if it was unknown or already revoked
+func RevokeAPIKey(id uint) (bool, error) {
+	res := DB.Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+func TouchAPIKey(id uint, at time.Time) error {
+	return DB.Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"time"
+
+	"ecommerce-gin/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func CreateAuditLog(entry *models.AuditLog) error {
+	entry.UserAgent = truncate(entry.UserAgent, maxUserAgentLen)
+	return DB.Create(entry).Error
+}
+
+// AuditLogFilter narrows down audit log queries; zero values are ignored
+type AuditLogFilter struct {
+	ActorType  string
+	ActorID    uint
+	Action     string
+	EntityType string
+	EntityID   string
+	From       *time.Time
+	To         *time.Time
+}
+
+func auditLogQuery(f AuditLogFilter) *gorm.DB {
+	query := DB.Model(&models.AuditLog{})
+	if f.ActorType != "" {
+		query = query.Where("actor_type = ?", f.ActorType)
+	}
+	if f.ActorID != 0 {
+		query = query.Where("actor_id = ?", f.ActorID)
+	}
+	if f.Action != "" {
+		query = query.Where("action = ?", f.Action)
+	}
+	if f.EntityType != "" {
+		query = query.Where("entity_type = ?", f.EntityType)
+	}
+	if f.EntityID != "" {
+		query = query.Where("entity_id = ?", f.EntityID)
+	}
+	if f.From != nil {
+		query = query.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("created_at <= ?", *f.To)
+	}
+	return query
+}
+
+// ListAuditLogs returns matching entries, newest first, with pagination
+func ListAuditLogs(f AuditLogFilter, limit, offset int) ([]models.AuditLog, int64, error) {
+	var entries []models.AuditLog
+	var total int64
+
+	query := auditLogQuery(f)
+	if err := query.Count(&total).Order("id desc").Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// EachAuditLog calls fn for every matching entry in id order, loading them in batches
+func EachAuditLog(f AuditLogFilter, fn func(models.AuditLog) error) error {
+	var batch []models.AuditLog
+	return auditLogQuery(f).FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for _, e := range batch {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
@@ -7,14 +7,14 @@ import (
 // Get all cart items for a user
 func GetCartItems(userID uint) ([]models.CartItem, error) {
 	var items []models.CartItem
-	err := DB.Preload("Product").Where("user_id = ?", userID).Find(&items).Error
+	err := DB.Preload("Product").Preload("Variant").Where("user_id = ?", userID).Find(&items).Error
 	return items, err
 }
 
-// Find specific cart item (user + product)
-func GetCartItem(userID, productID uint) (*models.CartItem, error) {
+// Find specific cart item (user + product + variant, nil for products without variants)
+func GetCartItem(userID, productID uint, variantID *uint) (*models.CartItem, error) {
 	var item models.CartItem
-	err := DB.Where("user_id = ? AND product_id = ?", userID, productID).First(&item).Error
+	err := DB.Where(map[string]interface{}{"user_id": userID, "product_id": productID, "variant_id": variantID}).First(&item).Error
 	if err != nil {
 		return nil, err
 	}
@@ -23,7 +23,7 @@ func GetCartItem(userID, productID uint) (*models.CartItem, error) {
 
 func GetCartItemByID(itemID uint) (*models.CartItem, error) {
 	var item models.CartItem
-	err := DB.First(&item, itemID).Error
+	err := DB.Preload("Product").Preload("Variant").First(&item, itemID).Error
 	if err != nil {
 		return nil, err
 	}
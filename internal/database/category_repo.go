@@ -0,0 +1,220 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// ListCategories returns every category as a flat list, siblings in display order
+func ListCategories() ([]models.Category, error) {
+	var categories []models.Category
+	err := DB.Order("position, name").Find(&categories).Error
+	return categories, err
+}
+
+func GetCategoryByID(id uint) (*models.Category, error) {
+	var c models.Category
+	if err := DB.First(&c, id).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func GetCategoryBySlug(slug string) (*models.Category, error) {
+	var c models.Category
+	if err := DB.Where("slug = ?", slug).First(&c).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func CreateCategory(c *models.Category) error {
+	return DB.Create(c).Error
+}
+
+func UpdateCategory(c *models.Category) error {
+	return DB.Omit("Children").Save(c).Error
+}
+
+// DeleteCategory removes a category for good, freeing its slug
+func DeleteCategory(id uint) error {
+	return DB.Unscoped().Delete(&models.Category{}, id).Error
+}
+
+// CountCategoryUsage tells how many subcategories and products are directly in a category
+func CountCategoryUsage(id uint) (children, products int64, err error) {
+	if err = DB.Model(&models.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+		return
+	}
+	err = DB.Model(&models.Product{}).Where("category_id = ?", id).Count(&products).Error
+	return
+}
+
+// CategorySlugTaken reports whether another category already uses the slug
+func CategorySlugTaken(slug string, exceptID uint) (bool, error) {
+	var n int64
+	err := DB.Model(&models.Category{}).Where("slug = ? AND id <> ?", slug, exceptID).Count(&n).Error
+	return n > 0, err
+}
+
+// CategoryTree returns the root categories with their children nested
+func CategoryTree() ([]models.Category, error) {
+	all, err := ListCategories()
+	if err != nil {
+		return nil, err
+	}
+	byParent := map[uint][]models.Category{}
+	for _, c := range all {
+		var parent uint
+		if c.ParentID != nil {
+			parent = *c.ParentID
+		}
+		byParent[parent] = append(byParent[parent], c)
+	}
+
+	var build func(parent uint) []models.Category
+	build = func(parent uint) []models.Category {
+		nodes := byParent[parent]
+		for i := range nodes {
+			nodes[i].Children = build(nodes[i].ID)
+		}
+		return nodes
+	}
+	return build(0), nil
+}
+
+// CategoryDescendantIDs returns the id of the category and of everything below it
+func CategoryDescendantIDs(id uint) ([]uint, error) {
+	var all []models.Category
+	if err := DB.Select("id", "parent_id").Find(&all).Error; err != nil {
+		return nil, err
+	}
+	children := map[uint][]uint{}
+	for _, c := range all {
+		if c.ParentID != nil {
+			children[*c.ParentID] = append(children[*c.ParentID], c.ID)
+		}
+	}
+
+	ids := []uint{id}
+	seen := map[uint]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// CategoryBreadcrumbs returns the path from the root down to the category
+func CategoryBreadcrumbs(id uint) ([]models.CategoryCrumb, error) {
+	var all []models.Category
+	if err := DB.Select("id", "name", "slug", "parent_id").Find(&all).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Category, len(all))
+	for _, c := range all {
+		byID[c.ID] = c
+	}
+
+	var crumbs []models.CategoryCrumb
+	for cur, ok := byID[id]; ok && len(crumbs) <= len(all); {
+		crumbs = append([]models.CategoryCrumb{{ID: cur.ID, Name: cur.Name, Slug: cur.Slug}}, crumbs...)
+		if cur.ParentID == nil {
+			break
+		}
+		cur, ok = byID[*cur.ParentID]
+	}
+	return crumbs, nil
+}
+
+// NewCategorySlug picks a free slug for a new category: the slugified name, else
+// prefixed with the parent's slug ("women-shoes"), else with a -2, -3, ... suffix
+func NewCategorySlug(tx *gorm.DB, name string, parent *models.Category) (string, error) {
+	base := utils.Slugify(name)
+	if base == "" {
+		return "", nil
+	}
+	if parent != nil {
+		taken, err := categorySlugExists(tx, base)
+		if err != nil {
+			return "", err
+		}
+		if taken {
+			base = parent.Slug + "-" + base
+		}
+	}
+
+	slug := base
+	for i := 2; ; i++ {
+		taken, err := categorySlugExists(tx, slug)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+func categorySlugExists(tx *gorm.DB, slug string) (bool, error) {
+	var n int64
+	err := tx.Model(&models.Category{}).Where("slug = ?", slug).Count(&n).Error
+	return n > 0, err
+}
+
+// MigrateLegacyCategories turns the old free-text product categories into root
+// categories. Each distinct value becomes one category named as written, even
+// one that reads like a path such as "Men > Shoes"; values that slugify the same
+// ("Electronics", "electronics") share it. Connect runs it once, when it creates
+// the category tree.
+func MigrateLegacyCategories(db *gorm.DB) error {
+	var names []string
+	if err := db.Unscoped().Model(&models.Product{}).
+		Where("category_id IS NULL AND category IS NOT NULL AND category <> ''").
+		Distinct("category").Pluck("category", &names).Error; err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		bySlug := map[string]uint{}
+		for _, name := range names {
+			base := utils.Slugify(name)
+			if base == "" {
+				continue
+			}
+			id, ok := bySlug[base]
+			if !ok {
+				slug, err := NewCategorySlug(tx, name, nil)
+				if err != nil {
+					return err
+				}
+				c := &models.Category{Name: strings.TrimSpace(name), Slug: slug}
+				if err := tx.Create(c).Error; err != nil {
+					return err
+				}
+				id, bySlug[base] = c.ID, c.ID
+			}
+			if err := tx.Unscoped().Model(&models.Product{}).
+				Where("category_id IS NULL AND category = ?", name).
+				Update("category_id", id).Error; err != nil {
+				return err
+			}
+		}
+		log.Printf("Migrated %d legacy product categories", len(names))
+		return nil
+	})
+}
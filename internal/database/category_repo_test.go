@@ -0,0 +1,44 @@
+package database
+
+import (
+	"testing"
+
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/testutil"
+)
+
+func TestMigrateLegacyCategories(t *testing.T) {
+	store := testutil.UseDB(t, &DB)
+	for i, category := range []string{"Electronics", "electronics", "Men > Shoes", ""} {
+		p := &models.Product{Name: "Product", Slug: string(rune('a' + i)), Price: 10, IsActive: true, LegacyCategory: category}
+		if err := DB.Create(p).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := MigrateLegacyCategories(DB); err != nil {
+		t.Fatal(err)
+	}
+
+	categories := store.Rows("categories")
+	if len(categories) != 2 {
+		t.Fatalf("categories = %v, want Electronics and Men > Shoes", categories)
+	}
+	ids := map[string]any{}
+	for _, c := range categories {
+		if c["parent_id"] != nil {
+			t.Errorf("category %v has a parent, want every one at the root", c["name"])
+		}
+		ids[c["slug"].(string)] = c["id"]
+	}
+	if ids["electronics"] == nil || ids["men-shoes"] == nil {
+		t.Fatalf("category slugs = %v, want electronics and men-shoes", ids)
+	}
+
+	want := []any{ids["electronics"], ids["electronics"], ids["men-shoes"], nil}
+	for i, p := range store.Rows("products") {
+		if p["category_id"] != want[i] {
+			t.Errorf("product %d (%q) category_id = %v, want %v", i+1, p["category"], p["category_id"], want[i])
+		}
+	}
+}
@@ -26,20 +26,59 @@ func Connect() {
 		cfg.DBName,
 	)
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatal("Failed to connect database: ", err)
 	}
+	// sales counts are kept up to date from now on, older orders are counted once
+	backfillSales := !db.Migrator().HasColumn(&models.Product{}, "sales_count")
+	// the free-text categories move into the category tree when it is created
+	migrateCategories := !db.Migrator().HasTable(&models.Category{})
+
 	// Migrate the schema, that mean create tables if not exists
 	db.AutoMigrate(
 		&models.User{},
+		&models.Category{},
 		&models.Product{},
+		&models.ProductSlugHistory{},
+		&models.ProductImage{},
+		&models.ProductReview{},
+		&models.ProductOption{},
+		&models.ProductVariant{},
 		&models.CartItem{},
 		&models.Order{},
 		&models.OrderItem{},
 		&models.PaymentIntent{},
+		&models.Session{},
+		&models.RotatedRefreshToken{},
+		&models.SecurityEvent{},
+		&models.UserToken{},
+		&models.RecoveryCode{},
+		&models.Role{},
+		&models.Permission{},
+		&models.UserIdentity{},
+		&models.APIKey{},
+		&models.AuditLog{},
+		&models.Passkey{},
 	)
 
+	if err := SeedRolesAndPermissions(db); err != nil {
+		log.Fatal("Failed to seed roles: ", err)
+	}
+	if migrateCategories {
+		if err := MigrateLegacyCategories(db); err != nil {
+			log.Fatal("Failed to migrate categories: ", err)
+		}
+	}
+	if err := MigrateLegacyProductImages(db); err != nil {
+		log.Fatal("Failed to migrate product images: ", err)
+	}
+	if backfillSales {
+		if err := BackfillSalesCounts(db); err != nil {
+			log.Fatal("Failed to count product sales: ", err)
+		}
+	}
+
 	DB = db
 	fmt.Println("Database connected")
 }
@@ -13,7 +13,7 @@ func AdminListOrders(limit, offset int, status string, from, to *time.Time) ([]m
 	var orders []models.Order
 	var total int64
 
-	query := DB.Model(&models.Order{}).Preload("OrderItems.Product").Order("created_at desc")
+	query := DB.Model(&models.Order{}).Preload("OrderItems.Product").Preload("OrderItems.Variant", unscopedPreload).Order("created_at desc")
 
 	if status != "" {
 		query = query.Where("status = ?", status)
@@ -34,7 +34,7 @@ func AdminListOrders(limit, offset int, status string, from, to *time.Time) ([]m
 // AdminGetOrderByID returns an order by id (admin)
 func AdminGetOrderByID(orderID uint) (*models.Order, error) {
 	var order models.Order
-	if err := DB.Preload("OrderItems.Product").First(&order, orderID).Error; err != nil {
+	if err := DB.Preload("OrderItems.Product").Preload("OrderItems.Variant", unscopedPreload).First(&order, orderID).Error; err != nil {
 		return nil, err
 	}
 	return &order, nil
@@ -50,13 +50,31 @@ func UpdateOrderStatus(tx *gorm.DB, orderID uint, newStatus string) error {
 }
 
 // RefundStockOnCancel decrements nothing here — instead restore stock when cancelling
+// It also takes the units off the products' sales counts.
 func RestoreStockForOrder(tx *gorm.DB, orderID uint) error {
-	// For each order item, add quantity back to product
+	// For each order item, add quantity back to the variant and product
 	var items []models.OrderItem
 	if err := tx.Where("order_id = ?", orderID).Find(&items).Error; err != nil {
 		return err
 	}
 	for _, it := range items {
+		if err := tx.Model(&models.Product{}).
+			Where("id = ?", it.ProductID).
+			Update("sales_count", gorm.Expr("GREATEST(sales_count - ?, 0)", it.Quantity)).Error; err != nil {
+			return err
+		}
+		if it.VariantID != nil {
+			if err := tx.Model(&models.ProductVariant{}).
+				Where("id = ?", *it.VariantID).
+				Update("quantity", gorm.Expr("quantity + ?", it.Quantity)).Error; err != nil {
+				return err
+			}
+			// the product total follows its variants
+			if err := syncProductQuantity(tx, it.ProductID); err != nil {
+				return err
+			}
+			continue
+		}
 		if err := tx.Model(&models.Product{}).
 			Where("id = ?", it.ProductID).
 			Update("quantity", gorm.Expr("quantity + ?", it.Quantity)).Error; err != nil {
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"errors"
+
 	"ecommerce-gin/internal/models"
 
 	"gorm.io/gorm"
@@ -16,7 +18,7 @@ func CreateOrderItems(tx *gorm.DB, items []models.OrderItem) error {
 
 func GetOrdersForUser(userID uint) ([]models.Order, error) {
 	var orders []models.Order
-	err := DB.Preload("OrderItems.Product").Preload("OrderItems.Order").
+	err := DB.Preload("OrderItems.Product").Preload("OrderItems.Variant", unscopedPreload).Preload("OrderItems.Order").
 		Where("user_id = ?", userID).Order("id DESC").
 		Find(&orders).Error
 	return orders, err
@@ -24,7 +26,7 @@ func GetOrdersForUser(userID uint) ([]models.Order, error) {
 
 func GetOrderByID(userID, orderID uint) (*models.Order, error) {
 	var order models.Order
-	err := DB.Preload("OrderItems.Product").
+	err := DB.Preload("OrderItems.Product").Preload("OrderItems.Variant", unscopedPreload).
 		Where("id = ? AND user_id = ?", orderID, userID).
 		First(&order).Error
 	if err != nil {
@@ -33,10 +35,42 @@ func GetOrderByID(userID, orderID uint) (*models.Order, error) {
 	return &order, nil
 }
 
-func DeductProductStock(tx *gorm.DB, productID uint, quantity int) error {
-	return tx.Model(&models.Product{}).
-		Where("id = ?", productID).
-		Update("quantity", gorm.Expr("quantity - ?", quantity)).Error
+// ErrInsufficientStock is returned when stock ran out between validation and the update
+var ErrInsufficientStock = errors.New("not enough stock")
+
+// DeductProductStock takes quantity off the variant, if any, and the product total.
+// The updates only apply while enough is left, so concurrent checkouts cannot oversell.
+func DeductProductStock(tx *gorm.DB, productID uint, variantID *uint, quantity int) error {
+	if variantID != nil {
+		res := tx.Model(&models.ProductVariant{}).
+			Where("id = ? AND product_id = ? AND quantity >= ?", *variantID, productID, quantity).
+			Update("quantity", gorm.Expr("quantity - ?", quantity))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInsufficientStock
+		}
+	}
+
+	res := tx.Model(&models.Product{}).
+		Where("id = ? AND quantity >= ?", productID, quantity).
+		Updates(map[string]any{
+			"quantity":    gorm.Expr("quantity - ?", quantity),
+			"sales_count": gorm.Expr("sales_count + ?", quantity),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+// unscopedPreload also loads soft deleted rows, for order history
+func unscopedPreload(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
 }
 
 func ClearCartInTransaction(tx *gorm.DB, userID uint) error {
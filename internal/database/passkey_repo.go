@@ -0,0 +1,50 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"ecommerce-gin/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func CreatePasskey(p *models.Passkey) error {
+	return DB.Create(p).Error
+}
+
+func ListPasskeys(userID uint) ([]models.Passkey, error) {
+	var passkeys []models.Passkey
+	err := DB.Where("user_id = ?", userID).Order("id").Find(&passkeys).Error
+	return passkeys, err
+}
+
+// GetPasskeyByCredentialID returns nil, nil if the credential is unknown
+func GetPasskeyByCredentialID(credentialID string) (*models.Passkey, error) {
+	var p models.Passkey
+	err := DB.Where("credential_id = ?", credentialID).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// UpdatePasskeyUsage stores the new signature counter. It only succeeds if the
+// counter is still the one the assertion was checked against, so two logins
+// racing with the same assertion cannot both win.
+func UpdatePasskeyUsage(id uint, oldCount, newCount uint32) (bool, error) {
+	res := DB.Model(&models.Passkey{}).
+		Where("id = ? AND sign_count = ?", id, oldCount).
+		Updates(map[string]interface{}{"sign_count": newCount, "last_used_at": time.Now()})
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeletePasskey removes a passkey of the user for good, so the authenticator can be
+// registered again. Returns false if there was nothing to delete.
+func DeletePasskey(userID, id uint) (bool, error) {
+	res := DB.Unscoped().Where("id = ? AND user_id = ?", id, userID).Delete(&models.Passkey{})
+	return res.RowsAffected > 0, res.Error
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"errors"
+	"log"
+
+	"ecommerce-gin/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrImageOrder is returned when a new gallery order does not list every image once
+var ErrImageOrder = errors.New("image_ids must list every image of the product once")
+
+func orderImages(db *gorm.DB) *gorm.DB {
+	return db.Order("position, id")
+}
+
+// primaryImageOnly preloads just the primary image, for list views
+func primaryImageOnly(db *gorm.DB) *gorm.DB {
+	return db.Where("is_primary = ?", true)
+}
+
+// ListProductImages returns the gallery of a product in display order
+func ListProductImages(productID uint) ([]models.ProductImage, error) {
+	var images []models.ProductImage
+	err := orderImages(DB.Where("product_id = ?", productID)).Find(&images).Error
+	return images, err
+}
+
+// GetProductImage returns an image of the product
+func GetProductImage(productID, imageID uint) (*models.ProductImage, error) {
+	var img models.ProductImage
+	if err := DB.Where("id = ? AND product_id = ?", imageID, productID).First(&img).Error; err != nil {
+		return nil, err
+	}
+	return &img, nil
+}
+
+// AttachProductImage adds an image at the end of the gallery. The first image of a
+// product becomes its primary image whether asked or not.
+func AttachProductImage(img *models.ProductImage) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		return attachProductImage(tx, img)
+	})
+}
+
+func attachProductImage(tx *gorm.DB, img *models.ProductImage) error {
+	var last struct{ Count, MaxPosition int }
+	if err := tx.Model(&models.ProductImage{}).Where("product_id = ?", img.ProductID).
+		Select("COUNT(*) AS count, COALESCE(MAX(position), -1) AS max_position").
+		Scan(&last).Error; err != nil {
+		return err
+	}
+	img.Position = last.MaxPosition + 1
+	img.IsPrimary = img.IsPrimary || last.Count == 0
+	if err := tx.Create(img).Error; err != nil {
+		return err
+	}
+	var primaryID uint
+	if img.IsPrimary {
+		primaryID = img.ID
+	}
+	return syncPrimaryImage(tx, img.ProductID, primaryID)
+}
+
+// UpdateProductImage saves the alt text and, when the image is made primary, moves
+// the primary flag to it
+func UpdateProductImage(img *models.ProductImage) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(img).Select("alt_text").Updates(img).Error; err != nil {
+			return err
+		}
+		if !img.IsPrimary {
+			return nil
+		}
+		return syncPrimaryImage(tx, img.ProductID, img.ID)
+	})
+}
+
+// ReorderProductImages gives the images the order of ids, which must hold every
+// image of the product
+func ReorderProductImages(productID uint, ids []uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var current []uint
+		if err := tx.Model(&models.ProductImage{}).Where("product_id = ?", productID).
+			Pluck("id", &current).Error; err != nil {
+			return err
+		}
+		listed := map[uint]bool{}
+		for _, id := range ids {
+			listed[id] = true
+		}
+		if len(ids) != len(current) || len(listed) != len(current) {
+			return ErrImageOrder
+		}
+		for _, id := range current {
+			if !listed[id] {
+				return ErrImageOrder
+			}
+		}
+
+		for i, id := range ids {
+			if err := tx.Model(&models.ProductImage{}).
+				Where("id = ? AND product_id = ?", id, productID).
+				Update("position", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DetachProductImage removes an image from the gallery; the first remaining image
+// takes over when it was the primary one
+func DetachProductImage(productID, imageID uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Where("id = ? AND product_id = ?", imageID, productID).Delete(&models.ProductImage{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return syncPrimaryImage(tx, productID, 0)
+	})
+}
+
+// syncPrimaryImage makes primaryID the primary image, or keeps the current one when
+// it is 0, falling back to the first image. The product's image_url follows.
+func syncPrimaryImage(tx *gorm.DB, productID, primaryID uint) error {
+	var images []models.ProductImage
+	if err := orderImages(tx.Where("product_id = ?", productID)).Find(&images).Error; err != nil {
+		return err
+	}
+
+	var primary *models.ProductImage
+	for i := range images {
+		if (primaryID != 0 && images[i].ID == primaryID) || (primaryID == 0 && images[i].IsPrimary && primary == nil) {
+			primary = &images[i]
+		}
+	}
+	if primary == nil && len(images) > 0 {
+		primary = &images[0]
+	}
+
+	url := ""
+	if primary != nil {
+		url = primary.URL
+		if err := tx.Model(&models.ProductImage{}).Where("product_id = ?", productID).
+			Update("is_primary", gorm.Expr("id = ?", primary.ID)).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&models.Product{}).Where("id = ?", productID).Update("image_url", url).Error
+}
+
+// MigrateLegacyProductImages turns the image_url of products without a gallery into
+// their primary image. It only looks at products that have no images yet, so it
+// is cheap to run on every start.
+func MigrateLegacyProductImages(db *gorm.DB) error {
+	var products []models.Product
+	err := db.Unscoped().Select("id", "image_url").
+		Where("image_url IS NOT NULL AND image_url <> ''").
+		Where("NOT EXISTS (SELECT 1 FROM product_images WHERE product_images.product_id = products.id)").
+		Find(&products).Error
+	if err != nil || len(products) == 0 {
+		return err
+	}
+
+	images := make([]models.ProductImage, 0, len(products))
+	for _, p := range products {
+		images = append(images, models.ProductImage{ProductID: p.ID, URL: p.ImageURL, IsPrimary: true})
+	}
+	if err := db.CreateInBatches(&images, 200).Error; err != nil {
+		return err
+	}
+	log.Printf("Migrated %d product images", len(images))
+	return nil
+}
@@ -0,0 +1,412 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// ProductImportRow is one line of a catalog import. Rows with a SKU describe a
+// variant of the product named by Slug, the others a product. Nil fields are
+// left as they are.
+type ProductImportRow struct {
+	Line        int
+	Slug        string
+	SKU         string
+	Name        *string
+	Description *string
+	Price       *float64 // for a variant, its price override
+	Quantity    *int
+	Category    *string // category slug
+	ImageURL    *string
+	IsActive    *bool
+
+	ProductOptions []models.ProductOption // product rows: replaces the option types
+	VariantOptions map[string]string      // variant rows: the variant's option values
+}
+
+// ImportRowError is a problem with one line of an import
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportSummary is the outcome of an import
+type ImportSummary struct {
+	Rows       int              `json:"rows"`
+	Created    int              `json:"created"`
+	Updated    int              `json:"updated"`
+	DryRun     bool             `json:"dry_run"`
+	Applied    bool             `json:"applied"` // false on a dry run and whenever a row failed
+	ErrorCount int              `json:"error_count"`
+	Errors     []ImportRowError `json:"errors"` // the first MaxImportErrors of them
+
+	ProductIDs []uint   `json:"-"` // products written, for the search index
+	Slugs      []string `json:"-"` // their slugs before the import, for the cache
+}
+
+// MaxImportErrors caps the row errors kept in a summary
+const MaxImportErrors = 1000
+
+var errImportRollback = errors.New("import rolled back")
+
+// ImportProducts upserts the rows in one transaction: products by slug, variants by
+// SKU. Nothing is written on a dry run or when any row fails, so a dry run reports
+// exactly what a real run would do. parseErrors are lines that could not be read;
+// they make the import fail too. progress is called after each row.
+func ImportProducts(rows []ProductImportRow, parseErrors []ImportRowError, dryRun bool, progress func(done int)) (*ImportSummary, error) {
+	im := &importer{
+		summary: &ImportSummary{Rows: len(rows), DryRun: dryRun, Errors: []ImportRowError{}},
+		touched: map[uint]bool{},
+	}
+	badLines := map[int]bool{}
+	for _, e := range parseErrors {
+		im.fail(e.Line, e.Field, e.Message)
+		badLines[e.Line] = true
+	}
+	im.summary.Rows += len(badLines)
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		im.tx = tx
+		for i := range rows {
+			var err error
+			if rows[i].SKU != "" {
+				err = im.variant(&rows[i])
+			} else {
+				err = im.product(&rows[i])
+			}
+			if err != nil {
+				return fmt.Errorf("line %d: %w", rows[i].Line, err)
+			}
+			if progress != nil {
+				progress(i + 1)
+			}
+		}
+		if dryRun || im.summary.ErrorCount > 0 {
+			return errImportRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRollback) {
+		return nil, err
+	}
+
+	im.summary.Applied = err == nil
+	if im.summary.Applied {
+		for id := range im.touched {
+			im.summary.ProductIDs = append(im.summary.ProductIDs, id)
+		}
+	}
+	return im.summary, nil
+}
+
+type importer struct {
+	tx      *gorm.DB
+	summary *ImportSummary
+	touched map[uint]bool
+}
+
+func (im *importer) fail(line int, field, message string) {
+	im.summary.ErrorCount++
+	if len(im.summary.Errors) < MaxImportErrors {
+		im.summary.Errors = append(im.summary.Errors, ImportRowError{Line: line, Field: field, Message: message})
+	}
+}
+
+func (im *importer) touch(p *models.Product) {
+	if !im.touched[p.ID] {
+		im.touched[p.ID] = true
+		im.summary.Slugs = append(im.summary.Slugs, p.Slug)
+	}
+}
+
+// category resolves a category slug; ok is false when the row failed
+func (im *importer) category(row *ProductImportRow) (id *uint, ok bool) {
+	var c models.Category
+	err := im.tx.Where("slug = ?", *row.Category).First(&c).Error
+	if err != nil {
+		im.fail(row.Line, "category", "unknown category "+*row.Category)
+		return nil, false
+	}
+	return &c.ID, true
+}
+
+func (im *importer) product(row *ProductImportRow) error {
+	errorsBefore := im.summary.ErrorCount
+
+	var p models.Product
+	found := false
+	if row.Slug != "" {
+		err := im.tx.Unscoped().Where("slug = ?", row.Slug).First(&p).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+		case err != nil:
+			return err
+		case p.DeletedAt.Valid:
+			im.fail(row.Line, "slug", "belongs to a deleted product")
+			return nil
+		default:
+			found = true
+		}
+	}
+
+	var categoryID *uint
+	if row.Category != nil {
+		var ok bool
+		if categoryID, ok = im.category(row); !ok {
+			return nil
+		}
+	}
+
+	if !found {
+		return im.createProduct(row, categoryID, errorsBefore)
+	}
+
+	updates := map[string]any{}
+	if row.Name != nil {
+		updates["name"] = *row.Name
+	}
+	if row.Description != nil {
+		updates["description"] = *row.Description
+	}
+	if row.Price != nil {
+		updates["price"] = *row.Price
+	}
+	if row.IsActive != nil {
+		updates["is_active"] = *row.IsActive
+	}
+	if row.Category != nil {
+		updates["category_id"] = categoryID
+	}
+
+	var variants []models.ProductVariant
+	if err := im.tx.Where("product_id = ?", p.ID).Find(&variants).Error; err != nil {
+		return err
+	}
+	if row.Quantity != nil {
+		if len(variants) > 0 {
+			im.fail(row.Line, "quantity", "quantity of a product with variants is set per variant")
+		} else {
+			updates["quantity"] = *row.Quantity
+		}
+	}
+	if row.ProductOptions != nil {
+		for _, v := range variants {
+			if err := models.CheckVariantOptions(row.ProductOptions, v.Options); err != nil {
+				im.fail(row.Line, "options", fmt.Sprintf("variant %s: %s", v.SKU, err.Error()))
+			}
+		}
+	}
+	if im.summary.ErrorCount > errorsBefore {
+		return nil
+	}
+
+	im.touch(&p)
+	if len(updates) > 0 {
+		if err := im.tx.Model(&models.Product{}).Where("id = ?", p.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	if row.ProductOptions != nil {
+		if err := replaceProductOptions(im.tx, p.ID, row.ProductOptions); err != nil {
+			return err
+		}
+	}
+	if row.ImageURL != nil && *row.ImageURL != p.ImageURL {
+		if err := im.setPrimaryImage(p.ID, *row.ImageURL); err != nil {
+			return err
+		}
+	}
+	im.summary.Updated++
+	return nil
+}
+
+func (im *importer) createProduct(row *ProductImportRow, categoryID *uint, errorsBefore int) error {
+	if row.Name == nil {
+		im.fail(row.Line, "name", "required for a new product")
+	}
+	if row.Price == nil {
+		im.fail(row.Line, "price", "required for a new product")
+	}
+	slug := row.Slug
+	if slug != "" {
+		if clean := utils.Slugify(slug); clean != slug {
+			im.fail(row.Line, "slug", fmt.Sprintf("must be lowercase letters, digits and dashes, e.g. %q", clean))
+		} else if taken, err := ProductSlugTaken(im.tx, slug, 0); err != nil {
+			return err
+		} else if taken {
+			im.fail(row.Line, "slug", "still redirects to another product")
+		}
+	}
+	if im.summary.ErrorCount > errorsBefore {
+		return nil
+	}
+
+	if slug == "" {
+		var err error
+		if slug, err = NewProductSlug(im.tx, *row.Name, 0); err != nil {
+			return err
+		}
+	}
+	p := models.Product{
+		Name:       *row.Name,
+		Slug:       slug,
+		Price:      *row.Price,
+		CategoryID: categoryID,
+		IsActive:   true,
+	}
+	if row.Description != nil {
+		p.Description = *row.Description
+	}
+	if row.Quantity != nil {
+		p.Quantity = *row.Quantity
+	}
+	if row.IsActive != nil {
+		p.IsActive = *row.IsActive
+	}
+	if err := im.tx.Create(&p).Error; err != nil {
+		return err
+	}
+	// gorm leaves a false is_active out of the insert and the column defaults to true
+	if row.IsActive != nil && !*row.IsActive {
+		if err := im.tx.Model(&p).Update("is_active", false).Error; err != nil {
+			return err
+		}
+	}
+	im.touch(&p)
+
+	if row.ProductOptions != nil {
+		if err := replaceProductOptions(im.tx, p.ID, row.ProductOptions); err != nil {
+			return err
+		}
+	}
+	if row.ImageURL != nil && *row.ImageURL != "" {
+		if err := im.setPrimaryImage(p.ID, *row.ImageURL); err != nil {
+			return err
+		}
+	}
+	im.summary.Created++
+	return nil
+}
+
+// setPrimaryImage makes the image with this URL primary, adding it to the gallery
+// when it is not there yet
+func (im *importer) setPrimaryImage(productID uint, url string) error {
+	var img models.ProductImage
+	err := im.tx.Where("product_id = ? AND url = ?", productID, url).First(&img).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return attachProductImage(im.tx, &models.ProductImage{ProductID: productID, URL: url, IsPrimary: true})
+	}
+	if err != nil {
+		return err
+	}
+	return syncPrimaryImage(im.tx, productID, img.ID)
+}
+
+func (im *importer) variant(row *ProductImportRow) error {
+	errorsBefore := im.summary.ErrorCount
+
+	var v models.ProductVariant
+	found := false
+	err := im.tx.Unscoped().Where("sku = ?", row.SKU).First(&v).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+	case err != nil:
+		return err
+	case v.DeletedAt.Valid:
+		im.fail(row.Line, "sku", "belongs to a deleted variant")
+		return nil
+	default:
+		found = true
+	}
+
+	var p models.Product
+	query := im.tx.Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Preload("Variants")
+	if found {
+		if err := query.First(&p, v.ProductID).Error; err != nil {
+			im.fail(row.Line, "sku", "belongs to a deleted product")
+			return nil
+		}
+		if row.Slug != "" && row.Slug != p.Slug {
+			im.fail(row.Line, "slug", "sku belongs to product "+p.Slug)
+			return nil
+		}
+	} else {
+		if row.Slug == "" {
+			im.fail(row.Line, "slug", "required for a new variant")
+			return nil
+		}
+		if err := query.Where("slug = ?", row.Slug).First(&p).Error; err != nil {
+			im.fail(row.Line, "slug", "no product with this slug; put the product row before its variants")
+			return nil
+		}
+		v = models.ProductVariant{ProductID: p.ID, SKU: row.SKU, IsActive: true}
+	}
+
+	if row.VariantOptions != nil || !found {
+		options := models.CanonicalVariantOptions(p.Options, row.VariantOptions)
+		if err := models.CheckVariantOptions(p.Options, options); err != nil {
+			im.fail(row.Line, "options", err.Error())
+		} else {
+			candidate := models.ProductVariant{Options: options}
+			for _, other := range p.Variants {
+				if other.ID != v.ID && other.OptionKey() == candidate.OptionKey() {
+					im.fail(row.Line, "options", "variant "+other.SKU+" already has these options")
+				}
+			}
+			v.Options = options
+		}
+	}
+	if im.summary.ErrorCount > errorsBefore {
+		return nil
+	}
+
+	if row.Price != nil {
+		v.Price = row.Price
+	}
+	if row.Quantity != nil {
+		v.Quantity = *row.Quantity
+	}
+	if row.ImageURL != nil {
+		v.ImageURL = *row.ImageURL
+	}
+	if row.IsActive != nil {
+		v.IsActive = *row.IsActive
+	}
+
+	im.touch(&p)
+	if found {
+		err = im.tx.Save(&v).Error
+		im.summary.Updated++
+	} else {
+		err = im.tx.Create(&v).Error
+		im.summary.Created++
+	}
+	if err != nil {
+		return err
+	}
+	return syncProductQuantity(im.tx, p.ID)
+}
+
+// EachProductForExport calls fn for every product, inactive ones included, with its
+// category, options and variants, in id order
+func EachProductForExport(fn func(models.Product) error) error {
+	var batch []models.Product
+	return DB.Preload("Category").
+		Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		FindInBatches(&batch, 200, func(tx *gorm.DB, _ int) error {
+			for _, p := range batch {
+				if err := fn(p); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
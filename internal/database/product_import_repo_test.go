@@ -0,0 +1,83 @@
+package database
+
+import (
+	"testing"
+
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/testutil"
+)
+
+func TestImportProductsKeepsInactive(t *testing.T) {
+	testutil.UseDB(t, &DB)
+	str := func(s string) *string { return &s }
+	num := func(f float64) *float64 { return &f }
+	qty := func(n int) *int { return &n }
+	flag := func(b bool) *bool { return &b }
+
+	rows := []ProductImportRow{
+		{Line: 2, Slug: "lamp", Name: str("Lamp"), Price: num(30), IsActive: flag(false),
+			ProductOptions: []models.ProductOption{{Name: "Color", Values: []string{"Red", "Blue"}}}},
+		{Line: 3, Slug: "lamp", SKU: "LAMP-RED", Quantity: qty(4), IsActive: flag(false),
+			VariantOptions: map[string]string{"Color": "Red"}},
+		{Line: 4, Slug: "lamp", SKU: "LAMP-BLUE", Quantity: qty(2),
+			VariantOptions: map[string]string{"Color": "Blue"}},
+		{Line: 5, Slug: "desk", Name: str("Desk"), Price: num(120), Quantity: qty(1)},
+	}
+	summary, err := ImportProducts(rows, nil, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summary.Applied || summary.Created != 4 {
+		t.Fatalf("summary = %+v, want 4 rows created and applied", summary)
+	}
+
+	var lamp, desk models.Product
+	if err := DB.Preload("Variants").Where("slug = ?", "lamp").First(&lamp).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := DB.Where("slug = ?", "desk").First(&desk).Error; err != nil {
+		t.Fatal(err)
+	}
+	if lamp.IsActive {
+		t.Error("lamp imported with is_active false came out active")
+	}
+	if !desk.IsActive {
+		t.Error("desk imported without is_active should default to active")
+	}
+
+	active := map[string]bool{}
+	for _, v := range lamp.Variants {
+		active[v.SKU] = v.IsActive
+	}
+	if len(active) != 2 || active["LAMP-RED"] || !active["LAMP-BLUE"] {
+		t.Errorf("variants active = %v, want LAMP-RED inactive and LAMP-BLUE active", active)
+	}
+	if lamp.Quantity != 2 {
+		t.Errorf("lamp quantity = %d, want 2 from the active variant only", lamp.Quantity)
+	}
+}
+
+func TestImportProductsDryRunWritesNothing(t *testing.T) {
+	store := testutil.UseDB(t, &DB)
+	if err := DB.Create(&models.Product{Name: "Desk", Slug: "desk", Price: 100, IsActive: true}).Error; err != nil {
+		t.Fatal(err)
+	}
+	str := func(s string) *string { return &s }
+	num := func(f float64) *float64 { return &f }
+
+	rows := []ProductImportRow{
+		{Line: 2, Slug: "desk", Price: num(120)},
+		{Line: 3, Slug: "lamp", Name: str("Lamp"), Price: num(30)},
+	}
+	summary, err := ImportProducts(rows, nil, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Applied || summary.Created != 1 || summary.Updated != 1 {
+		t.Fatalf("summary = %+v, want 1 created and 1 updated, not applied", summary)
+	}
+	products := store.Rows("products")
+	if len(products) != 1 || products[0]["price"] != float64(100) {
+		t.Errorf("products = %v, want only the desk at its old price", products)
+	}
+}
@@ -1,15 +1,56 @@
 package database
 
 import (
+	"errors"
+
 	"ecommerce-gin/internal/models"
+
+	"gorm.io/gorm"
 )
 
+// how often CreateProduct picks another slug when a concurrent create took it
+const slugAttempts = 5
+
+// CreateProduct stores a new product under a free slug made from its name. A
+// product created at the same time may take the slug between the check and the
+// insert; the unique index then rejects this one and the next free slug is tried.
 func CreateProduct(p *models.Product) error {
-	return DB.Create(p).Error
+	for attempt := 1; ; attempt++ {
+		slug, err := NewProductSlug(DB, p.Name, 0)
+		if err != nil {
+			return err
+		}
+		p.Slug = slug
+		err = DB.Create(p).Error
+		if err == nil || attempt == slugAttempts || !isDuplicateKey(err) {
+			return err
+		}
+	}
 }
 
+// isDuplicateKey reports whether err is a unique index violation. Connect turns
+// on TranslateError, so gorm reports those as ErrDuplicatedKey.
+func isDuplicateKey(err error) bool {
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
+
+// UpdateProduct applies the changes. When they change the slug, the old one goes
+// to the slug history so its links keep working.
 func UpdateProduct(id uint, data map[string]any) error {
-	return DB.Model(&models.Product{}).Where("id = ?", id).Updates(data).Error
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var current models.Product
+		if err := tx.Select("id", "slug").First(&current, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Product{}).Where("id = ?", id).Updates(data).Error; err != nil {
+			return err
+		}
+		slug, ok := data["slug"].(string)
+		if !ok || slug == current.Slug {
+			return nil
+		}
+		return recordSlugChange(tx, id, current.Slug, slug)
+	})
 }
 
 func DeleteProduct(id uint) error {
@@ -25,31 +66,140 @@ func GetProductByID(id uint) (*models.Product, error) {
 	return &p, nil
 }
 
+// GetProductBySlug loads a product for the storefront, with its gallery, options and active variants
 func GetProductBySlug(slug string) (*models.Product, error) {
 	var p models.Product
-	err := DB.Where("slug = ?", slug).First(&p).Error
+	err := DB.Preload("Category").
+		Preload("Images", orderImages).
+		Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Where("is_active = ?", true).Order("id") }).
+		Where("slug = ?", slug).First(&p).Error
 	if err != nil {
 		return nil, err
 	}
 	return &p, nil
 }
 
-func ListProducts(limit, offset int, search, category string) ([]models.Product, int64, error) {
+// ProductFilter narrows a product listing; nil fields do not restrict it
+type ProductFilter struct {
+	IDs         []uint // e.g. the matches of a search
+	CategoryIDs []uint
+	MinPrice    *float64
+	MaxPrice    *float64
+	InStock     *bool
+	Sort        []ProductSort // applied in order, newest first when empty
+}
+
+// ProductSort is one key of a listing order
+type ProductSort struct {
+	Key  string
+	Desc bool
+}
+
+// productSortColumns whitelists the sort keys
+var productSortColumns = map[string]string{
+	"price":      "price",
+	"newest":     "created_at",
+	"name":       "name",
+	"popularity": "sales_count",
+	"rating":     "rating_average",
+}
+
+// IsProductSortKey reports whether ListProducts can sort by key
+func IsProductSortKey(key string) bool {
+	_, ok := productSortColumns[key]
+	return ok
+}
+
+// ListProducts lists the active products matching the filter, each with only its primary image
+func ListProducts(limit, offset int, filter ProductFilter) ([]models.Product, int64, error) {
 	var products []models.Product
 	var total int64
 
 	query := DB.Model(&models.Product{}).Where("is_active = ?", true)
 
-	if search != "" {
-		query = query.Where("name LIKE ?", "%"+search+"%")
+	if filter.IDs != nil {
+		query = query.Where("id IN ?", filter.IDs)
+	}
+	if filter.CategoryIDs != nil {
+		query = query.Where("category_id IN ?", filter.CategoryIDs)
+	}
+	if filter.MinPrice != nil {
+		query = query.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("price <= ?", *filter.MaxPrice)
+	}
+	if filter.InStock != nil {
+		if *filter.InStock {
+			query = query.Where("quantity > 0")
+		} else {
+			query = query.Where("quantity <= 0")
+		}
 	}
 
-	if category != "" {
-		query = query.Where("category = ?", category)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
 
-	query.Count(&total)
+	for _, s := range filter.Sort {
+		column, ok := productSortColumns[s.Key]
+		if !ok {
+			continue
+		}
+		if s.Desc {
+			column += " DESC"
+		}
+		query = query.Order(column)
+	}
+	// ties, and the default, go newest first so pages do not overlap
+	query = query.Order("id DESC")
 
-	err := query.Limit(limit).Offset(offset).Find(&products).Error
+	err := query.Preload("Category").Preload("Images", primaryImageOnly).Limit(limit).Offset(offset).Find(&products).Error
 	return products, total, err
 }
+
+// ListSearchableProducts loads the active products the search index should hold;
+// ids limits it to those products, nil loads all of them
+func ListSearchableProducts(ids []uint) ([]models.Product, error) {
+	var products []models.Product
+	query := DB.Where("is_active = ?", true)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+	err := query.Find(&products).Error
+	return products, err
+}
+
+// GetProductsByIDs loads products in the order of ids, skipping missing ones, each
+// with only its primary image
+func GetProductsByIDs(ids []uint) ([]models.Product, error) {
+	var found []models.Product
+	if err := DB.Preload("Category").Preload("Images", primaryImageOnly).Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Product, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+	products := make([]models.Product, 0, len(found))
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			products = append(products, p)
+		}
+	}
+	return products, nil
+}
+
+// BackfillSalesCounts sets every product's sales count from the orders that were
+// not cancelled
+func BackfillSalesCounts(db *gorm.DB) error {
+	return db.Exec(`UPDATE products SET sales_count = (
+		SELECT COALESCE(SUM(order_items.quantity), 0) FROM order_items
+		JOIN orders ON orders.id = order_items.order_id
+		WHERE order_items.product_id = products.id
+			AND order_items.deleted_at IS NULL
+			AND orders.deleted_at IS NULL
+			AND orders.status <> 'cancelled'
+	)`).Error
+}
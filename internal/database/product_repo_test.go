@@ -0,0 +1,41 @@
+package database
+
+import (
+	"testing"
+
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+func TestCreateProductRetriesSlugTakenConcurrently(t *testing.T) {
+	store := testutil.UseDB(t, &DB)
+	store.Unique("products", "slug")
+
+	// another request stores "Shirt" after this one checked the slug but before it inserts
+	raced := false
+	err := DB.Callback().Create().Before("gorm:create").Register("test:race", func(tx *gorm.DB) {
+		if raced || tx.Statement.Table != "products" {
+			return
+		}
+		raced = true
+		if err := DB.Create(&models.Product{Name: "Shirt", Slug: "shirt", IsActive: true}).Error; err != nil {
+			t.Error(err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	product := &models.Product{Name: "Shirt", Price: 20, IsActive: true}
+	if err := CreateProduct(product); err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	if product.Slug != "shirt-2" {
+		t.Errorf("slug = %q, want shirt-2", product.Slug)
+	}
+	if rows := store.Rows("products"); len(rows) != 2 {
+		t.Errorf("stored %d products, want 2", len(rows))
+	}
+}
@@ -0,0 +1,233 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"ecommerce-gin/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrReviewExists is returned when the customer already reviewed the product
+var ErrReviewExists = errors.New("you already reviewed this product")
+
+// ReviewFilter narrows the public list of a product's reviews
+type ReviewFilter struct {
+	Rating       int // 0 for any
+	VerifiedOnly bool
+	Sort         string // newest (default), oldest, highest or lowest
+}
+
+var reviewSortOrders = map[string]string{
+	"newest":  "created_at DESC, id DESC",
+	"oldest":  "created_at, id",
+	"highest": "rating DESC, created_at DESC, id DESC",
+	"lowest":  "rating, created_at DESC, id DESC",
+}
+
+// IsReviewSort reports whether ListProductReviews can sort by key
+func IsReviewSort(key string) bool {
+	_, ok := reviewSortOrders[key]
+	return ok
+}
+
+// authorPreload loads only the name of the author, who may have deleted the account
+func authorPreload(db *gorm.DB) *gorm.DB {
+	return db.Unscoped().Select("id", "name")
+}
+
+func fillAuthorNames(reviews []models.ProductReview) {
+	for i := range reviews {
+		if reviews[i].User != nil {
+			reviews[i].AuthorName = reviews[i].User.Name
+		}
+	}
+}
+
+// HasDeliveredPurchase reports whether the user has a delivered order containing the product
+func HasDeliveredPurchase(tx *gorm.DB, userID, productID uint) (bool, error) {
+	var count int64
+	err := tx.Model(&models.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id AND orders.deleted_at IS NULL").
+		Where("orders.user_id = ? AND orders.status = ? AND order_items.product_id = ?", userID, "delivered", productID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CreateReview stores a new review, pending moderation
+func CreateReview(r *models.ProductReview) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Unscoped().Model(&models.ProductReview{}).
+			Where("product_id = ? AND user_id = ?", r.ProductID, r.UserID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrReviewExists
+		}
+
+		verified, err := HasDeliveredPurchase(tx, r.UserID, r.ProductID)
+		if err != nil {
+			return err
+		}
+		r.VerifiedPurchase = verified
+		r.Status = models.ReviewPending
+		return tx.Create(r).Error
+	})
+}
+
+// GetReview returns a review with its product and author name
+func GetReview(id uint) (*models.ProductReview, error) {
+	var r models.ProductReview
+	if err := DB.Preload("Product", unscopedPreload).Preload("User", authorPreload).First(&r, id).Error; err != nil {
+		return nil, err
+	}
+	if r.User != nil {
+		r.AuthorName = r.User.Name
+	}
+	return &r, nil
+}
+
+// UpdateReview saves the author's changes. The review goes back to moderation, so
+// an approved one leaves the product's rating until it is approved again.
+func UpdateReview(r *models.ProductReview) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		verified, err := HasDeliveredPurchase(tx, r.UserID, r.ProductID)
+		if err != nil {
+			return err
+		}
+		r.VerifiedPurchase = r.VerifiedPurchase || verified
+		r.Status = models.ReviewPending
+		r.ModerationNote = ""
+		r.ModeratedBy = nil
+		r.ModeratedAt = nil
+		if err := tx.Select("rating", "title", "body", "verified_purchase", "status",
+			"moderation_note", "moderated_by", "moderated_at").
+			Updates(r).Error; err != nil {
+			return err
+		}
+		return refreshProductRating(tx, r.ProductID)
+	})
+}
+
+// DeleteReview removes a review for good, so the author may write a new one
+func DeleteReview(r *models.ProductReview) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(&models.ProductReview{}, r.ID).Error; err != nil {
+			return err
+		}
+		return refreshProductRating(tx, r.ProductID)
+	})
+}
+
+// ModerateReview sets the moderation state of a review and updates the product's rating
+func ModerateReview(r *models.ProductReview, status, note string, moderatorID uint) error {
+	now := time.Now()
+	r.Status = status
+	r.ModerationNote = note
+	r.ModeratedBy = &moderatorID
+	r.ModeratedAt = &now
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ProductReview{}).Where("id = ?", r.ID).Updates(map[string]interface{}{
+			"status":          status,
+			"moderation_note": note,
+			"moderated_by":    moderatorID,
+			"moderated_at":    now,
+		}).Error; err != nil {
+			return err
+		}
+		return refreshProductRating(tx, r.ProductID)
+	})
+}
+
+// refreshProductRating recomputes the product's rating from its approved reviews.
+// It runs with every review write so reads never have to aggregate.
+func refreshProductRating(tx *gorm.DB, productID uint) error {
+	approved := tx.Model(&models.ProductReview{}).
+		Where("product_id = ? AND status = ?", productID, models.ReviewApproved)
+	return tx.Model(&models.Product{}).Where("id = ?", productID).Updates(map[string]interface{}{
+		"rating_count":   approved.Session(&gorm.Session{}).Select("COUNT(*)"),
+		"rating_average": approved.Session(&gorm.Session{}).Select("COALESCE(ROUND(AVG(rating), 2), 0)"),
+	}).Error
+}
+
+// MarkVerifiedPurchases flags the user's reviews of these products, once an order
+// containing them is delivered
+func MarkVerifiedPurchases(tx *gorm.DB, userID uint, productIDs []uint) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+	return tx.Model(&models.ProductReview{}).
+		Where("user_id = ? AND product_id IN ? AND verified_purchase = ?", userID, productIDs, false).
+		Update("verified_purchase", true).Error
+}
+
+// ListProductReviews returns a page of a product's approved reviews
+func ListProductReviews(productID uint, limit, offset int, filter ReviewFilter) ([]models.ProductReview, int64, error) {
+	query := DB.Model(&models.ProductReview{}).
+		Where("product_id = ? AND status = ?", productID, models.ReviewApproved)
+	if filter.Rating > 0 {
+		query = query.Where("rating = ?", filter.Rating)
+	}
+	if filter.VerifiedOnly {
+		query = query.Where("verified_purchase = ?", true)
+	}
+	order, ok := reviewSortOrders[filter.Sort]
+	if !ok {
+		order = reviewSortOrders["newest"]
+	}
+
+	var total int64
+	var reviews []models.ProductReview
+	if err := query.Count(&total).Preload("User", authorPreload).Order(order).
+		Limit(limit).Offset(offset).Find(&reviews).Error; err != nil {
+		return nil, 0, err
+	}
+	fillAuthorNames(reviews)
+	return reviews, total, nil
+}
+
+// ProductRatingBreakdown counts the approved reviews of a product per star rating
+func ProductRatingBreakdown(productID uint) (map[int]int64, error) {
+	var rows []struct {
+		Rating int
+		Count  int64
+	}
+	err := DB.Model(&models.ProductReview{}).
+		Select("rating, COUNT(*) AS count").
+		Where("product_id = ? AND status = ?", productID, models.ReviewApproved).
+		Group("rating").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	breakdown := map[int]int64{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}
+	for _, row := range rows {
+		breakdown[row.Rating] = row.Count
+	}
+	return breakdown, nil
+}
+
+// ListUserReviews returns every review the user wrote, whatever its state, newest first
+func ListUserReviews(userID uint) ([]models.ProductReview, error) {
+	var reviews []models.ProductReview
+	err := DB.Preload("Product", unscopedPreload).Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").Find(&reviews).Error
+	return reviews, err
+}
+
+// ListReviewsForModeration returns reviews in a moderation state, oldest first so
+// the queue is worked in order
+func ListReviewsForModeration(status string, limit, offset int) ([]models.ProductReview, int64, error) {
+	query := DB.Model(&models.ProductReview{}).Where("status = ?", status)
+
+	var total int64
+	var reviews []models.ProductReview
+	if err := query.Count(&total).Preload("Product", unscopedPreload).Preload("User", authorPreload).
+		Order("created_at, id").Limit(limit).Offset(offset).Find(&reviews).Error; err != nil {
+		return nil, 0, err
+	}
+	fillAuthorNames(reviews)
+	return reviews, total, nil
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// NewProductSlug picks a free slug for a product: the slugified name, else with a
+// -2, -3, ... suffix. productID is the product being renamed, 0 for a new one.
+func NewProductSlug(tx *gorm.DB, name string, productID uint) (string, error) {
+	base := utils.Slugify(name)
+	if base == "" {
+		base = "product"
+	}
+
+	slug := base
+	for i := 2; ; i++ {
+		taken, err := ProductSlugTaken(tx, slug, productID)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// ProductSlugTaken reports whether a slug belongs to another product, deleted ones
+// included, or still redirects to one
+func ProductSlugTaken(tx *gorm.DB, slug string, productID uint) (bool, error) {
+	var n int64
+	if err := tx.Unscoped().Model(&models.Product{}).
+		Where("slug = ? AND id <> ?", slug, productID).Count(&n).Error; err != nil {
+		return false, err
+	}
+	if n > 0 {
+		return true, nil
+	}
+	err := tx.Model(&models.ProductSlugHistory{}).
+		Where("slug = ? AND product_id <> ?", slug, productID).Count(&n).Error
+	return n > 0, err
+}
+
+// GetProductIDByOldSlug returns the product an old slug redirects to, 0 if none does
+func GetProductIDByOldSlug(slug string) (uint, error) {
+	var h models.ProductSlugHistory
+	err := DB.Where("slug = ?", slug).First(&h).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return h.ProductID, nil
+}
+
+// recordSlugChange keeps the old slug of a product for redirects. A product
+// taking back one of its old slugs removes it from the history.
+func recordSlugChange(tx *gorm.DB, productID uint, oldSlug, newSlug string) error {
+	if err := tx.Where("slug IN ?", []string{oldSlug, newSlug}).
+		Delete(&models.ProductSlugHistory{}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.ProductSlugHistory{ProductID: productID, Slug: oldSlug}).Error
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"errors"
+
+	"ecommerce-gin/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GetProductWithVariants loads a product with its gallery, options and all variants, active or not
+func GetProductWithVariants(id uint) (*models.Product, error) {
+	var p models.Product
+	err := DB.Preload("Images", orderImages).
+		Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&p, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ReplaceProductOptions swaps the option types of a product for the given ones
+func ReplaceProductOptions(productID uint, options []models.ProductOption) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		return replaceProductOptions(tx, productID, options)
+	})
+}
+
+func replaceProductOptions(tx *gorm.DB, productID uint, options []models.ProductOption) error {
+	if err := tx.Unscoped().Where("product_id = ?", productID).Delete(&models.ProductOption{}).Error; err != nil {
+		return err
+	}
+	if len(options) == 0 {
+		return nil
+	}
+	for i := range options {
+		options[i].ProductID = productID
+		options[i].Position = i
+	}
+	return tx.Create(&options).Error
+}
+
+func CountProductVariants(productID uint) (int64, error) {
+	var n int64
+	err := DB.Model(&models.ProductVariant{}).Where("product_id = ?", productID).Count(&n).Error
+	return n, err
+}
+
+// GetProductVariant returns a variant of the product
+func GetProductVariant(productID, variantID uint) (*models.ProductVariant, error) {
+	var v models.ProductVariant
+	if err := DB.Where("id = ? AND product_id = ?", variantID, productID).First(&v).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// GetVariantBySKU finds a variant by SKU, deleted ones included since they still hold it;
+// returns nil, nil if the SKU is free
+func GetVariantBySKU(sku string) (*models.ProductVariant, error) {
+	var v models.ProductVariant
+	err := DB.Unscoped().Where("sku = ?", sku).First(&v).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// CreateProductVariant stores a variant and updates the product's total stock
+func CreateProductVariant(v *models.ProductVariant) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(v).Error; err != nil {
+			return err
+		}
+		return syncProductQuantity(tx, v.ProductID)
+	})
+}
+
+// UpdateProductVariant saves all fields of a variant and updates the product's total stock
+func UpdateProductVariant(v *models.ProductVariant) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(v).Error; err != nil {
+			return err
+		}
+		return syncProductQuantity(tx, v.ProductID)
+	})
+}
+
+// DeleteProductVariant soft deletes a variant; order items keep pointing to it
+func DeleteProductVariant(productID, variantID uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND product_id = ?", variantID, productID).Delete(&models.ProductVariant{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// nobody can check it out anymore
+		if err := tx.Where("variant_id = ?", variantID).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		return syncProductQuantity(tx, productID)
+	})
+}
+
+// syncProductQuantity sets the product quantity to the stock of its active variants
+func syncProductQuantity(tx *gorm.DB, productID uint) error {
+	sum := tx.Model(&models.ProductVariant{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("product_id = ? AND is_active = ?", productID, true)
+	return tx.Model(&models.Product{}).Where("id = ?", productID).Update("quantity", sum).Error
+}
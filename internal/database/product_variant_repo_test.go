@@ -0,0 +1,39 @@
+package database
+
+import (
+	"testing"
+
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/testutil"
+)
+
+func TestCreateProductVariantKeepsInactive(t *testing.T) {
+	store := testutil.UseDB(t, &DB)
+	product := &models.Product{Name: "Shirt", Slug: "shirt", Price: 20, IsActive: true}
+	if err := DB.Create(product).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []*models.ProductVariant{
+		{ProductID: product.ID, SKU: "SHIRT-S", Quantity: 3, IsActive: true},
+		{ProductID: product.ID, SKU: "SHIRT-M", Quantity: 5, IsActive: false},
+	} {
+		if err := CreateProductVariant(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := GetProductWithVariants(product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Variants) != 2 || !got.Variants[0].IsActive || got.Variants[1].IsActive {
+		t.Fatalf("variants = %+v, want SHIRT-S active and SHIRT-M inactive", got.Variants)
+	}
+	if got.Quantity != 3 {
+		t.Errorf("product quantity = %d, want 3 from the active variant only", got.Quantity)
+	}
+	if rows := store.Rows("product_variants"); rows[1]["is_active"] != false {
+		t.Errorf("stored is_active = %v, want false", rows[1]["is_active"])
+	}
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"time"
+
+	"ecommerce-gin/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ReplaceRecoveryCodes deletes the user's old recovery codes and stores the new hashes
+func ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]models.RecoveryCode, len(hashes))
+		for i, h := range hashes {
+			codes[i] = models.RecoveryCode{UserID: userID, CodeHash: h}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// ConsumeRecoveryCode marks an unused code as used; returns false if none matched
+func ConsumeRecoveryCode(userID uint, hash string) (bool, error) {
+	res := DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Limit(1).
+		Update("used_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+// CountUnusedRecoveryCodes returns how many recovery codes the user has left
+func CountUnusedRecoveryCodes(userID uint) (int64, error) {
+	var n int64
+	err := DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
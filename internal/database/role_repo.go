@@ -0,0 +1,131 @@
+package database
+
+import (
+	"errors"
+
+	"ecommerce-gin/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// every known permission, seeded on startup
+var permissionSeeds = []models.Permission{
+	{Name: models.PermOrdersRead, Description: "View all orders and order statistics"},
+	{Name: models.PermOrdersUpdate, Description: "Change order status"},
+	{Name: models.PermProductsWrite, Description: "Create, update and delete products"},
+	{Name: models.PermUploadsCreate, Description: "Upload product images"},
+	{Name: models.PermUsersRead, Description: "View customer accounts"},
+	{Name: models.PermUsersWrite, Description: "Disable, unlock and log out customer accounts"},
+	{Name: models.PermUsersImpersonate, Description: "Act as a customer for support, without checkout or payment"},
+	{Name: models.PermRolesManage, Description: "Manage roles and assign them to users"},
+	{Name: models.PermAPIKeysManage, Description: "Create and revoke API keys for integrations"},
+	{Name: models.PermAuditRead, Description: "Read and export the audit log"},
+	{Name: models.PermReviewsModerate, Description: "Approve, reject and hide product reviews"},
+}
+
+// built-in roles and their initial permissions; admin always gets every permission
+var roleSeeds = []struct {
+	Name        string
+	Description string
+	Permissions []string
+}{
+	{"admin", "Full access", nil},
+	{"customer", "Shopper, no back-office access", []string{}},
+	{"support", "Customer support staff", []string{models.PermOrdersRead, models.PermUsersRead, models.PermReviewsModerate}},
+	{"warehouse", "Fulfilment staff", []string{models.PermOrdersRead, models.PermOrdersUpdate, models.PermProductsWrite}},
+}
+
+// SeedRolesAndPermissions creates missing permissions and built-in roles.
+// Permissions of existing roles are left alone, except admin which is kept complete.
+func SeedRolesAndPermissions(db *gorm.DB) error {
+	byName := map[string]models.Permission{}
+	for _, p := range permissionSeeds {
+		perm := p
+		if err := db.Where(models.Permission{Name: p.Name}).Attrs(models.Permission{Description: p.Description}).
+			FirstOrCreate(&perm).Error; err != nil {
+			return err
+		}
+		byName[perm.Name] = perm
+	}
+
+	for _, seed := range roleSeeds {
+		var role models.Role
+		err := db.Where("name = ?", seed.Name).First(&role).Error
+		created := false
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			role = models.Role{Name: seed.Name, Description: seed.Description}
+			if err := db.Create(&role).Error; err != nil {
+				return err
+			}
+			created = true
+		} else if err != nil {
+			return err
+		}
+
+		var perms []models.Permission
+		switch {
+		case seed.Name == "admin":
+			for _, p := range byName {
+				perms = append(perms, p)
+			}
+		case created:
+			for _, name := range seed.Permissions {
+				perms = append(perms, byName[name])
+			}
+		default:
+			continue
+		}
+		if err := db.Model(&role).Association("Permissions").Replace(perms); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListRoles returns all roles with their permissions
+func ListRoles() ([]models.Role, error) {
+	var roles []models.Role
+	err := DB.Preload("Permissions").Order("name").Find(&roles).Error
+	return roles, err
+}
+
+// ListPermissions returns every known permission
+func ListPermissions() ([]models.Permission, error) {
+	var perms []models.Permission
+	err := DB.Order("name").Find(&perms).Error
+	return perms, err
+}
+
+// GetRoleByName returns the role with its permissions or nil
+func GetRoleByName(name string) (*models.Role, error) {
+	var role models.Role
+	if err := DB.Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// GetPermissionsByNames returns the permissions matching names
+func GetPermissionsByNames(names []string) ([]models.Permission, error) {
+	var perms []models.Permission
+	err := DB.Where("name IN ?", names).Find(&perms).Error
+	return perms, err
+}
+
+// CreateRole saves a new role with its permissions
+func CreateRole(role *models.Role) error {
+	return DB.Create(role).Error
+}
+
+// SetRolePermissions replaces the permissions of a role
+func SetRolePermissions(role *models.Role, perms []models.Permission) error {
+	return DB.Model(role).Association("Permissions").Replace(perms)
+}
+
+// UpdateUserRole assigns a role (by name) to a user
+func UpdateUserRole(userID uint, role string) error {
+	return DB.Model(&models.User{}).Where("id = ?", userID).Update("role", role).Error
+}
@@ -0,0 +1,11 @@
+package database
+
+import (
+	"ecommerce-gin/internal/models"
+)
+
+// CreateSecurityEvent records a security relevant event for a user
+func CreateSecurityEvent(e *models.SecurityEvent) error {
+	e.UserAgent = truncate(e.UserAgent, maxUserAgentLen)
+	return DB.Create(e).Error
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"ecommerce-gin/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// the user_agent columns are varchar(512); MySQL in strict mode rejects longer values
+const maxUserAgentLen = 512
+
+// CreateSession stores a new device session (expects hashed refresh token)
+func CreateSession(s *models.Session) error {
+	s.UserAgent = truncate(s.UserAgent, maxUserAgentLen)
+	return DB.Create(s).Error
+}
+
+// truncate cuts s to at most n characters, the unit of a varchar's length
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// GetActiveSessionByTokenHash returns the unexpired, unrevoked session owning the hash or nil
+func GetActiveSessionByTokenHash(hash string) (*models.Session, error) {
+	var s models.Session
+	err := DB.Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?", hash, time.Now()).
+		First(&s).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// RotateSessionToken swaps the session's refresh token hash for a new one and keeps the
+// old hash in the session's rotation family. Returns false if oldHash is no longer current.
+func RotateSessionToken(sessionID uint, oldHash, newHash string, expiry time.Time) (bool, error) {
+	rotated := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Session{}).
+			Where("id = ? AND token_hash = ?", sessionID, oldHash).
+			Updates(map[string]interface{}{
+				"token_hash":   newHash,
+				"expires_at":   expiry,
+				"last_used_at": time.Now(),
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		rotated = true
+		return tx.Create(&models.RotatedRefreshToken{
+			SessionID: sessionID,
+			TokenHash: oldHash,
+			RotatedAt: time.Now(),
+		}).Error
+	})
+	return rotated, err
+}
+
+// GetRotatedRefreshToken returns the superseded token record for the hash or nil
+func GetRotatedRefreshToken(hash string) (*models.RotatedRefreshToken, error) {
+	var t models.RotatedRefreshToken
+	if err := DB.Where("token_hash = ?", hash).First(&t).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetSessionByID returns a session regardless of its state
+func GetSessionByID(sessionID uint) (*models.Session, error) {
+	var s models.Session
+	if err := DB.First(&s, sessionID).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// RevokeSessionFamily revokes a session and with it every refresh token of its rotation family
+func RevokeSessionFamily(sessionID uint) error {
+	return DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// ListActiveSessions returns the user's sessions that can still be refreshed, most recent first
+func ListActiveSessions(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSession revokes one session of the user; returns false if no active session matched
+func RevokeSession(userID, sessionID uint) (bool, error) {
+	res := DB.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+// RevokeAllSessions revokes every active session of the user
+func RevokeAllSessions(userID uint) error {
+	return DB.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/testutil"
+)
+
+func TestCreateTruncatesUserAgent(t *testing.T) {
+	store := testutil.UseDB(t, &DB)
+	// multibyte, so a byte count would cut a character in half
+	ua := strings.Repeat("é", maxUserAgentLen+100)
+
+	if err := CreateSession(&models.Session{UserID: 1, TokenHash: "hash", UserAgent: ua}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateSecurityEvent(&models.SecurityEvent{UserID: 1, Type: "login", UserAgent: ua}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateAuditLog(&models.AuditLog{ActorType: "anonymous", Action: "test", UserAgent: ua}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range []string{"sessions", "security_events", "audit_logs"} {
+		got, _ := store.Rows(table)[0]["user_agent"].(string)
+		if utf8.RuneCountInString(got) != maxUserAgentLen || !utf8.ValidString(got) {
+			t.Errorf("%s user_agent has %d characters, want %d", table, utf8.RuneCountInString(got), maxUserAgentLen)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"time"
+
+	"ecommerce-gin/internal/models"
+)
+
+// AdminListUsers returns users with pagination, a name/email search and role filter
+func AdminListUsers(limit, offset int, search, role string) ([]models.User, int64, error) {
+	var users []models.User
+	var total int64
+
+	query := DB.Model(&models.User{}).Order("created_at desc")
+
+	if search != "" {
+		like := "%" + search + "%"
+		query = query.Where("email LIKE ? OR name LIKE ?", like, like)
+	}
+	if role != "" {
+		query = query.Where("role = ?", role)
+	}
+
+	if err := query.Count(&total).Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// GetUserOrderStats returns the number of orders and the lifetime spend of a user.
+// Pending and cancelled orders never got paid and don't count as spend.
+func GetUserOrderStats(userID uint) (int64, float64, error) {
+	var stats struct {
+		Count int64
+		Spend float64
+	}
+	err := DB.Model(&models.Order{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(CASE WHEN status NOT IN ('pending', 'cancelled') THEN total_price ELSE 0 END), 0) AS spend").
+		Where("user_id = ?", userID).
+		Scan(&stats).Error
+	return stats.Count, stats.Spend, err
+}
+
+// SetUserDisabled disables the account at the given time, or enables it when at is nil
+func SetUserDisabled(userID uint, at *time.Time) error {
+	return DB.Model(&models.User{}).Where("id = ?", userID).Update("disabled_at", at).Error
+}
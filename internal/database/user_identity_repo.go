@@ -0,0 +1,37 @@
+package database
+
+import (
+	"errors"
+
+	"ecommerce-gin/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GetUserIdentity finds the link for a provider subject; returns nil, nil if there is none
+func GetUserIdentity(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := DB.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func CreateUserIdentity(identity *models.UserIdentity) error {
+	return DB.Create(identity).Error
+}
+
+// CreateUserWithIdentity stores a new user and its first external identity together
+func CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
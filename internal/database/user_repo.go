@@ -2,10 +2,10 @@ package database
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"ecommerce-gin/internal/models"
-	"ecommerce-gin/internal/utils"
 
 	"gorm.io/gorm"
 )
@@ -36,40 +36,101 @@ func GetUserByID(id uint) (*models.User, error) {
 	return &u, nil
 }
 
-// SaveRefreshToken stores hashed refresh token + expiry for a user
-func SaveRefreshToken(userID uint, hashedToken string, expiry time.Time) error {
+// MarkUserVerified records that the user confirmed their email address
+func MarkUserVerified(userID uint, at time.Time) error {
+	return DB.Model(&models.User{}).Where("id = ?", userID).Update("verified_at", at).Error
+}
+
+// UpdateUserPassword stores a new password hash for the user
+func UpdateUserPassword(userID uint, hashed string) error {
+	return DB.Model(&models.User{}).Where("id = ?", userID).Update("password", hashed).Error
+}
+
+// SetTOTPSecret stores a pending 2FA secret; it only becomes active with EnableTOTP
+func SetTOTPSecret(userID uint, secret string) error {
 	return DB.Model(&models.User{}).Where("id = ?", userID).
 		Updates(map[string]interface{}{
-			"refresh_token": hashedToken,
-			"token_expiry":  expiry,
+			"totp_secret":     secret,
+			"totp_enabled_at": nil,
 		}).Error
 }
 
-// ClearRefreshToken clears refresh token fields for user
-func ClearRefreshToken(userID uint) error {
-	return DB.Model(&models.User{}).Where("id = ?", userID).
+// EnableTOTP activates the user's 2FA secret
+func EnableTOTP(userID uint, at time.Time) error {
+	return DB.Model(&models.User{}).Where("id = ?", userID).Update("totp_enabled_at", at).Error
+}
+
+// DisableTOTP removes the user's 2FA secret and recovery codes
+func DisableTOTP(userID uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Updates(map[string]interface{}{
+				"totp_secret":     "",
+				"totp_enabled_at": nil,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// UpdateUserName changes the display name
+func UpdateUserName(userID uint, name string) error {
+	return DB.Model(&models.User{}).Where("id = ?", userID).Update("name", name).Error
+}
+
+// SetPendingEmail stores a requested new address until it is verified
+func SetPendingEmail(userID uint, email string) error {
+	return DB.Model(&models.User{}).Where("id = ?", userID).Update("pending_email", email).Error
+}
+
+// ConfirmPendingEmail makes the pending address the user's email; returns false if
+// email is no longer the pending one
+func ConfirmPendingEmail(userID uint, email string, at time.Time) (bool, error) {
+	res := DB.Model(&models.User{}).
+		Where("id = ? AND pending_email = ?", userID, email).
 		Updates(map[string]interface{}{
-			"refresh_token": "",
-			"token_expiry":  nil,
-		}).Error
+			"email":         email,
+			"pending_email": "",
+			"verified_at":   at,
+		})
+	return res.RowsAffected > 0, res.Error
 }
 
-// FindUserByActiveRefreshToken tries to find user(s) with token_expiry > now and checks hashed tokens.
-// This is less efficient for huge user base but works for MVP / single-token-per-user model.
-func FindUserByActiveRefreshToken(refreshToken string) (*models.User, error) {
-	var users []models.User
-	if err := DB.Where("token_expiry > ?", time.Now()).Find(&users).Error; err != nil {
-		return nil, err
-	}
-	for _, u := range users {
-		if u.RefreshToken != "" {
-			// compare hash
-			if utils.CheckTokenHash(u.RefreshToken, refreshToken) {
-				// must return pointer to record found in DB
-				user := u
-				return &user, nil
+// AnonymizeUser wipes the personal data of a user and soft deletes the account.
+// Orders are kept for accounting and still point to the anonymized user.
+func AnonymizeUser(userID uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Updates(map[string]interface{}{
+				"name":            "Deleted user",
+				"email":           fmt.Sprintf("deleted-%d@deleted.invalid", userID),
+				"pending_email":   "",
+				"password":        "",
+				"verified_at":     nil,
+				"totp_secret":     "",
+				"totp_enabled_at": nil,
+			}).Error; err != nil {
+			return err
+		}
+
+		// rows that only exist for this person; identities and passkeys go for
+		// good so the external account or authenticator can be used again
+		for _, m := range []interface{}{&models.CartItem{}, &models.RecoveryCode{}, &models.UserToken{}} {
+			if err := tx.Where("user_id = ?", userID).Delete(m).Error; err != nil {
+				return err
 			}
 		}
-	}
-	return nil, nil
+		for _, m := range []interface{}{&models.UserIdentity{}, &models.Passkey{}} {
+			if err := tx.Unscoped().Where("user_id = ?", userID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Session{}).Where("user_id = ?", userID).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "user_agent": "", "ip": "", "device_name": ""}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&models.User{}, userID).Error
+	})
 }
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"time"
+
+	"ecommerce-gin/internal/models"
+)
+
+// CreateUserToken stores a new single-use token (expects hashed token)
+func CreateUserToken(t *models.UserToken) error {
+	return DB.Create(t).Error
+}
+
+// ConsumeUserToken marks a valid token as used and returns it, or nil if it is unknown,
+// expired or already used. The conditional update makes it safe against double use.
+func ConsumeUserToken(purpose, hash string) (*models.UserToken, error) {
+	now := time.Now()
+	res := DB.Model(&models.UserToken{}).
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hash, purpose, now).
+		Update("used_at", now)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	var t models.UserToken
+	if err := DB.Where("token_hash = ?", hash).First(&t).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// InvalidateUserTokens burns every unused token of the user for the given purpose
+func InvalidateUserTokens(userID uint, purpose string) error {
+	return DB.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
@@ -1,19 +0,0 @@
-package middleware
-
-import (
-	"net/http"
-
-	"github.com/gin-gonic/gin"
-)
-
-func AdminOnly() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		roleRaw, exists := c.Get("role")
-		if !exists || roleRaw.(string) != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "admin only"})
-			c.Abort()
-			return
-		}
-		c.Next()
-	}
-}
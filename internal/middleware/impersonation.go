@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NoImpersonation blocks routes that staff must never use on a customer's behalf,
+// like paying or changing the customer's credentials
+func NoImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("impersonated") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not allowed while impersonating a user"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// actorActive checks that the staff member behind an impersonation token has not
+// been logged out or disabled since it was issued. Writes the error response if not.
+func actorActive(c *gin.Context, actorID uint, issuedAtMillis int64) bool {
+	revoked, err := services.IsAccessTokenRevoked("", actorID, 0, issuedAtMillis)
+	if err == nil && !revoked {
+		var disabled bool
+		disabled, err = services.IsUserDisabled(actorID)
+		revoked = disabled
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "token check unavailable"})
+		return false
+	}
+	if revoked {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+		return false
+	}
+	return true
+}
+
+// auditImpersonatedRequest records every request made with an impersonation token
+func auditImpersonatedRequest(c *gin.Context, actorID, userID uint) {
+	entry := &models.AuditLog{
+		ActorType:  "user",
+		ActorID:    &actorID,
+		OnBehalfOf: &userID,
+		Action:     "impersonation.request",
+		EntityType: "user",
+		EntityID:   fmt.Sprint(userID),
+		After:      fmt.Sprintf(`{"method":%q,"path":%q,"status":%d}`, c.Request.Method, c.Request.URL.Path, c.Writer.Status()),
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		RequestID:  c.GetString("request_id"),
+	}
+	if err := database.CreateAuditLog(entry); err != nil {
+		log.Println("failed to write audit log:", err)
+	}
+}
@@ -1,17 +1,26 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
-	"ecommerce-gin/internal/config"
+	"ecommerce-gin/internal/services"
+	"ecommerce-gin/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// JWTAuth accepts a user access token, or an API key in X-API-Key or as the Bearer
+// token. API key requests have no user_id; their scopes are set as "permissions".
 func JWTAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			apiKeyAuth(c, key)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing authorization header"})
@@ -23,24 +32,89 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 		tokenStr := parts[1]
+		if services.IsAPIKey(tokenStr) {
+			apiKeyAuth(c, tokenStr)
+			return
+		}
 
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrTokenUnverifiable
-			}
-			return []byte(config.Cfg.JWTSecret), nil
-		})
+		token, err := jwt.Parse(tokenStr, utils.Keys.Keyfunc)
 		if err != nil || !token.Valid {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
 		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
+		if _, isPurposeToken := claims["purpose"]; !ok || isPurposeToken {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
 			return
 		}
+
+		jti, _ := claims["jti"].(string)
+		sub, _ := claims["sub"].(float64)
+		sid, _ := claims["sid"].(float64)
+		iatMillis, _ := claims["iat_ms"].(float64)
+		revoked, err := services.IsAccessTokenRevoked(jti, uint(sub), uint(sid), int64(iatMillis))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "token check unavailable"})
+			return
+		}
+		if revoked {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+			return
+		}
+		disabled, err := services.IsUserDisabled(uint(sub))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "token check unavailable"})
+			return
+		}
+		if disabled {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "account disabled"})
+			return
+		}
+
+		// impersonation token: the staff member behind it has to be in good standing too
+		var actorID uint
+		if act, ok := claims["act"].(map[string]interface{}); ok {
+			actorSub, _ := act["sub"].(float64)
+			actorID = uint(actorSub)
+			if !actorActive(c, actorID, int64(iatMillis)) {
+				return
+			}
+			c.Set("actor_id", act["sub"])
+		}
+
+		c.Set("auth_type", "user")
 		c.Set("user_id", claims["sub"])
 		c.Set("role", claims["role"])
+		c.Set("session_id", claims["sid"])
+		c.Set("mfa", claims["mfa"] == true)
+		c.Set("jti", jti)
+		c.Set("token_exp", claims["exp"])
+		c.Set("impersonated", actorID != 0)
 		c.Next()
+
+		if actorID != 0 {
+			auditImpersonatedRequest(c, actorID, uint(sub))
+		}
+	}
+}
+
+func apiKeyAuth(c *gin.Context, raw string) {
+	key, err := services.AuthenticateAPIKey(raw)
+	if errors.Is(err, services.ErrInvalidAPIKey) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "api key check unavailable"})
+		return
+	}
+
+	perms := make(map[string]bool, len(key.Scopes))
+	for _, s := range key.Scopes {
+		perms[s] = true
 	}
+	c.Set("auth_type", "api_key")
+	c.Set("api_key_id", key.ID)
+	c.Set("permissions", perms)
+	c.Next()
 }
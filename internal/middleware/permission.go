@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+
+	"ecommerce-gin/internal/config"
+	"ecommerce-gin/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission lets the request through only if the caller holds every listed
+// permission. User tokens get the permissions of their role, API keys their scopes.
+func RequirePermission(perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+
+		var granted map[string]bool
+		if v, ok := c.Get("permissions"); ok {
+			granted = v.(map[string]bool)
+		} else {
+			var err error
+			granted, err = services.PermissionsForRole(role)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to load permissions"})
+				return
+			}
+		}
+		for _, p := range perms {
+			if !granted[p] {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing permission " + p})
+				return
+			}
+		}
+
+		if config.Cfg.RequireAdmin2FA && role == "admin" && !c.GetBool("mfa") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "two-factor authentication required"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserOnly rejects API key requests on routes that act on the logged-in user
+// (cart, orders, account settings), which an integration has no user for.
+func UserOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_type") != "user" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this endpoint requires a user login"})
+			return
+		}
+		c.Next()
+	}
+}
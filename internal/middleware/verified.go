@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"ecommerce-gin/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail blocks users that have not confirmed their email address yet
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uidRaw, _ := c.Get("user_id")
+		uid, _ := uidRaw.(float64)
+
+		user, err := database.GetUserByID(uint(uid))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		if user.VerifiedAt == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "email not verified"})
+			return
+		}
+		c.Next()
+	}
+}
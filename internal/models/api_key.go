@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey authenticates a server-to-server integration. Only the SHA-256 hash of
+// the key is stored; Prefix is kept in clear so admins can tell keys apart.
+type APIKey struct {
+	gorm.Model
+
+	Name        string     `json:"name" gorm:"type:varchar(100);not null"`
+	Prefix      string     `json:"prefix" gorm:"type:varchar(16)"`
+	KeyHash     string     `json:"-" gorm:"type:char(64);uniqueIndex"`
+	Scopes      []string   `json:"scopes" gorm:"serializer:json;type:text"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedByID uint       `json:"created_by_id"`
+}
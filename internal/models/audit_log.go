@@ -0,0 +1,37 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrAuditLogImmutable = errors.New("audit log entries cannot be changed or deleted")
+
+// AuditLog records who did what to which entity. Entries are append-only:
+// the hooks below refuse updates and deletes made through gorm.
+type AuditLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+
+	ActorType  string `json:"actor_type" gorm:"type:varchar(16)"` // user, api_key, anonymous
+	ActorID    *uint  `json:"actor_id" gorm:"index"`
+	OnBehalfOf *uint  `json:"on_behalf_of,omitempty" gorm:"index"`  // impersonated user, when staff acted as a customer
+	Action     string `json:"action" gorm:"type:varchar(64);index"` // e.g. product.update
+	EntityType string `json:"entity_type" gorm:"type:varchar(32);index:idx_audit_entity"`
+	EntityID   string `json:"entity_id" gorm:"type:varchar(64);index:idx_audit_entity"`
+	Before     string `json:"before,omitempty" gorm:"type:text"` // JSON of the changed fields before
+	After      string `json:"after,omitempty" gorm:"type:text"`  // JSON of the changed fields after
+	IP         string `json:"ip" gorm:"type:varchar(64)"`
+	UserAgent  string `json:"user_agent" gorm:"type:varchar(512)"`
+	RequestID  string `json:"request_id" gorm:"type:varchar(64);index"`
+}
+
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
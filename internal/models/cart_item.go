@@ -5,10 +5,28 @@ import "gorm.io/gorm"
 type CartItem struct {
 	gorm.Model
 
-	UserID    uint `json:"user_id"`    // This is the place to link to User model, it automatically creates foreign key relation
-	ProductID uint `json:"product_id"` // This is the place to link to User model, it automatically creates foreign key relation
-	Quantity  int  `json:"quantity"`
+	UserID    uint  `json:"user_id"`    // This is the place to link to User model, it automatically creates foreign key relation
+	ProductID uint  `json:"product_id"` // This is the place to link to User model, it automatically creates foreign key relation
+	VariantID *uint `json:"variant_id"` // set for products with variants
+	Quantity  int   `json:"quantity"`
 
 	// User    User    `json:"user" gorm:"foreignKey:UserID"`  // This helps to preload user details in cart items, not create the foreign key
-	Product Product `json:"product,omitzero" gorm:"foreignKey:ProductID"` // This is helps to preload product details in cart items  // not create the foreign key
+	Product Product         `json:"product,omitzero" gorm:"foreignKey:ProductID"` // This is helps to preload product details in cart items  // not create the foreign key
+	Variant *ProductVariant `json:"variant,omitempty" gorm:"foreignKey:VariantID"`
+}
+
+// UnitPrice is the price of one unit, honouring a variant price override
+func (ci *CartItem) UnitPrice() float64 {
+	if ci.Variant != nil {
+		return ci.Variant.EffectivePrice(ci.Product.Price)
+	}
+	return ci.Product.Price
+}
+
+// Stock is what is left of the product or variant in the cart
+func (ci *CartItem) Stock() int {
+	if ci.Variant != nil {
+		return ci.Variant.Quantity
+	}
+	return ci.Product.Quantity
 }
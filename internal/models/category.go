@@ -0,0 +1,22 @@
+package models
+
+import "gorm.io/gorm"
+
+// Category is a node in the product category tree
+type Category struct {
+	gorm.Model
+
+	Name     string `json:"name" gorm:"type:varchar(100);not null"`
+	Slug     string `json:"slug" gorm:"type:varchar(255);uniqueIndex;not null"`
+	ParentID *uint  `json:"parent_id" gorm:"index"`
+	Position int    `json:"position"` // sort order among siblings
+
+	Children []Category `json:"children,omitempty" gorm:"foreignKey:ParentID"`
+}
+
+// CategoryCrumb is one step of the path from the root to a category
+type CategoryCrumb struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
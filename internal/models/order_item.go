@@ -5,13 +5,16 @@ import "gorm.io/gorm"
 type OrderItem struct {
 	gorm.Model
 
-	OrderID   uint `json:"order_id"`
-	ProductID uint `json:"product_id"`
+	OrderID   uint   `json:"order_id"`
+	ProductID uint   `json:"product_id"`
+	VariantID *uint  `json:"variant_id"`
+	SKU       string `json:"sku" gorm:"type:varchar(64)"` // copied at checkout, variants may change later
 
 	Quantity int     `json:"quantity"`
 	Price    float64 `json:"price"`
 	Subtotal float64 `json:"subtotal"` // quantity * price
 
 	Order   Order
-	Product Product `json:"product"`
+	Product Product         `json:"product"`
+	Variant *ProductVariant `json:"variant,omitempty" gorm:"foreignKey:VariantID"`
 }
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Passkey is a WebAuthn credential registered by a user
+type Passkey struct {
+	gorm.Model
+
+	UserID       uint       `json:"-" gorm:"index"`
+	Name         string     `json:"name" gorm:"type:varchar(100)"`
+	CredentialID string     `json:"credential_id" gorm:"type:varchar(255);uniqueIndex;not null"` // base64url
+	PublicKey    []byte     `json:"-" gorm:"not null"`                                           // COSE key
+	SignCount    uint32     `json:"-"`
+	AAGUID       string     `json:"aaguid" gorm:"type:char(32)"` // authenticator model, hex
+	LastUsedAt   *time.Time `json:"last_used_at"`
+}
@@ -12,7 +12,25 @@ type Product struct {
 	Description string  `json:"description"`
 	Price       float64 `json:"price"`
 	Quantity    int     `json:"quantity"`
-	Category    string  `json:"category"`
-	ImageURL    string  `json:"image_url"`
+	CategoryID  *uint   `json:"category_id" gorm:"index"`
+	ImageURL    string  `json:"image_url"` // URL of the primary image
 	IsActive    bool    `json:"is_active" gorm:"default:true"`
+	SalesCount  int     `json:"sales_count" gorm:"not null;default:0;index"` // units sold in orders that were not cancelled
+
+	// over the approved reviews, kept up to date by every review write
+	RatingAverage float64 `json:"rating_average" gorm:"not null;default:0;index"`
+	RatingCount   int     `json:"rating_count" gorm:"not null;default:0"`
+
+	Category    *Category       `json:"category,omitempty"`
+	Breadcrumbs []CategoryCrumb `json:"breadcrumbs,omitempty" gorm:"-"` // filled for the product page
+
+	// the whole gallery on the product page, only the primary image in lists
+	Images []ProductImage `json:"images,omitempty" gorm:"foreignKey:ProductID"`
+
+	// LegacyCategory is the old free-text category, read once by MigrateLegacyCategories
+	LegacyCategory string `json:"-" gorm:"column:category"`
+
+	// products with variants are sold per variant; Quantity is then the sum of their stock
+	Options  []ProductOption  `json:"options,omitempty" gorm:"foreignKey:ProductID"`
+	Variants []ProductVariant `json:"variants,omitempty" gorm:"foreignKey:ProductID"`
 }
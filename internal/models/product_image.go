@@ -0,0 +1,17 @@
+package models
+
+import "gorm.io/gorm"
+
+// ProductImage is a picture in a product's gallery. Exactly one image of a
+// product with images is primary; Product.ImageURL mirrors its URL.
+type ProductImage struct {
+	gorm.Model
+
+	ProductID uint   `json:"product_id" gorm:"index"`
+	URL       string `json:"url" gorm:"type:varchar(1024);not null"`
+	AltText   string `json:"alt_text" gorm:"type:varchar(255)"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Position  int    `json:"position"`
+	IsPrimary bool   `json:"is_primary"`
+}
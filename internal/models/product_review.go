@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Review moderation states. Only approved reviews are public and count towards
+// the product's rating.
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+	ReviewHidden   = "hidden" // approved before, taken down later
+)
+
+// ProductReview is a customer's rating of a product; one per customer and product
+type ProductReview struct {
+	gorm.Model
+
+	ProductID uint   `json:"product_id" gorm:"uniqueIndex:idx_review_product_user;not null"`
+	UserID    uint   `json:"user_id" gorm:"uniqueIndex:idx_review_product_user;index;not null"`
+	Rating    int    `json:"rating" gorm:"not null"` // 1 to 5
+	Title     string `json:"title" gorm:"type:varchar(200)"`
+	Body      string `json:"body" gorm:"type:text"`
+
+	// the author had a delivered order with the product when writing, or since
+	VerifiedPurchase bool `json:"verified_purchase"`
+
+	Status         string     `json:"status" gorm:"type:varchar(16);index;not null;default:pending"`
+	ModerationNote string     `json:"moderation_note,omitempty" gorm:"type:varchar(500)"` // shown to the author
+	ModeratedBy    *uint      `json:"moderated_by,omitempty"`
+	ModeratedAt    *time.Time `json:"moderated_at,omitempty"`
+
+	AuthorName string   `json:"author_name" gorm:"-"`
+	User       *User    `json:"-"`
+	Product    *Product `json:"product,omitempty"`
+}
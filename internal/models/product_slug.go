@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// ProductSlugHistory keeps a slug a product used before, so old links can be
+// redirected to its current slug
+type ProductSlugHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ProductID uint      `json:"product_id" gorm:"index;not null"`
+	Slug      string    `json:"slug" gorm:"type:varchar(255);uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ProductOption is a dimension a product comes in, e.g. "Size" with S, M and L
+type ProductOption struct {
+	gorm.Model
+
+	ProductID uint     `json:"product_id" gorm:"index"`
+	Name      string   `json:"name" gorm:"type:varchar(64);not null"`
+	Position  int      `json:"position"`
+	Values    []string `json:"values" gorm:"serializer:json;type:text"`
+}
+
+// ProductVariant is one purchasable combination of option values with its own
+// SKU and stock. Price overrides the product price when set.
+type ProductVariant struct {
+	gorm.Model
+
+	ProductID uint              `json:"product_id" gorm:"index"`
+	SKU       string            `json:"sku" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Price     *float64          `json:"price"`
+	Quantity  int               `json:"quantity"`
+	ImageURL  string            `json:"image_url"`
+	IsActive  bool              `json:"is_active"`                                // no column default, gorm would skip a false value
+	Options   map[string]string `json:"options" gorm:"serializer:json;type:text"` // option name -> value
+}
+
+// EffectivePrice is the variant price, falling back to the product's
+func (v *ProductVariant) EffectivePrice(productPrice float64) float64 {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return productPrice
+}
+
+// OptionKey identifies the option combination, independent of map order
+func (v *ProductVariant) OptionKey() string {
+	parts := make([]string, 0, len(v.Options))
+	for name, value := range v.Options {
+		parts = append(parts, strings.ToLower(name)+"="+strings.ToLower(value))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ";")
+}
+
+// CanonicalVariantOptions spells option names and values the way the product
+// defines them, so "size": "m" becomes "Size": "M"; unknown entries are kept as given
+func CanonicalVariantOptions(options []ProductOption, given map[string]string) map[string]string {
+	out := make(map[string]string, len(given))
+	for name, value := range given {
+		out[name] = value
+		for _, o := range options {
+			if !strings.EqualFold(o.Name, name) {
+				continue
+			}
+			delete(out, name)
+			out[o.Name] = value
+			for _, v := range o.Values {
+				if strings.EqualFold(v, value) {
+					out[o.Name] = v
+				}
+			}
+		}
+	}
+	return out
+}
+
+// CheckVariantOptions requires exactly one allowed value for every option of the product
+func CheckVariantOptions(options []ProductOption, values map[string]string) error {
+	for _, o := range options {
+		value, ok := values[o.Name]
+		if !ok {
+			return fmt.Errorf("missing value for option %s", o.Name)
+		}
+		allowed := false
+		for _, v := range o.Values {
+			if v == value {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%s is not a value of option %s", value, o.Name)
+		}
+	}
+	if len(values) != len(options) {
+		return errors.New("variant has options the product does not define")
+	}
+	return nil
+}
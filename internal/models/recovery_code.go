@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCode is a one-time 2FA backup code. Only the SHA-256 hash is stored.
+type RecoveryCode struct {
+	gorm.Model
+
+	UserID   uint       `json:"user_id" gorm:"index"`
+	CodeHash string     `json:"-" gorm:"type:char(64);index"`
+	UsedAt   *time.Time `json:"used_at"`
+}
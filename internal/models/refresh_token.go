@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RotatedRefreshToken remembers a refresh token that has already been rotated away.
+// Every token ever issued for a Session belongs to that session's rotation family,
+// so seeing one of these again means the family has been compromised.
+type RotatedRefreshToken struct {
+	gorm.Model
+
+	SessionID uint      `json:"session_id" gorm:"index"`
+	TokenHash string    `json:"-" gorm:"type:char(64);uniqueIndex;not null"`
+	RotatedAt time.Time `json:"rotated_at"`
+}
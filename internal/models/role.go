@@ -0,0 +1,35 @@
+package models
+
+import "gorm.io/gorm"
+
+// Permission names, "<resource>:<action>"
+const (
+	PermOrdersRead       = "orders:read"
+	PermOrdersUpdate     = "orders:update"
+	PermProductsWrite    = "products:write"
+	PermUploadsCreate    = "uploads:create"
+	PermUsersRead        = "users:read"
+	PermUsersWrite       = "users:write"
+	PermUsersImpersonate = "users:impersonate"
+	PermRolesManage      = "roles:manage"
+	PermAPIKeysManage    = "api_keys:manage"
+	PermAuditRead        = "audit:read"
+	PermReviewsModerate  = "reviews:moderate"
+)
+
+// Permission is a single capability that can be granted to roles
+type Permission struct {
+	gorm.Model
+
+	Name        string `json:"name" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Description string `json:"description"`
+}
+
+// Role is a named set of permissions; User.Role holds the role name
+type Role struct {
+	gorm.Model
+
+	Name        string       `json:"name" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions"`
+}
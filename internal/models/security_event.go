@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+type SecurityEvent struct {
+	gorm.Model
+
+	UserID    uint   `json:"user_id" gorm:"index"`
+	SessionID uint   `json:"session_id"`
+	Type      string `json:"type" gorm:"type:varchar(64);index"` // refresh_token_reuse, ...
+	IP        string `json:"ip" gorm:"type:varchar(64)"`
+	UserAgent string `json:"user_agent" gorm:"type:varchar(512)"`
+	Details   string `json:"details" gorm:"type:text"`
+}
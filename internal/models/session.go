@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session represents one logged-in device. Only the SHA-256 hash of the
+// refresh token is stored and it doubles as the lookup key on refresh.
+type Session struct {
+	gorm.Model
+
+	UserID     uint       `json:"user_id" gorm:"index"`
+	TokenHash  string     `json:"-" gorm:"type:char(64);uniqueIndex;not null"`
+	DeviceName string     `json:"device_name"`
+	UserAgent  string     `json:"user_agent" gorm:"type:varchar(512)"`
+	IP         string     `json:"ip" gorm:"type:varchar(64)"`
+	MFA        bool       `json:"mfa"` // logged in with a second factor
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
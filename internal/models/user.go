@@ -12,11 +12,17 @@ type User struct {
 	Name     string `json:"name"`
 	Email    string `json:"email" gorm:"type:varchar(255);uniqueIndex;not null"`
 	Password string `json:"-"`                            // don't expose
-	Role     string `json:"role" gorm:"default:customer"` // "admin" or "customer"
+	Role     string `json:"role" gorm:"default:customer"` // name of a Role
 
-	RefreshToken string     `json:"-" gorm:"type:text"` // hashed
-	TokenExpiry  *time.Time `json:"-"`                  // refresh token expiry
+	VerifiedAt   *time.Time `json:"verified_at"`             // nil until the email address is confirmed
+	PendingEmail string     `json:"pending_email,omitempty"` // requested new address, waiting for verification
+
+	TOTPSecret    string     `json:"-"`               // base32, set during 2FA setup
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at"` // nil until setup is confirmed with a code
+
+	DisabledAt *time.Time `json:"disabled_at"` // set by an admin; disabled accounts cannot log in
 
 	CartItems []CartItem
 	Orders    []Order
+	Sessions  []Session `json:"-"`
 }
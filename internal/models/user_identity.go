@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+// UserIdentity links an account at an external OIDC provider to a local user
+type UserIdentity struct {
+	gorm.Model
+
+	UserID   uint   `json:"user_id" gorm:"index"`
+	Provider string `json:"provider" gorm:"type:varchar(64);uniqueIndex:idx_identity_provider_subject"`
+	Subject  string `json:"-" gorm:"type:varchar(255);uniqueIndex:idx_identity_provider_subject"`
+	Email    string `json:"email"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserToken is a single-use secret mailed to a user (password reset, ...).
+// Only the SHA-256 hash is stored.
+type UserToken struct {
+	gorm.Model
+
+	UserID    uint       `json:"user_id" gorm:"index"`
+	Purpose   string     `json:"purpose" gorm:"type:varchar(32);index"` // password_reset
+	TokenHash string     `json:"-" gorm:"type:char(64);uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
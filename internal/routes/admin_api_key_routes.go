@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"ecommerce-gin/internal/controllers"
+	"ecommerce-gin/internal/middleware"
+	"ecommerce-gin/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAdminAPIKeyRoutes(r *gin.Engine, group ...*gin.RouterGroup) {
+	admin := group[0].Group("/admin/api-keys")
+	// keys are managed by people, an integration cannot mint new keys
+	admin.Use(middleware.UserOnly(), middleware.RequirePermission(models.PermAPIKeysManage))
+
+	admin.GET("", controllers.AdminListAPIKeysHandler)
+	admin.POST("", controllers.AdminCreateAPIKeyHandler)
+	admin.DELETE("/:id", controllers.AdminRevokeAPIKeyHandler)
+}
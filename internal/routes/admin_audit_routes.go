@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"ecommerce-gin/internal/controllers"
+	"ecommerce-gin/internal/middleware"
+	"ecommerce-gin/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAdminAuditRoutes(r *gin.Engine, group ...*gin.RouterGroup) {
+	admin := group[0].Group("/admin/audit-logs")
+	admin.Use(middleware.RequirePermission(models.PermAuditRead))
+
+	admin.GET("", controllers.AdminListAuditLogsHandler)
+	admin.GET("/export", controllers.AdminExportAuditLogsHandler)
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"ecommerce-gin/internal/controllers"
+	"ecommerce-gin/internal/middleware"
+	"ecommerce-gin/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAdminRoleRoutes(r *gin.Engine, group ...*gin.RouterGroup) {
+	admin := group[0].Group("/admin")
+	admin.Use(middleware.RequirePermission(models.PermRolesManage))
+
+	admin.GET("/roles", controllers.AdminListRolesHandler)
+	admin.POST("/roles", controllers.AdminCreateRoleHandler)
+	admin.PUT("/roles/:name/permissions", controllers.AdminSetRolePermissionsHandler)
+	admin.GET("/permissions", controllers.AdminListPermissionsHandler)
+}
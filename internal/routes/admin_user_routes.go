@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"ecommerce-gin/internal/controllers"
+	"ecommerce-gin/internal/middleware"
+	"ecommerce-gin/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAdminUserRoutes(r *gin.Engine, group ...*gin.RouterGroup) {
+	admin := group[0].Group("/admin/users")
+
+	canRead := middleware.RequirePermission(models.PermUsersRead)
+	canWrite := middleware.RequirePermission(models.PermUsersWrite)
+
+	admin.GET("", canRead, controllers.AdminListUsersHandler)
+	admin.GET("/:id", canRead, controllers.AdminGetUserHandler)
+	admin.POST("/:id/disable", canWrite, controllers.AdminDisableUserHandler)
+	admin.POST("/:id/enable", canWrite, controllers.AdminEnableUserHandler)
+	admin.POST("/:id/logout", canWrite, controllers.AdminRevokeUserTokensHandler)
+	admin.POST("/:id/revoke-tokens", canWrite, controllers.AdminRevokeUserTokensHandler)
+	admin.POST("/:id/unlock", canWrite, controllers.AdminUnlockUserHandler)
+	admin.POST("/:id/impersonate", middleware.UserOnly(), middleware.NoImpersonation(),
+		middleware.RequirePermission(models.PermUsersImpersonate), controllers.AdminImpersonateUserHandler)
+	admin.PUT("/:id/role", middleware.RequirePermission(models.PermRolesManage), controllers.AdminAssignRoleHandler)
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"ecommerce-gin/internal/controllers"
+	"ecommerce-gin/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,6 +11,7 @@ func RegisterCartRoutes(r *gin.Engine, group ...*gin.RouterGroup) {
 
 	cart := group[0].Group("/cart")
 	// cart.Use(middleware.JWTAuth()) // dont need to add again as group already has it
+	cart.Use(middleware.UserOnly())
 
 	cart.POST("/add", controllers.AddToCart)
 	cart.GET("/", controllers.GetCart)
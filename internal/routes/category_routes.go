@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"ecommerce-gin/internal/controllers"
+	"ecommerce-gin/internal/middleware"
+	"ecommerce-gin/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterCategoryRoutes(r *gin.Engine, group ...*gin.RouterGroup) {
+
+	// Public
+	r.GET("/categories", controllers.ListCategories)
+	r.GET("/categories/:slug", controllers.GetCategory)
+
+	// Admin only, part of managing the catalog
+	admin := group[0].Group("/admin/categories") // /api/admin/categories
+	admin.Use(middleware.RequirePermission(models.PermProductsWrite))
+
+	admin.POST("/", controllers.CreateCategory)
+	admin.PUT("/:id", controllers.UpdateCategory)
+	admin.DELETE("/:id", controllers.DeleteCategory)
+}
@@ -4,13 +4,15 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"ecommerce-gin/internal/controllers"
+	"ecommerce-gin/internal/middleware"
 )
 
 func RegisterOrderRoutes(r *gin.Engine, group ...*gin.RouterGroup) {
 	order := group[0].Group("/orders")
 	// order.Use(middleware.JWTAuth()) // dont need to add again as group already has it
+	order.Use(middleware.UserOnly())
 
-	order.POST("/checkout", controllers.Checkout)
+	order.POST("/checkout", middleware.NoImpersonation(), middleware.RequireVerifiedEmail(), controllers.Checkout)
 	order.GET("/", controllers.MyOrders)
 	order.GET("/:id", controllers.OrderDetails)
 }
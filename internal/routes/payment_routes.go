@@ -2,6 +2,7 @@ package routes
 
 import (
 	"ecommerce-gin/internal/controllers"
+	"ecommerce-gin/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,6 +11,6 @@ func RegisterPaymentRoutes(r *gin.Engine, group ...*gin.RouterGroup) {
 
 	p := group[0].Group("/payment")
 
-	p.POST("/start/:orderId", controllers.StartPaymentHandler)
+	p.POST("/start/:orderId", middleware.UserOnly(), middleware.NoImpersonation(), controllers.StartPaymentHandler)
 	p.POST("/webhook", controllers.PaymentWebhook)
 }
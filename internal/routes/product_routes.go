@@ -3,6 +3,7 @@ package routes
 import (
 	"ecommerce-gin/internal/controllers"
 	"ecommerce-gin/internal/middleware"
+	"ecommerce-gin/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,13 +12,32 @@ func RegisterProductRoutes(r *gin.Engine, group ...*gin.RouterGroup) {
 
 	// Public
 	r.GET("/products", controllers.ListProducts) // /api/products
+	r.GET("/products/search", controllers.SearchProducts)
 	r.GET("/products/:slug", middleware.RateLimit(2), controllers.GetProduct)
 
 	// Admin only
 	admin := group[0].Group("/admin/products") // /api/admin/products
-	admin.Use(middleware.AdminOnly())
+	admin.Use(middleware.RequirePermission(models.PermProductsWrite))
 
 	admin.POST("/", controllers.CreateProduct)
+	admin.GET("/:id", controllers.AdminGetProduct)
 	admin.PUT("/:id", controllers.UpdateProduct)
 	admin.DELETE("/:id", controllers.DeleteProduct)
+
+	// options and variants
+	admin.PUT("/:id/options", controllers.SetProductOptions)
+	admin.POST("/:id/variants", controllers.CreateProductVariant)
+	admin.PUT("/:id/variants/:variant_id", controllers.UpdateProductVariant)
+	admin.DELETE("/:id/variants/:variant_id", controllers.DeleteProductVariant)
+
+	// image gallery
+	admin.POST("/:id/images", controllers.AttachProductImage)
+	admin.PUT("/:id/images/order", controllers.ReorderProductImages)
+	admin.PUT("/:id/images/:image_id", controllers.UpdateProductImage)
+	admin.DELETE("/:id/images/:image_id", controllers.DetachProductImage)
+
+	// bulk import and export
+	admin.POST("/import", controllers.ImportProducts)
+	admin.GET("/import/:job_id", controllers.GetImportJob)
+	admin.GET("/export", controllers.ExportProducts)
 }
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"ecommerce-gin/internal/controllers"
+	"ecommerce-gin/internal/middleware"
+	"ecommerce-gin/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterReviewRoutes(r *gin.Engine, group ...*gin.RouterGroup) {
+	// Public
+	r.GET("/products/:slug/reviews", controllers.ListProductReviews)
+
+	// Customers, in their own name only
+	me := group[0].Group("", middleware.UserOnly())
+	me.POST("/products/:slug/reviews", middleware.NoImpersonation(), middleware.RequireVerifiedEmail(), controllers.CreateReview)
+	me.GET("/reviews", controllers.MyReviews)
+	me.PUT("/reviews/:id", middleware.NoImpersonation(), controllers.UpdateReview)
+	me.DELETE("/reviews/:id", middleware.NoImpersonation(), controllers.DeleteReview)
+
+	// Moderation
+	admin := group[0].Group("/admin/reviews")
+	admin.Use(middleware.RequirePermission(models.PermReviewsModerate))
+
+	admin.GET("", controllers.AdminListReviewsHandler)
+	admin.PUT("/:id/status", controllers.AdminModerateReviewHandler)
+}
@@ -11,24 +11,65 @@ func SetupRoutes(r *gin.Engine) *gin.RouterGroup {
 	// Public auth routes
 	r.POST("/auth/signup", controllers.Signup)
 	r.POST("/auth/login", controllers.Login)
+	r.POST("/auth/login/2fa", controllers.LoginTwoFactor)
 	r.POST("/auth/refresh", controllers.Refresh)
+	r.GET("/auth/verify-email", controllers.VerifyEmail)
+	r.POST("/auth/verify-email/resend", controllers.ResendVerification)
+	r.POST("/auth/password/forgot", controllers.ForgotPassword)
+	r.POST("/auth/password/reset", controllers.ResetPassword)
+
+	// Passwordless login
+	r.POST("/auth/magic-link", controllers.RequestMagicLink)
+	r.POST("/auth/magic-link/verify", controllers.VerifyMagicLink)
+	r.POST("/auth/passkeys/login/begin", controllers.PasskeyLoginBegin)
+	r.POST("/auth/passkeys/login/finish", controllers.PasskeyLoginFinish)
+
+	// Social login (OIDC)
+	r.GET("/auth/oidc/providers", controllers.ListOIDCProviders)
+	r.GET("/auth/oidc/:provider/login", controllers.OIDCLogin)
+	r.GET("/auth/oidc/:provider/callback", controllers.OIDCCallback)
+
+	// Public keys for verifying our access tokens
+	r.GET("/.well-known/jwks.json", controllers.JWKS)
 
 	// Protected API group
 	api := r.Group("/api")
 	api.Use(middleware.JWTAuth())
 
+	// everything below acts on the logged-in user, API keys are not accepted
+	me := api.Group("", middleware.UserOnly())
+
 	// logout (requires valid access token)
-	api.POST("/auth/logout", controllers.Logout)
-
-	// example protected endpoint
-	api.GET("/profile", func(c *gin.Context) {
-		uid, _ := c.Get("user_id")
-		role := c.GetString("role")
-		c.JSON(200, gin.H{
-			"user_id": uid,
-			"role":    role,
-		})
-	})
+	me.POST("/auth/logout", controllers.Logout)
+
+	me.GET("/profile", controllers.GetProfile)
+
+	// account settings, off limits to staff impersonating the user
+	account := me.Group("", middleware.NoImpersonation())
+
+	account.POST("/auth/password/change", controllers.ChangePassword)
+
+	// two-factor authentication
+	account.POST("/auth/2fa/setup", controllers.TwoFactorSetup)
+	account.POST("/auth/2fa/enable", controllers.TwoFactorEnable)
+	account.POST("/auth/2fa/disable", controllers.TwoFactorDisable)
+	account.POST("/auth/2fa/recovery-codes", controllers.RegenerateRecoveryCodes)
+
+	// passkeys
+	account.GET("/auth/passkeys", controllers.ListPasskeys)
+	account.POST("/auth/passkeys/register/begin", controllers.PasskeyRegisterBegin)
+	account.POST("/auth/passkeys/register/finish", controllers.PasskeyRegisterFinish)
+	account.DELETE("/auth/passkeys/:id", controllers.DeletePasskey)
+
+	// device sessions
+	account.GET("/auth/sessions", controllers.ListSessions)
+	account.DELETE("/auth/sessions", controllers.RevokeAllSessions)
+	account.DELETE("/auth/sessions/:id", controllers.RevokeSession)
+
+	// profile changes and data export
+	account.PUT("/profile", controllers.UpdateProfile)
+	account.DELETE("/profile", controllers.DeleteProfile)
+	account.GET("/profile/export", controllers.ExportProfile)
 
 	return api
 }
@@ -3,6 +3,7 @@ package routes
 import (
 	"ecommerce-gin/internal/controllers"
 	"ecommerce-gin/internal/middleware"
+	"ecommerce-gin/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,7 +13,7 @@ func RegisterUploadRoutes(r *gin.Engine, group ...*gin.RouterGroup) {
 	r.Static("/uploads", "./uploads")
 
 	admin := group[0].Group("/upload")
-	admin.Use(middleware.AdminOnly())
+	admin.Use(middleware.RequirePermission(models.PermUploadsCreate))
 
 	// admin.POST("/product", controllers.UploadProductImage)
 	admin.POST("/product", controllers.UploadProductImageS3)
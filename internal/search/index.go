@@ -0,0 +1,388 @@
+// Package search is an in-memory product search index: BM25 ranking over name,
+// category and description, typo tolerant matching and facet counts.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// fields of a document, in posting order
+const (
+	fieldName = iota
+	fieldCategory
+	fieldDescription
+	numFields
+)
+
+// a match in the name counts more than one in the category path or the description
+var fieldWeights = [numFields]float64{3, 2, 1}
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// how much prefix and fuzzy matches are worth compared to an exact word
+const (
+	prefixWeight = 0.7
+	typoWeight   = 0.5 // per edit
+)
+
+// PriceBucketBounds are the upper bounds of the price facet buckets; the last bucket is open
+var PriceBucketBounds = []float64{25, 50, 100, 250, 500}
+
+// Document is what the index knows about a product
+type Document struct {
+	ID          uint
+	Name        string
+	Description string
+	Categories  []string // names from the root down to the product's category
+	CategoryIDs []uint   // ids of the same path
+	Price       float64
+	InStock     bool
+}
+
+type indexedDoc struct {
+	Document
+	lengths [numFields]int
+}
+
+type posting [numFields]int // term frequency per field
+
+// Index is safe for concurrent use
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[uint]*indexedDoc
+	postings map[string]map[uint]*posting
+	byLength map[int]map[string]bool // the terms of postings by length in runes
+	totals   [numFields]int          // summed field lengths, for the averages
+}
+
+func NewIndex() *Index {
+	return &Index{docs: map[uint]*indexedDoc{}, postings: map[string]map[uint]*posting{},
+		byLength: map[int]map[string]bool{}}
+}
+
+// Len returns the number of indexed documents
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+// Upsert adds a document or replaces the one with the same id
+func (ix *Index) Upsert(doc Document) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(doc.ID)
+	ix.add(doc)
+}
+
+// Remove drops a document; unknown ids are ignored
+func (ix *Index) Remove(id uint) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+// Replace swaps the whole content of the index
+func (ix *Index) Replace(docs []Document) {
+	fresh := NewIndex()
+	for _, d := range docs {
+		fresh.add(d)
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.docs, ix.postings, ix.byLength, ix.totals = fresh.docs, fresh.postings, fresh.byLength, fresh.totals
+}
+
+func (ix *Index) add(doc Document) {
+	d := &indexedDoc{Document: doc}
+	texts := [numFields]string{doc.Name, strings.Join(doc.Categories, " "), doc.Description}
+	for f, text := range texts {
+		terms := Tokenize(text)
+		d.lengths[f] = len(terms)
+		ix.totals[f] += len(terms)
+		for _, t := range terms {
+			docs := ix.postings[t]
+			if docs == nil {
+				docs = map[uint]*posting{}
+				ix.postings[t] = docs
+				n := utf8.RuneCountInString(t)
+				if ix.byLength[n] == nil {
+					ix.byLength[n] = map[string]bool{}
+				}
+				ix.byLength[n][t] = true
+			}
+			p := docs[doc.ID]
+			if p == nil {
+				p = &posting{}
+				docs[doc.ID] = p
+			}
+			p[f]++
+		}
+	}
+	ix.docs[doc.ID] = d
+}
+
+func (ix *Index) remove(id uint) {
+	d, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	texts := [numFields]string{d.Name, strings.Join(d.Categories, " "), d.Description}
+	for f, text := range texts {
+		ix.totals[f] -= d.lengths[f]
+		for _, t := range Tokenize(text) {
+			if docs := ix.postings[t]; docs != nil {
+				delete(docs, id)
+				if len(docs) == 0 {
+					delete(ix.postings, t)
+					n := utf8.RuneCountInString(t)
+					delete(ix.byLength[n], t)
+					if len(ix.byLength[n]) == 0 {
+						delete(ix.byLength, n)
+					}
+				}
+			}
+		}
+	}
+	delete(ix.docs, id)
+}
+
+// Query describes a search. Filters left nil do not restrict the result.
+type Query struct {
+	Text        string
+	CategoryIDs []uint // any of these categories, e.g. a category and its descendants
+	InStock     *bool
+	MinPrice    *float64
+	MaxPrice    *float64
+}
+
+// Hit is a matching document and its relevance
+type Hit struct {
+	ID    uint
+	Score float64
+}
+
+// CategoryCount is the number of hits in a category, subcategories included
+type CategoryCount struct {
+	ID    uint `json:"id"`
+	Count int  `json:"count"`
+}
+
+// PriceBucket counts hits with Min <= price < Max; Max is nil for the last bucket
+type PriceBucket struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max"`
+	Count int      `json:"count"`
+}
+
+// Facets are counted over the text matches. Each facet honours every filter
+// except its own, so the counts show what picking another value would give.
+type Facets struct {
+	Categories []CategoryCount `json:"categories"`
+	Price      []PriceBucket   `json:"price"`
+	InStock    int             `json:"in_stock"`
+	OutOfStock int             `json:"out_of_stock"`
+}
+
+// Result holds every hit, best first, and the facets
+type Result struct {
+	Hits   []Hit
+	Facets Facets
+}
+
+// Search ranks the documents matching every word of the query. Words may match
+// exactly, as a prefix or with a few typos, each worth a bit less. An empty
+// text matches everything, newest first.
+func (ix *Index) Search(q Query) Result {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	scores := ix.match(Tokenize(q.Text))
+
+	var categoryFilter map[uint]bool
+	if q.CategoryIDs != nil {
+		categoryFilter = make(map[uint]bool, len(q.CategoryIDs))
+		for _, id := range q.CategoryIDs {
+			categoryFilter[id] = true
+		}
+	}
+
+	res := Result{Facets: Facets{Price: newPriceBuckets()}}
+	categoryCounts := map[uint]int{}
+
+	for id, score := range scores {
+		d := ix.docs[id]
+		catOK := categoryFilter == nil || (len(d.CategoryIDs) > 0 && categoryFilter[d.CategoryIDs[len(d.CategoryIDs)-1]])
+		stockOK := q.InStock == nil || *q.InStock == d.InStock
+		priceOK := (q.MinPrice == nil || d.Price >= *q.MinPrice) && (q.MaxPrice == nil || d.Price <= *q.MaxPrice)
+
+		if stockOK && priceOK {
+			for _, c := range d.CategoryIDs {
+				categoryCounts[c]++
+			}
+		}
+		if catOK && stockOK {
+			res.Facets.Price[priceBucket(d.Price)].Count++
+		}
+		if catOK && priceOK {
+			if d.InStock {
+				res.Facets.InStock++
+			} else {
+				res.Facets.OutOfStock++
+			}
+		}
+		if catOK && stockOK && priceOK {
+			res.Hits = append(res.Hits, Hit{ID: id, Score: score})
+		}
+	}
+
+	sort.Slice(res.Hits, func(i, j int) bool {
+		if res.Hits[i].Score != res.Hits[j].Score {
+			return res.Hits[i].Score > res.Hits[j].Score
+		}
+		return res.Hits[i].ID > res.Hits[j].ID
+	})
+
+	for id, n := range categoryCounts {
+		res.Facets.Categories = append(res.Facets.Categories, CategoryCount{ID: id, Count: n})
+	}
+	sort.Slice(res.Facets.Categories, func(i, j int) bool {
+		a, b := res.Facets.Categories[i], res.Facets.Categories[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.ID < b.ID
+	})
+	return res
+}
+
+// match scores every document containing all query terms
+func (ix *Index) match(terms []string) map[uint]float64 {
+	scores := map[uint]float64{}
+	if len(terms) == 0 {
+		for id := range ix.docs {
+			scores[id] = 0
+		}
+		return scores
+	}
+
+	var avg [numFields]float64
+	if n := len(ix.docs); n > 0 {
+		for f := range avg {
+			avg[f] = math.Max(float64(ix.totals[f])/float64(n), 1)
+		}
+	}
+
+	for i, term := range terms {
+		// best scoring variant of this term per document
+		best := map[uint]float64{}
+		for variant, weight := range ix.expand(term) {
+			docs := ix.postings[variant]
+			idf := math.Log(1 + (float64(len(ix.docs))-float64(len(docs))+0.5)/(float64(len(docs))+0.5))
+			for id, p := range docs {
+				if i > 0 {
+					if _, ok := scores[id]; !ok {
+						continue
+					}
+				}
+				s := weight * idf * ix.fieldScore(ix.docs[id], p, avg)
+				if s > best[id] {
+					best[id] = s
+				}
+			}
+		}
+
+		if i == 0 {
+			scores = best
+			continue
+		}
+		for id := range scores {
+			if s, ok := best[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+	return scores
+}
+
+// fieldScore is the BM25 term frequency part, summed over the weighted fields
+func (ix *Index) fieldScore(d *indexedDoc, p *posting, avg [numFields]float64) float64 {
+	var s float64
+	for f := 0; f < numFields; f++ {
+		tf := float64(p[f])
+		if tf == 0 {
+			continue
+		}
+		norm := 1 - bm25B + bm25B*float64(d.lengths[f])/avg[f]
+		s += fieldWeights[f] * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+	}
+	return s
+}
+
+// expand finds the indexed terms a query word may stand for, with their weight
+func (ix *Index) expand(term string) map[string]float64 {
+	variants := map[string]float64{}
+	if _, ok := ix.postings[term]; ok {
+		variants[term] = 1
+	}
+
+	word := []rune(term)
+	typos := maxTypos(word)
+	// only terms longer than the word can start with it, and every typo changes
+	// the length by one at most, so most lengths need not be looked at
+	for n, terms := range ix.byLength {
+		prefixes := len(word) >= 2 && n > len(word)
+		near := typos > 0 && n >= len(word)-typos && n <= len(word)+typos
+		if !prefixes && !near {
+			continue
+		}
+		for candidate := range terms {
+			if candidate == term {
+				continue
+			}
+			weight := 0.0
+			if prefixes && strings.HasPrefix(candidate, term) {
+				weight = prefixWeight
+			}
+			if near {
+				if d := editDistance(word, []rune(candidate), typos); d <= typos {
+					weight = math.Max(weight, math.Pow(typoWeight, float64(d)))
+				}
+			}
+			if weight > 0 {
+				variants[candidate] = weight
+			}
+		}
+	}
+	return variants
+}
+
+func newPriceBuckets() []PriceBucket {
+	buckets := make([]PriceBucket, 0, len(PriceBucketBounds)+1)
+	lower := 0.0
+	for i := range PriceBucketBounds {
+		upper := PriceBucketBounds[i]
+		buckets = append(buckets, PriceBucket{Min: lower, Max: &upper})
+		lower = upper
+	}
+	return append(buckets, PriceBucket{Min: lower})
+}
+
+func priceBucket(price float64) int {
+	for i, upper := range PriceBucketBounds {
+		if price < upper {
+			return i
+		}
+	}
+	return len(PriceBucketBounds)
+}
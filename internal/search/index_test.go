@@ -0,0 +1,37 @@
+package search
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	ix := NewIndex()
+	ix.Upsert(Document{ID: 1, Name: "Wireless keyboard"})
+	ix.Upsert(Document{ID: 2, Name: "Keyboards and mice"})
+	ix.Upsert(Document{ID: 3, Name: "Key ring"})
+
+	got := ix.expand("keybaord")
+	want := map[string]float64{"keyboard": typoWeight, "keyboards": typoWeight * typoWeight}
+	if len(got) != len(want) {
+		t.Fatalf("expand(keybaord) = %v, want %v", got, want)
+	}
+	for term, w := range want {
+		if math.Abs(got[term]-w) > 1e-9 {
+			t.Errorf("expand(keybaord)[%s] = %v, want %v", term, got[term], w)
+		}
+	}
+
+	if got := ix.expand("key"); got["key"] != 1 || got["keyboard"] != prefixWeight || got["keyboards"] != prefixWeight || len(got) != 3 {
+		t.Errorf("expand(key) = %v, want key exactly and keyboard, keyboards as prefixes", got)
+	}
+
+	ix.Remove(2)
+	ix.Remove(3)
+	if _, ok := ix.expand("keybaord")["keyboards"]; ok {
+		t.Error("expand still finds a term of a removed document")
+	}
+	if terms := ix.byLength[len("keyboards")]; terms != nil {
+		t.Errorf("terms of length 9 = %v, want none left", terms)
+	}
+}
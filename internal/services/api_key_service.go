@@ -0,0 +1,62 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/utils"
+)
+
+// APIKeyPrefix starts every API key, which tells them apart from JWTs in a Bearer header
+const APIKeyPrefix = "eck_"
+
+// last_used_at is only written when it is older than this, not on every request
+const apiKeyTouchInterval = time.Minute
+
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// IsAPIKey reports whether a credential looks like one of our API keys
+func IsAPIKey(s string) bool {
+	return strings.HasPrefix(s, APIKeyPrefix)
+}
+
+// GenerateAPIKey returns a new raw key, its display prefix and its hash for storage
+func GenerateAPIKey() (raw, prefix, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	raw = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	hash, err = utils.HashToken(raw)
+	return raw, raw[:len(APIKeyPrefix)+8], hash, err
+}
+
+// AuthenticateAPIKey looks up a raw key and checks it is neither revoked nor expired
+func AuthenticateAPIKey(raw string) (*models.APIKey, error) {
+	hash, err := utils.HashToken(raw)
+	if err != nil {
+		return nil, err
+	}
+	key, err := database.GetAPIKeyByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if key == nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+		if err := database.TouchAPIKey(key.ID, now); err != nil {
+			log.Println("failed to update api key last use:", err)
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
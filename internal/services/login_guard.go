@@ -0,0 +1,129 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/config"
+)
+
+// Failed logins are counted per account (email) and per client IP. Both counters
+// slow the caller down exponentially once they pass the backoff threshold and an
+// account is locked for a while after too many failures. Keys are derived from the
+// submitted email, so unknown addresses are throttled exactly like real ones.
+
+const (
+	loginFailureWindow = time.Hour
+	maxLoginBackoff    = 5 * time.Minute
+)
+
+func accountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// LoginRetryAfter returns how long the caller must wait before the next attempt (0 = go ahead)
+func LoginRetryAfter(email, ip string) (time.Duration, error) {
+	acct := accountKey(email)
+	pipe := cache.Rdb.Pipeline()
+	lock := pipe.PTTL(cache.Ctx, "login:lock:"+acct)
+	acctWait := pipe.PTTL(cache.Ctx, "login:wait:acct:"+acct)
+	ipWait := pipe.PTTL(cache.Ctx, "login:wait:ip:"+ip)
+	if _, err := pipe.Exec(cache.Ctx); err != nil {
+		return 0, err
+	}
+
+	wait := time.Duration(0)
+	for _, d := range []time.Duration{lock.Val(), acctWait.Val(), ipWait.Val()} {
+		if d > wait {
+			wait = d
+		}
+	}
+	return wait, nil
+}
+
+// RecordLoginFailure counts a failed attempt and applies backoff. Returns true
+// when this failure locked the account.
+func RecordLoginFailure(email, ip string) (bool, error) {
+	cfg := config.Cfg
+	acct := accountKey(email)
+
+	acctFails, err := incrWithWindow("login:fail:acct:" + acct)
+	if err != nil {
+		return false, err
+	}
+	ipFails, err := incrWithWindow("login:fail:ip:" + ip)
+	if err != nil {
+		return false, err
+	}
+
+	if acctFails >= int64(cfg.LoginMaxFailures) {
+		lockFor := time.Duration(cfg.LoginLockMinutes) * time.Minute
+		if err := cache.Rdb.Set(cache.Ctx, "login:lock:"+acct, "1", lockFor).Err(); err != nil {
+			return false, err
+		}
+		// start counting from scratch once the lock runs out
+		cache.Rdb.Del(cache.Ctx, "login:fail:acct:"+acct, "login:wait:acct:"+acct)
+		return true, nil
+	}
+
+	if d := backoff(acctFails, cfg.LoginBackoffAfter); d > 0 {
+		cache.Rdb.Set(cache.Ctx, "login:wait:acct:"+acct, "1", d)
+	}
+	if d := backoff(ipFails, cfg.LoginIPBackoffAfter); d > 0 {
+		cache.Rdb.Set(cache.Ctx, "login:wait:ip:"+ip, "1", d)
+	}
+	return false, nil
+}
+
+// RecordLoginSuccess clears the account's failure history (the IP counter keeps running)
+func RecordLoginSuccess(email string) error {
+	acct := accountKey(email)
+	return cache.Rdb.Del(cache.Ctx, "login:fail:acct:"+acct, "login:wait:acct:"+acct).Err()
+}
+
+// AccountLockedFor returns the remaining lock time of an account (0 = not locked)
+func AccountLockedFor(email string) (time.Duration, error) {
+	d, err := cache.Rdb.PTTL(cache.Ctx, "login:lock:"+accountKey(email)).Result()
+	if err != nil || d < 0 {
+		return 0, err
+	}
+	return d, nil
+}
+
+// UnlockAccount lifts a lock and forgets the account's failed attempts
+func UnlockAccount(email string) error {
+	acct := accountKey(email)
+	return cache.Rdb.Del(cache.Ctx,
+		"login:lock:"+acct,
+		"login:fail:acct:"+acct,
+		"login:wait:acct:"+acct,
+	).Err()
+}
+
+func incrWithWindow(key string) (int64, error) {
+	n, err := cache.Rdb.Incr(cache.Ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 {
+		cache.Rdb.Expire(cache.Ctx, key, loginFailureWindow)
+	}
+	return n, nil
+}
+
+// backoff doubles the wait for every failure past the threshold: 1s, 2s, 4s, ... capped
+func backoff(failures int64, threshold int) time.Duration {
+	over := failures - int64(threshold)
+	if over <= 0 {
+		return 0
+	}
+	if over > 16 {
+		return maxLoginBackoff
+	}
+	d := time.Second << (over - 1)
+	if d > maxLoginBackoff {
+		return maxLoginBackoff
+	}
+	return d
+}
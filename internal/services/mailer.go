@@ -0,0 +1,100 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ecommerce-gin/internal/config"
+)
+
+// Mail is a plain-text email
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails; pick the driver with MAIL_DRIVER
+type Mailer interface {
+	Send(m Mail) error
+}
+
+var mailer Mailer
+
+func InitMailer() {
+	cfg := config.Cfg
+
+	switch cfg.MailDriver {
+	case "smtp":
+		mailer = &SMTPMailer{
+			Addr:     cfg.SMTPHost + ":" + cfg.SMTPPort,
+			Host:     cfg.SMTPHost,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		}
+	default:
+		mailer = &OutboxMailer{Dir: cfg.MailOutboxDir, From: cfg.MailFrom}
+	}
+	fmt.Println("Mailer ready:", cfg.MailDriver)
+}
+
+// SendMail delivers m through the configured driver
+func SendMail(m Mail) error {
+	return mailer.Send(m)
+}
+
+func formatMessage(from string, m Mail) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + m.To + "\r\n")
+	b.WriteString("Subject: " + m.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(m.Body)
+	return []byte(b.String())
+}
+
+// OutboxMailer writes every message as an .eml file into Dir instead of sending it,
+// so email flows can be exercised offline
+type OutboxMailer struct {
+	Dir  string
+	From string
+}
+
+func (o *OutboxMailer) Send(m Mail) error {
+	if err := os.MkdirAll(o.Dir, 0o755); err != nil {
+		return err
+	}
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(m.To)
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), recipient)
+	if err := os.WriteFile(filepath.Join(o.Dir, name), formatMessage(o.From, m), 0o644); err != nil {
+		return err
+	}
+	log.Println("Mail written to outbox:", name)
+	return nil
+}
+
+// SMTPMailer sends through an SMTP server, e.g. a local MailHog / Mailpit stand-in
+type SMTPMailer struct {
+	Addr     string
+	Host     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s *SMTPMailer) Send(m Mail) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	return smtp.SendMail(s.Addr, auth, s.From, []string{m.To}, formatMessage(s.From, m))
+}
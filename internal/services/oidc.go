@@ -0,0 +1,351 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/config"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// OIDCIdentity is what a verified ID token tells us about the user
+type OIDCIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+var (
+	ErrUnknownOIDCProvider = errors.New("unknown identity provider")
+	ErrInvalidOIDCState    = errors.New("invalid or expired login state")
+)
+
+// how long the user may take at the provider before the callback
+const oidcStateTTL = 10 * time.Minute
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcClient struct {
+	cfg        config.OIDCProvider
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]interface{}
+}
+
+// oidcState is kept in Redis between the redirect to the provider and the callback
+type oidcState struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+}
+
+var oidcClients = map[string]*oidcClient{}
+
+// MockOIDC is the built-in provider when OIDC_MOCK_ENABLED is set in development
+// or test, nil otherwise. It signs in any email it is given, so its identities
+// never get linked to existing accounts.
+var MockOIDC *MockOIDCProvider
+
+// mockOIDCEnvs are the only APP_ENV values the mock provider runs in
+var mockOIDCEnvs = map[string]bool{"development": true, "test": true}
+
+// IsMockOIDC reports whether identities of the provider come from the built-in mock
+func IsMockOIDC(provider string) bool {
+	return MockOIDC != nil && provider == "mock"
+}
+
+func InitOIDC() {
+	cfg := config.Cfg
+
+	for _, p := range cfg.OIDCProviders {
+		oidcClients[p.Name] = &oidcClient{cfg: p, httpClient: &http.Client{Timeout: 10 * time.Second}}
+	}
+
+	if cfg.OIDCMockEnabled && !mockOIDCEnvs[cfg.AppEnv] {
+		log.Printf("OIDC_MOCK_ENABLED ignored: the mock provider only runs with APP_ENV development or test, not %q", cfg.AppEnv)
+	}
+	if cfg.OIDCMockEnabled && mockOIDCEnvs[cfg.AppEnv] {
+		log.Println("WARNING: mock OIDC provider enabled under /oidc-mock. It signs in any email address without a password; never enable it on a reachable server.")
+		MockOIDC = NewMockOIDCProvider(cfg.AppBaseURL+"/oidc-mock", "mock-client")
+		oidcClients["mock"] = &oidcClient{
+			cfg: config.OIDCProvider{
+				Name:         "mock",
+				Issuer:       MockOIDC.Issuer,
+				ClientID:     MockOIDC.ClientID,
+				ClientSecret: "mock-secret",
+				Scopes:       []string{"openid", "email", "profile"},
+			},
+			// back-channel calls never leave the process
+			httpClient: &http.Client{Transport: MockOIDC.Transport()},
+		}
+	}
+
+	if len(oidcClients) > 0 {
+		fmt.Println("OIDC providers:", strings.Join(OIDCProviderNames(), ", "))
+	}
+}
+
+// OIDCProviderNames lists the configured providers
+func OIDCProviderNames() []string {
+	names := make([]string, 0, len(oidcClients))
+	for name := range oidcClients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func oidcRedirectURI(provider string) string {
+	return config.Cfg.AppBaseURL + "/auth/oidc/" + provider + "/callback"
+}
+
+// OIDCAuthorizationURL starts an authorization code flow with PKCE and returns
+// the provider URL to send the browser to
+func OIDCAuthorizationURL(provider, loginHint string) (string, error) {
+	client, ok := oidcClients[provider]
+	if !ok {
+		return "", ErrUnknownOIDCProvider
+	}
+	d, err := client.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	state := oidcState{Provider: provider, Verifier: randomURLToken(32), Nonce: randomURLToken(16)}
+	stateID := randomURLToken(24)
+	data, _ := json.Marshal(state)
+	if err := cache.Rdb.Set(cache.Ctx, "oidc:state:"+stateID, data, oidcStateTTL).Err(); err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(state.Verifier))
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", client.cfg.ClientID)
+	q.Set("redirect_uri", oidcRedirectURI(provider))
+	q.Set("scope", strings.Join(client.cfg.Scopes, " "))
+	q.Set("state", stateID)
+	q.Set("nonce", state.Nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	if loginHint != "" {
+		q.Set("login_hint", loginHint)
+	}
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// OIDCExchangeCode finishes the flow: checks the state, redeems the code with the
+// PKCE verifier and verifies the returned ID token
+func OIDCExchangeCode(provider, stateID, code string) (*OIDCIdentity, error) {
+	client, ok := oidcClients[provider]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+
+	// state is single use
+	raw, err := cache.Rdb.GetDel(cache.Ctx, "oidc:state:"+stateID).Result()
+	if err != nil {
+		return nil, ErrInvalidOIDCState
+	}
+	var state oidcState
+	if err := json.Unmarshal([]byte(raw), &state); err != nil || state.Provider != provider {
+		return nil, ErrInvalidOIDCState
+	}
+
+	d, err := client.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", oidcRedirectURI(provider))
+	form.Set("client_id", client.cfg.ClientID)
+	form.Set("client_secret", client.cfg.ClientSecret)
+	form.Set("code_verifier", state.Verifier)
+
+	resp, err := client.httpClient.PostForm(d.TokenEndpoint, form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint answered %d", resp.StatusCode)
+	}
+	var tokenResp struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, err
+	}
+	if tokenResp.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	return client.verifyIDToken(tokenResp.IDToken, d.Issuer, state.Nonce)
+}
+
+func (c *oidcClient) verifyIDToken(raw, issuer, nonce string) (*OIDCIdentity, error) {
+	token, err := jwt.Parse(raw, c.keyfunc, jwt.WithValidMethods([]string{"RS256", "ES256"}))
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("invalid id token: %v", err)
+	}
+	claims := token.Claims.(jwt.MapClaims)
+
+	if !claims.VerifyIssuer(issuer, true) {
+		return nil, errors.New("id token issuer mismatch")
+	}
+	if !claims.VerifyAudience(c.cfg.ClientID, true) {
+		return nil, errors.New("id token audience mismatch")
+	}
+	if claims["nonce"] != nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, errors.New("id token has no subject")
+	}
+	id := &OIDCIdentity{Provider: c.cfg.Name, Subject: sub}
+	id.Email, _ = claims["email"].(string)
+	id.Name, _ = claims["name"].(string)
+	// some providers send the flag as a string
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		id.EmailVerified = v
+	case string:
+		id.EmailVerified = v == "true"
+	}
+	return id, nil
+}
+
+func (c *oidcClient) getDiscovery() (*oidcDiscovery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.discovery != nil {
+		return c.discovery, nil
+	}
+
+	var d oidcDiscovery
+	if err := c.getJSON(c.cfg.Issuer+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, err
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("incomplete discovery document")
+	}
+	c.discovery = &d
+	return c.discovery, nil
+}
+
+// keyfunc finds the provider key for a token, refreshing the JWKS once on an unknown kid
+func (c *oidcClient) keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	c.mu.Lock()
+	key, ok := c.keys[kid]
+	c.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	d, err := c.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := c.getJSON(d.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]interface{}{}
+	for _, k := range set.Keys {
+		switch k.Kty {
+		case "RSA":
+			n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			if k.Crv != "P-256" {
+				continue
+			}
+			x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+			y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.mu.Unlock()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, jwt.ErrTokenUnverifiable
+}
+
+func (c *oidcClient) getJSON(u string, v interface{}) error {
+	resp, err := c.httpClient.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s answered %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func randomURLToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
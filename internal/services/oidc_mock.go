@@ -0,0 +1,203 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"ecommerce-gin/internal/utils"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// MockOIDCProvider is a minimal OpenID Connect provider for development and tests.
+// Its authorize endpoint signs in whoever is named in login_hint without asking,
+// so the whole social login flow can run without network access.
+type MockOIDCProvider struct {
+	Issuer   string
+	ClientID string
+
+	key      *rsa.PrivateKey
+	basePath string
+
+	mu    sync.Mutex
+	codes map[string]mockAuthCode
+}
+
+type mockAuthCode struct {
+	email       string
+	nonce       string
+	challenge   string
+	redirectURI string
+	expiresAt   time.Time
+}
+
+const mockOIDCKid = "mock"
+
+func NewMockOIDCProvider(issuer, clientID string) *MockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	basePath := ""
+	if u, err := url.Parse(issuer); err == nil {
+		basePath = strings.TrimSuffix(u.Path, "/")
+	}
+	return &MockOIDCProvider{
+		Issuer:   issuer,
+		ClientID: clientID,
+		key:      key,
+		basePath: basePath,
+		codes:    map[string]mockAuthCode{},
+	}
+}
+
+// ServeHTTP accepts paths with or without the issuer path prefix
+func (m *MockOIDCProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimPrefix(r.URL.Path, m.basePath) {
+	case "/.well-known/openid-configuration":
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                                m.Issuer,
+			"authorization_endpoint":                m.Issuer + "/authorize",
+			"token_endpoint":                        m.Issuer + "/token",
+			"jwks_uri":                              m.Issuer + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+			"code_challenge_methods_supported":      []string{"S256"},
+		})
+	case "/authorize":
+		m.authorize(w, r)
+	case "/token":
+		m.token(w, r)
+	case "/jwks":
+		pub := m.key.PublicKey
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{"keys": []utils.JWK{{
+			Kty: "RSA",
+			Kid: mockOIDCKid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Transport serves requests in-process, for the back-channel calls of the client
+func (m *MockOIDCProvider) Transport() http.RoundTripper {
+	return mockTransport{m}
+}
+
+type mockTransport struct{ h http.Handler }
+
+func (t mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.h.ServeHTTP(rec, req)
+	return rec.Result(), nil
+}
+
+func (m *MockOIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI := q.Get("redirect_uri")
+	if q.Get("client_id") != m.ClientID || redirectURI == "" {
+		http.Error(w, "unknown client or missing redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if q.Get("response_type") != "code" || q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "only the code flow with S256 PKCE is supported", http.StatusBadRequest)
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(q.Get("login_hint")))
+	if email == "" {
+		email = "mock.user@example.com"
+	}
+
+	code := randomURLToken(24)
+	m.mu.Lock()
+	m.codes[code] = mockAuthCode{
+		email:       email,
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		redirectURI: redirectURI,
+		expiresAt:   time.Now().Add(time.Minute),
+	}
+	m.mu.Unlock()
+
+	back := url.Values{}
+	back.Set("code", code)
+	back.Set("state", q.Get("state"))
+	http.Redirect(w, r, redirectURI+"?"+back.Encode(), http.StatusFound)
+}
+
+func (m *MockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	m.mu.Lock()
+	ac, ok := m.codes[code]
+	delete(m.codes, code)
+	m.mu.Unlock()
+
+	if !ok || time.Now().After(ac.expiresAt) ||
+		r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("client_id") != m.ClientID ||
+		r.PostForm.Get("redirect_uri") != ac.redirectURI {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != ac.challenge {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	subject := sha256.Sum256([]byte(ac.email))
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            m.Issuer,
+		"sub":            "mock-" + hex.EncodeToString(subject[:8]),
+		"aud":            m.ClientID,
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          ac.nonce,
+		"email":          ac.email,
+		"email_verified": true,
+		"name":           strings.SplitN(ac.email, "@", 2)[0],
+	})
+	idToken.Header["kid"] = mockOIDCKid
+	signed, err := idToken.SignedString(m.key)
+	if err != nil {
+		writeMockJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomURLToken(24),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/config"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/webauthn"
+)
+
+var (
+	ErrPasskeyChallenge = errors.New("invalid or expired passkey challenge")
+	ErrInvalidPasskey   = errors.New("invalid passkey")
+	ErrPasskeyExists    = errors.New("passkey already registered")
+)
+
+// how long the browser prompt may stay open
+const passkeyChallengeTTL = 5 * time.Minute
+
+// PasskeyRelyingParty describes this site to authenticators. RP ID and origin
+// fall back to APP_BASE_URL.
+func PasskeyRelyingParty() webauthn.RelyingParty {
+	cfg := config.Cfg
+	rp := webauthn.RelyingParty{ID: cfg.WebAuthnRPID, Name: cfg.WebAuthnRPName, Origin: cfg.WebAuthnOrigin}
+	if u, err := url.Parse(cfg.AppBaseURL); err == nil {
+		if rp.ID == "" {
+			rp.ID = u.Hostname()
+		}
+		if rp.Origin == "" {
+			rp.Origin = u.Scheme + "://" + u.Host
+		}
+	}
+	rp.Origin = strings.TrimSuffix(rp.Origin, "/")
+	return rp
+}
+
+// passkeyUserHandle is the opaque user id stored on the authenticator
+func passkeyUserHandle(userID uint) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(userID))
+}
+
+// BeginPasskeyRegistration creates the options for navigator.credentials.create
+func BeginPasskeyRegistration(user *models.User) (webauthn.CreationOptions, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return webauthn.CreationOptions{}, err
+	}
+
+	existing, err := database.ListPasskeys(user.ID)
+	if err != nil {
+		return webauthn.CreationOptions{}, err
+	}
+	exclude := make([][]byte, 0, len(existing))
+	for _, p := range existing {
+		if id, err := webauthn.DecodeBase64(p.CredentialID); err == nil {
+			exclude = append(exclude, id)
+		}
+	}
+
+	key := "passkey:register:" + strconv.FormatUint(uint64(user.ID), 10)
+	if err := cache.Rdb.Set(cache.Ctx, key, challenge, passkeyChallengeTTL).Err(); err != nil {
+		return webauthn.CreationOptions{}, err
+	}
+
+	return PasskeyRelyingParty().CreationOptions(challenge, passkeyUserHandle(user.ID), user.Email, user.Name, exclude), nil
+}
+
+// FinishPasskeyRegistration verifies the authenticator response and stores the passkey
+func FinishPasskeyRegistration(user *models.User, name string, clientDataJSON, attestationObject []byte) (*models.Passkey, error) {
+	// challenge is single use
+	key := "passkey:register:" + strconv.FormatUint(uint64(user.ID), 10)
+	challenge, err := cache.Rdb.GetDel(cache.Ctx, key).Bytes()
+	if err != nil {
+		return nil, ErrPasskeyChallenge
+	}
+
+	cred, err := PasskeyRelyingParty().VerifyRegistration(challenge, clientDataJSON, attestationObject)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialID := webauthn.EncodeBase64(cred.ID)
+	if existing, err := database.GetPasskeyByCredentialID(credentialID); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, ErrPasskeyExists
+	}
+
+	if name == "" {
+		name = "Passkey"
+	}
+	passkey := &models.Passkey{
+		UserID:       user.ID,
+		Name:         name,
+		CredentialID: credentialID,
+		PublicKey:    cred.PublicKey,
+		SignCount:    cred.SignCount,
+		AAGUID:       hex.EncodeToString(cred.AAGUID),
+	}
+	if err := database.CreatePasskey(passkey); err != nil {
+		return nil, err
+	}
+	return passkey, nil
+}
+
+// BeginPasskeyLogin creates the options for navigator.credentials.get. With a
+// user the browser is limited to their passkeys; userID 0 lets the user pick
+// any discoverable passkey.
+func BeginPasskeyLogin(userID uint) (webauthn.RequestOptions, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return webauthn.RequestOptions{}, err
+	}
+
+	var allow [][]byte
+	if userID != 0 {
+		passkeys, err := database.ListPasskeys(userID)
+		if err != nil {
+			return webauthn.RequestOptions{}, err
+		}
+		for _, p := range passkeys {
+			if id, err := webauthn.DecodeBase64(p.CredentialID); err == nil {
+				allow = append(allow, id)
+			}
+		}
+	}
+
+	// keyed by the challenge itself, the response carries it back in clientDataJSON
+	key := "passkey:login:" + webauthn.EncodeBase64(challenge)
+	if err := cache.Rdb.Set(cache.Ctx, key, userID, passkeyChallengeTTL).Err(); err != nil {
+		return webauthn.RequestOptions{}, err
+	}
+
+	return PasskeyRelyingParty().RequestOptions(challenge, allow), nil
+}
+
+// FinishPasskeyLogin verifies an assertion and returns the passkey it was made with
+// and whether the authenticator verified the user (PIN, biometrics)
+func FinishPasskeyLogin(credentialID string, clientDataJSON, authenticatorData, signature, userHandle []byte) (*models.Passkey, bool, error) {
+	cd, err := webauthn.ParseClientData(clientDataJSON)
+	if err != nil {
+		return nil, false, err
+	}
+	challenge, err := webauthn.DecodeBase64(cd.Challenge)
+	if err != nil {
+		return nil, false, ErrPasskeyChallenge
+	}
+	userID, err := cache.Rdb.GetDel(cache.Ctx, "passkey:login:"+webauthn.EncodeBase64(challenge)).Uint64()
+	if err != nil {
+		return nil, false, ErrPasskeyChallenge
+	}
+
+	passkey, err := database.GetPasskeyByCredentialID(credentialID)
+	if err != nil {
+		return nil, false, err
+	}
+	if passkey == nil || (userID != 0 && uint(userID) != passkey.UserID) {
+		return nil, false, ErrInvalidPasskey
+	}
+	if len(userHandle) > 0 && !bytes.Equal(userHandle, passkeyUserHandle(passkey.UserID)) {
+		return nil, false, ErrInvalidPasskey
+	}
+
+	assertion, err := PasskeyRelyingParty().VerifyAssertion(challenge, passkey.PublicKey, passkey.SignCount,
+		clientDataJSON, authenticatorData, signature)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ok, err := database.UpdatePasskeyUsage(passkey.ID, passkey.SignCount, assertion.SignCount)
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok {
+		return nil, false, ErrInvalidPasskey
+	}
+	return passkey, assertion.UserVerified, nil
+}
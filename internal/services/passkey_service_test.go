@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/config"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/testutil"
+	"ecommerce-gin/internal/webauthn"
+
+	"gorm.io/gorm"
+)
+
+func setupPasskeys(t *testing.T) *testutil.Store {
+	t.Helper()
+	store := testutil.UseDB(t, &database.DB)
+	testutil.UseRedis(t, &cache.Rdb)
+	prev := config.Cfg
+	t.Cleanup(func() { config.Cfg = prev })
+	config.Cfg = config.Config{AppBaseURL: "https://shop.example.com", WebAuthnRPName: "Shop"}
+	return store
+}
+
+func challengeOf(t *testing.T, encoded string) []byte {
+	t.Helper()
+	challenge, err := webauthn.DecodeBase64(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return challenge
+}
+
+// registerPasskey runs a registration ceremony for user and returns the credential id
+func registerPasskey(t *testing.T, auth *webauthn.SoftwareAuthenticator, user *models.User) []byte {
+	t.Helper()
+	options, err := BeginPasskeyRegistration(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, clientData, attestation, err := auth.Register(challengeOf(t, options.Challenge), passkeyUserHandle(user.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FinishPasskeyRegistration(user, "Laptop", clientData, attestation); err != nil {
+		t.Fatalf("FinishPasskeyRegistration: %v", err)
+	}
+	return id
+}
+
+func testUser(id uint) *models.User {
+	return &models.User{Model: gorm.Model{ID: id}, Email: "jane@example.com", Name: "Jane"}
+}
+
+func TestFinishPasskeyRegistrationChallengeIsSingleUse(t *testing.T) {
+	store := setupPasskeys(t)
+	auth := webauthn.NewSoftwareAuthenticator(PasskeyRelyingParty())
+	user := testUser(7)
+
+	options, err := BeginPasskeyRegistration(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, clientData, attestation, err := auth.Register(challengeOf(t, options.Challenge), passkeyUserHandle(user.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	passkey, err := FinishPasskeyRegistration(user, "", clientData, attestation)
+	if err != nil {
+		t.Fatalf("FinishPasskeyRegistration: %v", err)
+	}
+	if passkey.UserID != user.ID || passkey.Name != "Passkey" || passkey.SignCount != 0 {
+		t.Errorf("passkey = %+v", passkey)
+	}
+
+	_, err = FinishPasskeyRegistration(user, "", clientData, attestation)
+	if !errors.Is(err, ErrPasskeyChallenge) {
+		t.Errorf("second finish: err = %v, want %v", err, ErrPasskeyChallenge)
+	}
+	if rows := store.Rows("passkeys"); len(rows) != 1 {
+		t.Errorf("stored %d passkeys, want 1", len(rows))
+	}
+}
+
+func TestFinishPasskeyLogin(t *testing.T) {
+	store := setupPasskeys(t)
+	auth := webauthn.NewSoftwareAuthenticator(PasskeyRelyingParty())
+	user := testUser(7)
+	id := registerPasskey(t, auth, user)
+
+	options, err := BeginPasskeyLogin(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientData, authData, sig, userHandle, err := auth.Assert(id, challengeOf(t, options.Challenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	passkey, verified, err := FinishPasskeyLogin(webauthn.EncodeBase64(id), clientData, authData, sig, userHandle)
+	if err != nil {
+		t.Fatalf("FinishPasskeyLogin: %v", err)
+	}
+	if passkey.UserID != user.ID || !verified {
+		t.Errorf("passkey user = %d, verified = %v; want %d, true", passkey.UserID, verified, user.ID)
+	}
+	if rows := store.Rows("passkeys"); len(rows) != 1 || rows[0]["sign_count"] != int64(1) {
+		t.Errorf("stored passkeys = %v, want sign count 1", rows)
+	}
+
+	// the challenge was consumed by the first login
+	_, _, err = FinishPasskeyLogin(webauthn.EncodeBase64(id), clientData, authData, sig, userHandle)
+	if !errors.Is(err, ErrPasskeyChallenge) {
+		t.Errorf("replay: err = %v, want %v", err, ErrPasskeyChallenge)
+	}
+}
+
+func TestFinishPasskeyLoginRejectsOtherUser(t *testing.T) {
+	setupPasskeys(t)
+	auth := webauthn.NewSoftwareAuthenticator(PasskeyRelyingParty())
+	id := registerPasskey(t, auth, testUser(7))
+	credentialID := webauthn.EncodeBase64(id)
+
+	t.Run("mismatched user handle", func(t *testing.T) {
+		options, err := BeginPasskeyLogin(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clientData, authData, sig, userHandle, err := auth.Assert(id, challengeOf(t, options.Challenge))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, err = FinishPasskeyLogin(credentialID, clientData, authData, sig, passkeyUserHandle(8))
+		if !errors.Is(err, ErrInvalidPasskey) {
+			t.Errorf("err = %v, want %v", err, ErrInvalidPasskey)
+		}
+		// a rejected attempt still uses up the challenge
+		_, _, err = FinishPasskeyLogin(credentialID, clientData, authData, sig, userHandle)
+		if !errors.Is(err, ErrPasskeyChallenge) {
+			t.Errorf("retry: err = %v, want %v", err, ErrPasskeyChallenge)
+		}
+	})
+
+	t.Run("login started for another user", func(t *testing.T) {
+		options, err := BeginPasskeyLogin(8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clientData, authData, sig, userHandle, err := auth.Assert(id, challengeOf(t, options.Challenge))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, err = FinishPasskeyLogin(credentialID, clientData, authData, sig, userHandle)
+		if !errors.Is(err, ErrInvalidPasskey) {
+			t.Errorf("err = %v, want %v", err, ErrInvalidPasskey)
+		}
+	})
+}
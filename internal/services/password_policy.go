@@ -0,0 +1,70 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"ecommerce-gin/internal/config"
+)
+
+// longer passwords only cost hashing time, nobody types more than this
+const passwordMaxLength = 128
+
+// breached passwords, lowercased; empty when no list is configured
+var breachedPasswords = map[string]struct{}{}
+
+var ErrBreachedPassword = errors.New("this password has appeared in a data breach, please choose another one")
+
+// InitPasswordPolicy loads the breached-password list from PASSWORD_BREACHED_FILE
+func InitPasswordPolicy() {
+	path := config.Cfg.PasswordBreachedFile
+	if path == "" {
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal("Failed to open breached password list: ", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if pw := strings.TrimSpace(scanner.Text()); pw != "" && !strings.HasPrefix(pw, "#") {
+			breachedPasswords[strings.ToLower(pw)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal("Failed to read breached password list: ", err)
+	}
+	fmt.Println("Breached password list loaded:", len(breachedPasswords), "entries")
+}
+
+// ValidatePassword checks a new password against the policy. email is the
+// account's address when known; the password may not be (a part of) it.
+func ValidatePassword(pw, email string) error {
+	n := utf8.RuneCountInString(pw)
+	if n < config.Cfg.PasswordMinLength {
+		return fmt.Errorf("password must be at least %d characters", config.Cfg.PasswordMinLength)
+	}
+	if n > passwordMaxLength {
+		return fmt.Errorf("password must be at most %d characters", passwordMaxLength)
+	}
+
+	lower := strings.ToLower(pw)
+	if email != "" {
+		email = strings.ToLower(email)
+		if lower == email || lower == strings.SplitN(email, "@", 2)[0] {
+			return errors.New("password must not be your email address")
+		}
+	}
+	if _, ok := breachedPasswords[lower]; ok {
+		return ErrBreachedPassword
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"ecommerce-gin/internal/database"
+)
+
+// role permissions change rarely but are needed on every admin request,
+// so they are kept in memory for a short while
+const rolePermissionTTL = time.Minute
+
+type cachedPermissions struct {
+	perms   map[string]bool
+	expires time.Time
+}
+
+var (
+	rolePermMu    sync.RWMutex
+	rolePermCache = map[string]cachedPermissions{}
+)
+
+// PermissionsForRole returns the set of permission names granted to a role
+func PermissionsForRole(role string) (map[string]bool, error) {
+	rolePermMu.RLock()
+	cached, ok := rolePermCache[role]
+	rolePermMu.RUnlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.perms, nil
+	}
+
+	perms := map[string]bool{}
+	r, err := database.GetRoleByName(role)
+	if err != nil {
+		return nil, err
+	}
+	if r != nil {
+		for _, p := range r.Permissions {
+			perms[p.Name] = true
+		}
+	}
+
+	rolePermMu.Lock()
+	rolePermCache[role] = cachedPermissions{perms: perms, expires: time.Now().Add(rolePermissionTTL)}
+	rolePermMu.Unlock()
+	return perms, nil
+}
+
+// InvalidateRolePermissions drops cached permissions after a role was edited
+func InvalidateRolePermissions() {
+	rolePermMu.Lock()
+	rolePermCache = map[string]cachedPermissions{}
+	rolePermMu.Unlock()
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+)
+
+// exportRecord is one line of an export, with the import columns
+type exportRecord struct {
+	Slug        string   `json:"slug"`
+	SKU         string   `json:"sku,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Price       *float64 `json:"price,omitempty"`
+	Quantity    *int     `json:"quantity,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	ImageURL    string   `json:"image_url,omitempty"`
+	IsActive    bool     `json:"is_active"`
+	Options     string   `json:"options,omitempty"`
+}
+
+func (r exportRecord) csv() []string {
+	price, quantity := "", ""
+	if r.Price != nil {
+		price = strconv.FormatFloat(*r.Price, 'f', -1, 64)
+	}
+	if r.Quantity != nil {
+		quantity = strconv.Itoa(*r.Quantity)
+	}
+	return []string{r.Slug, r.SKU, r.Name, r.Description, price, quantity, r.Category, r.ImageURL,
+		strconv.FormatBool(r.IsActive), r.Options}
+}
+
+// ExportProducts writes the whole catalog, inactive products included, as CSV or
+// NDJSON in the import format: a line per product followed by a line per variant,
+// so the file can be edited and imported again. flush is called after each product.
+func ExportProducts(w io.Writer, format string, flush func()) error {
+	var write func(exportRecord) error
+	var flushWriter func() error
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(ImportColumns); err != nil {
+			return err
+		}
+		write = func(r exportRecord) error { return cw.Write(r.csv()) }
+		flushWriter = func() error { cw.Flush(); return cw.Error() }
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		write = func(r exportRecord) error { return enc.Encode(r) }
+		flushWriter = func() error { return nil }
+	default:
+		return fmt.Errorf("%w: unknown format %q", ErrImportFile, format)
+	}
+
+	err := database.EachProductForExport(func(p models.Product) error {
+		for _, r := range exportProductRecords(&p) {
+			if err := write(r); err != nil {
+				return err
+			}
+		}
+		if err := flushWriter(); err != nil {
+			return err
+		}
+		flush()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flushWriter()
+}
+
+func exportProductRecords(p *models.Product) []exportRecord {
+	price := p.Price
+	product := exportRecord{
+		Slug:        p.Slug,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       &price,
+		ImageURL:    p.ImageURL,
+		IsActive:    p.IsActive,
+	}
+	// stock of products with variants is only set per variant
+	if len(p.Variants) == 0 {
+		quantity := p.Quantity
+		product.Quantity = &quantity
+	}
+	if p.Category != nil {
+		product.Category = p.Category.Slug
+	}
+	optionTypes := make([]string, 0, len(p.Options))
+	for _, o := range p.Options {
+		optionTypes = append(optionTypes, o.Name+"="+strings.Join(o.Values, ","))
+	}
+	product.Options = strings.Join(optionTypes, "; ")
+
+	records := []exportRecord{product}
+	for _, v := range p.Variants {
+		quantity := v.Quantity
+		values := make([]string, 0, len(p.Options))
+		for _, o := range p.Options {
+			if value, ok := v.Options[o.Name]; ok {
+				values = append(values, o.Name+"="+value)
+			}
+		}
+		records = append(records, exportRecord{
+			Slug:     p.Slug,
+			SKU:      v.SKU,
+			Price:    v.Price,
+			Quantity: &quantity,
+			ImageURL: v.ImageURL,
+			IsActive: v.IsActive,
+			Options:  strings.Join(values, "; "),
+		})
+	}
+	return records
+}
@@ -0,0 +1,461 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ImportColumns are the columns of import and export files, in export order.
+// Empty values leave a field as it is.
+var ImportColumns = []string{"slug", "sku", "name", "description", "price", "quantity", "category", "image_url", "is_active", "options"}
+
+// ErrImportFile is returned for files that cannot be read at all
+var ErrImportFile = errors.New("invalid import file")
+
+// how long finished import jobs can be polled
+const importJobTTL = 24 * time.Hour
+
+// ParseProductImport reads a CSV file with a header line, or NDJSON with one object
+// per line. Lines with bad values come back as row errors; an error is returned
+// only when the file as a whole cannot be read.
+func ParseProductImport(r io.Reader, format string) ([]database.ProductImportRow, []database.ImportRowError, error) {
+	switch format {
+	case "csv":
+		return parseImportCSV(r)
+	case "ndjson":
+		return parseImportNDJSON(r)
+	}
+	return nil, nil, fmt.Errorf("%w: unknown format %q", ErrImportFile, format)
+}
+
+func parseImportCSV(r io.Reader) ([]database.ProductImportRow, []database.ImportRowError, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: cannot read header: %v", ErrImportFile, err)
+	}
+	columns := make([]string, len(header))
+	seen := map[string]bool{}
+	for i, h := range header {
+		// spreadsheet programs like to start files with a byte order mark
+		name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		if !isImportColumn(name) {
+			return nil, nil, fmt.Errorf("%w: unknown column %q, use %s", ErrImportFile, h, strings.Join(ImportColumns, ", "))
+		}
+		if seen[name] {
+			return nil, nil, fmt.Errorf("%w: duplicate column %q", ErrImportFile, name)
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+	if !seen["slug"] && !seen["sku"] && !seen["name"] {
+		return nil, nil, fmt.Errorf("%w: needs a slug, sku or name column", ErrImportFile)
+	}
+
+	var rows []database.ProductImportRow
+	var rowErrors []database.ImportRowError
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			// a broken quote; the reader cannot tell where the records after it start
+			return nil, nil, fmt.Errorf("%w: %v", ErrImportFile, err)
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			rowErrors = append(rowErrors, database.ImportRowError{Line: line, Message: fmt.Sprintf("expected %d values, got %d", len(columns), len(record))})
+			continue
+		}
+
+		fields := make(map[string]string, len(columns))
+		for i, v := range record {
+			fields[columns[i]] = v
+		}
+		row, errs, empty := buildImportRow(line, fields)
+		if empty {
+			continue
+		}
+		if len(errs) > 0 {
+			rowErrors = append(rowErrors, errs...)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, nil
+}
+
+func parseImportNDJSON(r io.Reader) ([]database.ProductImportRow, []database.ImportRowError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	var rows []database.ProductImportRow
+	var rowErrors []database.ImportRowError
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var object map[string]any
+		dec := json.NewDecoder(bytes.NewReader(text))
+		dec.UseNumber()
+		if err := dec.Decode(&object); err != nil {
+			rowErrors = append(rowErrors, database.ImportRowError{Line: line, Message: "not a JSON object"})
+			continue
+		}
+
+		fields := make(map[string]string, len(object))
+		var errs []database.ImportRowError
+		for key, value := range object {
+			if !isImportColumn(key) {
+				errs = append(errs, database.ImportRowError{Line: line, Field: key, Message: "unknown field"})
+				continue
+			}
+			switch v := value.(type) {
+			case nil:
+			case string:
+				fields[key] = v
+			case json.Number:
+				fields[key] = v.String()
+			case bool:
+				fields[key] = strconv.FormatBool(v)
+			default:
+				errs = append(errs, database.ImportRowError{Line: line, Field: key, Message: "must be a string, number or boolean"})
+			}
+		}
+		row, fieldErrs, empty := buildImportRow(line, fields)
+		errs = append(errs, fieldErrs...)
+		if len(errs) > 0 {
+			rowErrors = append(rowErrors, errs...)
+			continue
+		}
+		if !empty {
+			rows = append(rows, row)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrImportFile, err)
+	}
+	return rows, rowErrors, nil
+}
+
+func isImportColumn(name string) bool {
+	for _, c := range ImportColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// buildImportRow checks and converts the values of one line; empty reports a line
+// without any value
+func buildImportRow(line int, fields map[string]string) (database.ProductImportRow, []database.ImportRowError, bool) {
+	row := database.ProductImportRow{Line: line}
+	var errs []database.ImportRowError
+	fail := func(field, message string) {
+		errs = append(errs, database.ImportRowError{Line: line, Field: field, Message: message})
+	}
+
+	values := map[string]string{}
+	for k, v := range fields {
+		if v = strings.TrimSpace(v); v != "" {
+			values[k] = v
+		}
+	}
+	if len(values) == 0 {
+		return row, nil, true
+	}
+
+	row.Slug = values["slug"]
+	row.SKU = values["sku"]
+	if len(row.SKU) > 64 {
+		fail("sku", "must be at most 64 characters")
+	}
+	if row.Slug == "" && row.SKU == "" && values["name"] == "" {
+		fail("", "needs a slug, sku or name")
+	}
+
+	if row.SKU != "" {
+		// a variant row only describes the variant
+		for _, key := range []string{"name", "description", "category"} {
+			if _, ok := values[key]; ok {
+				fail(key, "only product rows take "+key)
+			}
+		}
+	}
+	if v, ok := values["name"]; ok {
+		if len(v) > 255 {
+			fail("name", "must be at most 255 characters")
+		}
+		row.Name = &v
+	}
+	if v, ok := values["description"]; ok {
+		row.Description = &v
+	}
+	if v, ok := values["category"]; ok {
+		row.Category = &v
+	}
+	if v, ok := values["price"]; ok {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil || price < 0 {
+			fail("price", "must be a number of at least 0")
+		} else {
+			row.Price = &price
+		}
+	}
+	if v, ok := values["quantity"]; ok {
+		quantity, err := strconv.Atoi(v)
+		if err != nil || quantity < 0 {
+			fail("quantity", "must be a whole number of at least 0")
+		} else {
+			row.Quantity = &quantity
+		}
+	}
+	if v, ok := values["is_active"]; ok {
+		active, err := parseImportBool(v)
+		if err != nil {
+			fail("is_active", "must be true or false")
+		} else {
+			row.IsActive = &active
+		}
+	}
+	if v, ok := values["image_url"]; ok {
+		switch {
+		case row.SKU == "" && !IsUploadedImageURL(v):
+			// it goes into the product's gallery, like an image attached by hand
+			fail("image_url", "must point to an uploaded image")
+		case !strings.HasPrefix(v, "https://") && !strings.HasPrefix(v, "http://") && !strings.HasPrefix(v, "/uploads/"):
+			fail("image_url", "must be an http(s) URL or an /uploads/ path")
+		default:
+			row.ImageURL = &v
+		}
+	}
+	if v, ok := values["options"]; ok {
+		var err error
+		if row.SKU != "" {
+			row.VariantOptions, err = parseVariantOptions(v)
+		} else {
+			row.ProductOptions, err = parseProductOptions(v)
+		}
+		if err != nil {
+			fail("options", err.Error())
+		}
+	}
+	return row, errs, false
+}
+
+func parseImportBool(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "yes", "y":
+		return true, nil
+	case "no", "n":
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// parseProductOptions reads option types as "Size=S,M,L; Color=Red,Blue"
+func parseProductOptions(s string) ([]models.ProductOption, error) {
+	var options []models.ProductOption
+	names := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		name, list, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("write options as Size=S,M,L; Color=Red,Blue")
+		}
+		if names[strings.ToLower(name)] {
+			return nil, fmt.Errorf("duplicate option %s", name)
+		}
+		names[strings.ToLower(name)] = true
+
+		var values []string
+		seen := map[string]bool{}
+		for _, v := range strings.Split(list, ",") {
+			if v = strings.TrimSpace(v); v == "" {
+				continue
+			}
+			if seen[strings.ToLower(v)] {
+				return nil, fmt.Errorf("duplicate value %s for option %s", v, name)
+			}
+			seen[strings.ToLower(v)] = true
+			values = append(values, v)
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("option %s has no values", name)
+		}
+		options = append(options, models.ProductOption{Name: name, Values: values})
+	}
+	return options, nil
+}
+
+// parseVariantOptions reads option values as "Size=M; Color=Red"
+func parseVariantOptions(s string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, part := range strings.Split(s, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("write options as Size=M; Color=Red")
+		}
+		if _, dup := values[name]; dup {
+			return nil, fmt.Errorf("duplicate option %s", name)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// RunProductImport applies parsed rows and, unless it was a dry run, refreshes
+// the search index and the product cache
+func RunProductImport(rows []database.ProductImportRow, parseErrors []database.ImportRowError, dryRun bool, progress func(done int)) (*database.ImportSummary, error) {
+	summary, err := database.ImportProducts(rows, parseErrors, dryRun, progress)
+	if err != nil {
+		return nil, err
+	}
+	if summary.Applied {
+		for _, slug := range summary.Slugs {
+			cache.Delete("product:" + slug)
+		}
+		ReindexProducts(summary.ProductIDs...)
+	}
+	return summary, nil
+}
+
+// ImportJob is an import running in the background. Its state lives in Redis so
+// any instance can answer the polling.
+type ImportJob struct {
+	ID         string                  `json:"id"`
+	Status     string                  `json:"status"` // queued, running, done, failed
+	DryRun     bool                    `json:"dry_run"`
+	Total      int                     `json:"total"`
+	Processed  int                     `json:"processed"`
+	Result     *database.ImportSummary `json:"result,omitempty"`
+	Error      string                  `json:"error,omitempty"`
+	UserID     uint                    `json:"user_id"`
+	CreatedAt  time.Time               `json:"created_at"`
+	FinishedAt *time.Time              `json:"finished_at,omitempty"`
+}
+
+// StartImportJob queues the import and runs it in the background. onDone, if
+// not nil, gets the job once the import completed; it is not called when it failed.
+func StartImportJob(rows []database.ProductImportRow, parseErrors []database.ImportRowError, dryRun bool, userID uint, onDone func(*ImportJob)) (*ImportJob, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	job := &ImportJob{
+		ID:        hex.EncodeToString(b),
+		Status:    "queued",
+		DryRun:    dryRun,
+		Total:     len(rows),
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}
+	if err := saveImportJob(job); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		// a copy, so the handler's response is not raced
+		job := *job
+		// a panic would take the server down and leave the job running forever
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("product import %s panicked: %v\n%s", job.ID, r, debug.Stack())
+				if job.Status == "done" {
+					return // in onDone, the import itself went through
+				}
+				now := time.Now()
+				job.FinishedAt = &now
+				job.Status = "failed"
+				job.Error = "import failed"
+				if err := saveImportJob(&job); err != nil {
+					log.Println("failed to save import job:", err)
+				}
+			}
+		}()
+
+		job.Status = "running"
+		saveImportJob(&job)
+
+		lastSave := time.Now()
+		summary, err := RunProductImport(rows, parseErrors, dryRun, func(done int) {
+			job.Processed = done
+			if time.Since(lastSave) >= time.Second {
+				saveImportJob(&job)
+				lastSave = time.Now()
+			}
+		})
+
+		now := time.Now()
+		job.FinishedAt = &now
+		if err != nil {
+			log.Println("product import failed:", err)
+			job.Status = "failed"
+			job.Error = "import failed"
+		} else {
+			job.Status = "done"
+			job.Processed = job.Total
+			job.Result = summary
+		}
+		if err := saveImportJob(&job); err != nil {
+			log.Println("failed to save import job:", err)
+		}
+		if job.Status == "done" && onDone != nil {
+			onDone(&job)
+		}
+	}()
+	return job, nil
+}
+
+// GetImportJob returns a job, nil if it is unknown or expired
+func GetImportJob(id string) (*ImportJob, error) {
+	data, err := cache.Get("import:job:" + id)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var job ImportJob
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func saveImportJob(job *ImportJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return cache.Set("import:job:"+job.ID, string(data), importJobTTL)
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+func importRows() []database.ProductImportRow {
+	name, price := "Lamp", 30.0
+	return []database.ProductImportRow{{Line: 2, Slug: "lamp", Name: &name, Price: &price}}
+}
+
+// waitForJob polls the stored job until it finished
+func waitForJob(t *testing.T, id string) *ImportJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := GetImportJob(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job != nil && (job.Status == "done" || job.Status == "failed") {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("import job did not finish")
+	return nil
+}
+
+func TestStartImportJobCallsOnDone(t *testing.T) {
+	store := testutil.UseDB(t, &database.DB)
+	testutil.UseRedis(t, &cache.Rdb)
+
+	finished := make(chan *ImportJob, 1)
+	job, err := StartImportJob(importRows(), nil, false, 1, func(j *ImportJob) { finished <- j })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case j := <-finished:
+		if j.ID != job.ID || j.Status != "done" || j.Result == nil || !j.Result.Applied || j.Result.Created != 1 {
+			t.Errorf("finished job = %+v, result %+v", j, j.Result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("onDone was not called")
+	}
+	if stored := waitForJob(t, job.ID); stored.Status != "done" || stored.Processed != 1 {
+		t.Errorf("stored job = %+v, want done with 1 row processed", stored)
+	}
+	if rows := store.Rows("products"); len(rows) != 1 {
+		t.Errorf("stored %d products, want 1", len(rows))
+	}
+}
+
+func TestStartImportJobRecoversFromPanic(t *testing.T) {
+	testutil.UseDB(t, &database.DB)
+	testutil.UseRedis(t, &cache.Rdb)
+	err := database.DB.Callback().Create().Before("gorm:create").Register("test:panic", func(*gorm.DB) {
+		panic("boom")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	called := make(chan struct{}, 1)
+	job, err := StartImportJob(importRows(), nil, false, 1, func(*ImportJob) { called <- struct{}{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := waitForJob(t, job.ID)
+	if stored.Status != "failed" || stored.Error == "" || stored.FinishedAt == nil {
+		t.Errorf("stored job = %+v, want failed with an error and a finish time", stored)
+	}
+	select {
+	case <-called:
+		t.Error("onDone was called for a failed import")
+	default:
+	}
+}
+
+func TestParseProductImportCSVRejectsBrokenQuotes(t *testing.T) {
+	_, _, err := ParseProductImport(strings.NewReader("slug,name,price\nlamp,Lamp,30\nde\"sk,Desk,120\n"), "csv")
+	if !errors.Is(err, ErrImportFile) || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("err = %v, want %v on line 3", err, ErrImportFile)
+	}
+
+	rows, rowErrors, err := ParseProductImport(strings.NewReader("slug,name,price\nlamp,Lamp\ndesk,Desk,120\n"), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || len(rowErrors) != 1 || rowErrors[0].Line != 2 {
+		t.Errorf("rows = %+v, errors = %+v; want desk, and line 2 short of a value", rows, rowErrors)
+	}
+}
+
+func TestParseProductImportRequiresUploadedProductImages(t *testing.T) {
+	file := "slug,sku,name,price,image_url\n" +
+		"lamp,,Lamp,30,https://elsewhere.example.com/lamp.jpg\n" +
+		"desk,,Desk,120,/uploads/20240101120000.jpg\n" +
+		"desk,DESK-OAK,,,https://elsewhere.example.com/oak.jpg\n"
+	rows, rowErrors, err := ParseProductImport(strings.NewReader(file), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Line != 2 || rowErrors[0].Field != "image_url" {
+		t.Errorf("errors = %+v, want the lamp's image_url on line 2", rowErrors)
+	}
+	if len(rows) != 2 || *rows[0].ImageURL != "/uploads/20240101120000.jpg" || rows[1].SKU != "DESK-OAK" {
+		t.Errorf("rows = %+v, want the desk and its variant", rows)
+	}
+}
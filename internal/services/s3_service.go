@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"mime/multipart"
 	"path/filepath"
+	"strings"
 	"time"
 
 	appConfig "ecommerce-gin/internal/config" // rename this OR using Aliasing
@@ -74,3 +75,12 @@ func UploadToS3(file multipart.File, fileHeader *multipart.FileHeader) (string,
 
 	return newName, nil
 }
+
+// IsUploadedImageURL accepts URLs handed out by the upload endpoints
+func IsUploadedImageURL(url string) bool {
+	if strings.HasPrefix(url, "/uploads/") {
+		return true
+	}
+	public := strings.TrimSuffix(appConfig.Cfg.S3PublicURL, "/")
+	return public != "" && strings.HasPrefix(url, public+"/")
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"ecommerce-gin/internal/config"
+	"ecommerce-gin/internal/database"
+	"ecommerce-gin/internal/models"
+	"ecommerce-gin/internal/search"
+)
+
+// productIndex holds every active product. Each instance keeps its own copy:
+// writes through this instance update it right away, and a periodic rebuild
+// picks up changes made elsewhere.
+var productIndex = search.NewIndex()
+
+// InitSearch builds the product index and schedules the periodic rebuild
+func InitSearch() {
+	if err := RebuildSearchIndex(); err != nil {
+		log.Println("failed to build search index:", err)
+	}
+
+	if every := config.Cfg.SearchRefreshMinutes; every > 0 {
+		go func() {
+			for range time.Tick(time.Duration(every) * time.Minute) {
+				if err := RebuildSearchIndex(); err != nil {
+					log.Println("failed to rebuild search index:", err)
+				}
+			}
+		}()
+	}
+}
+
+// RebuildSearchIndex reloads every product, e.g. after categories were renamed or moved
+func RebuildSearchIndex() error {
+	products, err := database.ListSearchableProducts(nil)
+	if err != nil {
+		return err
+	}
+	paths, err := loadCategoryPaths()
+	if err != nil {
+		return err
+	}
+
+	docs := make([]search.Document, 0, len(products))
+	for i := range products {
+		docs = append(docs, searchDocument(&products[i], paths))
+	}
+	productIndex.Replace(docs)
+	log.Printf("Search index ready: %d products", len(docs))
+	return nil
+}
+
+// ReindexProducts refreshes the given products after they were written; deleted
+// and inactive ones leave the index
+func ReindexProducts(ids ...uint) {
+	if len(ids) == 0 {
+		return
+	}
+	products, err := database.ListSearchableProducts(ids)
+	if err != nil {
+		log.Println("failed to reindex products:", err)
+		return
+	}
+	paths, err := loadCategoryPaths()
+	if err != nil {
+		log.Println("failed to reindex products:", err)
+		return
+	}
+
+	active := map[uint]bool{}
+	for i := range products {
+		productIndex.Upsert(searchDocument(&products[i], paths))
+		active[products[i].ID] = true
+	}
+	for _, id := range ids {
+		if !active[id] {
+			productIndex.Remove(id)
+		}
+	}
+}
+
+// SearchProducts runs a query against the product index
+func SearchProducts(q search.Query) search.Result {
+	return productIndex.Search(q)
+}
+
+type categoryPath struct {
+	names []string
+	ids   []uint
+}
+
+// loadCategoryPaths maps every category to the names and ids from the root down to it
+func loadCategoryPaths() (map[uint]categoryPath, error) {
+	categories, err := database.ListCategories()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+
+	paths := make(map[uint]categoryPath, len(categories))
+	for _, c := range categories {
+		var p categoryPath
+		for cur, ok := c, true; ok && len(p.ids) <= len(categories); {
+			p.names = append([]string{cur.Name}, p.names...)
+			p.ids = append([]uint{cur.ID}, p.ids...)
+			if cur.ParentID == nil {
+				break
+			}
+			cur, ok = byID[*cur.ParentID]
+		}
+		paths[c.ID] = p
+	}
+	return paths, nil
+}
+
+func searchDocument(p *models.Product, paths map[uint]categoryPath) search.Document {
+	doc := search.Document{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		InStock:     p.Quantity > 0,
+	}
+	if p.CategoryID != nil {
+		path := paths[*p.CategoryID]
+		doc.Categories, doc.CategoryIDs = path.names, path.ids
+	}
+	return doc
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"ecommerce-gin/internal/cache"
+	"ecommerce-gin/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func deniedTokenKey(jti string) string {
+	return "auth:denied:" + jti
+}
+
+func revokedBeforeKey(userID uint) string {
+	return fmt.Sprintf("auth:revoked_before:%d", userID)
+}
+
+func revokedSessionKey(sessionID uint) string {
+	return fmt.Sprintf("auth:revoked_session:%d", sessionID)
+}
+
+func disabledUserKey(userID uint) string {
+	return fmt.Sprintf("auth:disabled:%d", userID)
+}
+
+// accessTokenTTL is how long a revocation marker has to live to cover any outstanding token
+func accessTokenTTL() time.Duration {
+	return time.Duration(config.Cfg.AccessTokenMinutes)*time.Minute + time.Minute
+}
+
+// RevokeAccessToken denylists a single access token until it would have expired anyway
+func RevokeAccessToken(jti string, exp time.Time) error {
+	ttl := time.Until(exp)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	return cache.Rdb.Set(cache.Ctx, deniedTokenKey(jti), "1", ttl).Err()
+}
+
+// RevokeUserTokens invalidates every access token issued to the user up to now.
+// The watermark is in milliseconds, so a token minted right after it (a login
+// following a password reset) stays valid. It only has to outlive the longest
+// access token, after that every token issued before it has expired on its own.
+func RevokeUserTokens(userID uint) error {
+	return cache.Rdb.Set(cache.Ctx, revokedBeforeKey(userID), time.Now().UnixMilli(), accessTokenTTL()).Err()
+}
+
+// RevokeSessionTokens invalidates every access token issued for one session
+func RevokeSessionTokens(sessionID uint) error {
+	return cache.Rdb.Set(cache.Ctx, revokedSessionKey(sessionID), "1", accessTokenTTL()).Err()
+}
+
+// IsAccessTokenRevoked reports whether the token was denylisted, belongs to a revoked
+// session or was issued before the user's revocation watermark. issuedAtMillis is
+// the iat_ms claim of the token.
+func IsAccessTokenRevoked(jti string, userID, sessionID uint, issuedAtMillis int64) (bool, error) {
+	vals, err := cache.Rdb.MGet(cache.Ctx,
+		deniedTokenKey(jti),
+		revokedSessionKey(sessionID),
+		revokedBeforeKey(userID),
+	).Result()
+	if err != nil && err != redis.Nil {
+		return false, err
+	}
+	if vals[0] != nil || vals[1] != nil {
+		return true, nil
+	}
+	if s, ok := vals[2].(string); ok {
+		watermark, _ := strconv.ParseInt(s, 10, 64)
+		if issuedAtMillis < watermark {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MarkUserDisabled makes JWTAuth reject the user's tokens until MarkUserEnabled.
+// The database flag stays authoritative for login and refresh.
+func MarkUserDisabled(userID uint) error {
+	return cache.Rdb.Set(cache.Ctx, disabledUserKey(userID), "1", 0).Err()
+}
+
+func MarkUserEnabled(userID uint) error {
+	return cache.Rdb.Del(cache.Ctx, disabledUserKey(userID)).Err()
+}
+
+// IsUserDisabled reports whether an admin disabled the account
+func IsUserDisabled(userID uint) (bool, error) {
+	n, err := cache.Rdb.Exists(cache.Ctx, disabledUserKey(userID)).Result()
+	return n > 0, err
+}
@@ -0,0 +1,166 @@
+package testutil
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// UseRedis points rdb, e.g. &cache.Rdb, at a new in-memory Redis until the test ends
+func UseRedis(t testing.TB, rdb **redis.Client) {
+	t.Helper()
+	prev := *rdb
+	client := NewRedis()
+	t.Cleanup(func() {
+		client.Close()
+		*rdb = prev
+	})
+	*rdb = client
+}
+
+// NewRedis returns a go-redis client backed by an in-process store. It speaks
+// enough RESP2 for the string commands the services use (PING, GET, SET, GETDEL,
+// DEL, EXISTS, INCR); expiries are accepted but never fire.
+func NewRedis() *redis.Client {
+	s := &redisStore{data: map[string]string{}}
+	return redis.NewClient(&redis.Options{
+		Addr:            "fake-redis:6379",
+		Protocol:        2,
+		DisableIdentity: true,
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			client, server := net.Pipe()
+			go s.serve(server)
+			return client, nil
+		},
+	})
+}
+
+type redisStore struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func (s *redisStore) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, s.exec(args)); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads one RESP array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func bulk(v string) string {
+	return "$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"
+}
+
+func integer(n int) string {
+	return ":" + strconv.Itoa(n) + "\r\n"
+}
+
+const nilReply = "$-1\r\n"
+
+func (s *redisStore) exec(args []string) string {
+	if len(args) == 0 {
+		return "-ERR empty command\r\n"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch cmd := strings.ToUpper(args[0]); {
+	case cmd == "PING":
+		return "+PONG\r\n"
+	case cmd == "GET" && len(args) == 2:
+		if v, ok := s.data[args[1]]; ok {
+			return bulk(v)
+		}
+		return nilReply
+	case cmd == "GETDEL" && len(args) == 2:
+		v, ok := s.data[args[1]]
+		if !ok {
+			return nilReply
+		}
+		delete(s.data, args[1])
+		return bulk(v)
+	case cmd == "SET" && len(args) >= 3:
+		_, exists := s.data[args[1]]
+		for _, opt := range args[3:] {
+			switch strings.ToUpper(opt) {
+			case "NX":
+				if exists {
+					return nilReply
+				}
+			case "XX":
+				if !exists {
+					return nilReply
+				}
+			}
+		}
+		s.data[args[1]] = args[2]
+		return "+OK\r\n"
+	case cmd == "DEL" || cmd == "EXISTS":
+		n := 0
+		for _, key := range args[1:] {
+			if _, ok := s.data[key]; ok {
+				n++
+				if cmd == "DEL" {
+					delete(s.data, key)
+				}
+			}
+		}
+		return integer(n)
+	case cmd == "INCR" && len(args) == 2:
+		n, err := strconv.Atoi(s.data[args[1]])
+		if err != nil && s.data[args[1]] != "" {
+			return "-ERR value is not an integer or out of range\r\n"
+		}
+		n++
+		s.data[args[1]] = strconv.Itoa(n)
+		return integer(n)
+	}
+	return "-ERR unsupported command '" + args[0] + "'\r\n"
+}
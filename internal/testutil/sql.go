@@ -0,0 +1,885 @@
+// Package testutil provides in-process stand-ins for MySQL and Redis so
+// repositories, services and handlers can be tested without running servers.
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// NewDB returns gorm on the MySQL dialector, backed by an in-memory store. The
+// store understands the single-table statements gorm generates: INSERT, SELECT
+// (*, [DISTINCT] columns, count(*), COALESCE(SUM(col), 0)), UPDATE and DELETE, with WHERE
+// made of comparisons, IN, IS [NOT] NULL, AND, OR and parentheses, and ORDER BY,
+// LIMIT and OFFSET. Joins and grouping are rejected. Statements it cannot run
+// fail the test through the error.
+//
+// Transactions and savepoints roll back the rows their own statements wrote.
+// There is no isolation: other connections see the writes before the commit.
+func NewDB() (*gorm.DB, *Store) {
+	store := &Store{tables: map[string]*table{}}
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(store),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{Logger: logger.Discard, TranslateError: true})
+	if err != nil {
+		panic(err)
+	}
+	return db, store
+}
+
+// UseDB points db, e.g. &database.DB, at a new in-memory store until the test ends
+func UseDB(t testing.TB, db **gorm.DB) *Store {
+	t.Helper()
+	prev := *db
+	t.Cleanup(func() { *db = prev })
+	var store *Store
+	*db, store = NewDB()
+	return store
+}
+
+// Store holds the rows of every table
+type Store struct {
+	mu     sync.Mutex
+	tables map[string]*table
+	undo   *[]func() // undo log of the transaction running the statement, if any
+}
+
+type table struct {
+	cols   []string
+	rows   []map[string]driver.Value
+	nextID int64
+	unique []string
+}
+
+// Unique makes inserts into table fail with gorm.ErrDuplicatedKey when col repeats
+func (s *Store) Unique(table, col string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.table(table)
+	t.unique = append(t.unique, col)
+}
+
+// Rows returns a copy of the rows of a table, with columns by name
+func (s *Store) Rows(name string) []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.tables[name]
+	if t == nil {
+		return nil
+	}
+	out := make([]map[string]any, len(t.rows))
+	for i, row := range t.rows {
+		out[i] = map[string]any{}
+		for k, v := range row {
+			out[i][k] = v
+		}
+	}
+	return out
+}
+
+func (s *Store) table(name string) *table {
+	t := s.tables[name]
+	if t == nil {
+		t = &table{cols: []string{"id"}, nextID: 1}
+		s.tables[name] = t
+	}
+	return t
+}
+
+// record remembers how to revert a write when it happens inside a transaction
+func (s *Store) record(undo func()) {
+	if s.undo != nil {
+		*s.undo = append(*s.undo, undo)
+	}
+}
+
+func (t *table) addColumn(col string) {
+	for _, c := range t.cols {
+		if c == col {
+			return
+		}
+	}
+	t.cols = append(t.cols, col)
+}
+
+// database/sql plumbing; every statement is run through Store.exec
+
+func (s *Store) Connect(context.Context) (driver.Conn, error) { return &conn{s: s}, nil }
+func (s *Store) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, errors.New("use NewDB") }
+
+type conn struct {
+	s          *Store
+	tx         bool
+	undo       []func()
+	savepoints []savepoint
+}
+
+type savepoint struct {
+	name string
+	at   int // length of the undo log when it was set
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) { return &stmt{c, query}, nil }
+func (c *conn) Close() error                              { return nil }
+func (c *conn) Begin() (driver.Tx, error) {
+	c.tx, c.undo, c.savepoints = true, nil, nil
+	return tx{c}, nil
+}
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) { return c.Begin() }
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.exec(query, values(args))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.exec(query, values(args))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *conn) exec(query string, args []driver.Value) (*result, error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	if m := savepointRe.FindStringSubmatch(strings.TrimSpace(strings.ReplaceAll(query, "`", ""))); m != nil {
+		return &result{}, c.savepoint(strings.ToUpper(m[1]), m[2])
+	}
+	if c.tx {
+		c.s.undo = &c.undo
+		defer func() { c.s.undo = nil }()
+	}
+	return c.s.exec(query, args)
+}
+
+func (c *conn) savepoint(op, name string) error {
+	if op == "SAVEPOINT" {
+		c.savepoints = append(c.savepoints, savepoint{name, len(c.undo)})
+		return nil
+	}
+	for i := len(c.savepoints) - 1; i >= 0; i-- {
+		sp := c.savepoints[i]
+		if sp.name != name {
+			continue
+		}
+		if op == "RELEASE SAVEPOINT" {
+			c.savepoints = c.savepoints[:i]
+		} else {
+			// the savepoint stays, it can be rolled back to again
+			c.rollback(sp.at)
+			c.savepoints = c.savepoints[:i+1]
+		}
+		return nil
+	}
+	return fmt.Errorf("testutil: no savepoint %q", name)
+}
+
+// rollback reverts the writes logged after the first at
+func (c *conn) rollback(at int) {
+	for i := len(c.undo) - 1; i >= at; i-- {
+		c.undo[i]()
+	}
+	c.undo = c.undo[:at]
+}
+
+type tx struct{ c *conn }
+
+func (t tx) Commit() error {
+	t.c.tx, t.c.undo, t.c.savepoints = false, nil, nil
+	return nil
+}
+
+func (t tx) Rollback() error {
+	t.c.s.mu.Lock()
+	defer t.c.s.mu.Unlock()
+	t.c.rollback(0)
+	t.c.tx, t.c.savepoints = false, nil
+	return nil
+}
+
+type stmt struct {
+	c     *conn
+	query string
+}
+
+func (st *stmt) Close() error  { return nil }
+func (st *stmt) NumInput() int { return -1 }
+func (st *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return st.c.exec(st.query, args)
+}
+func (st *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return st.c.exec(st.query, args)
+}
+
+func values(named []driver.NamedValue) []driver.Value {
+	out := make([]driver.Value, len(named))
+	for i, nv := range named {
+		out[i] = nv.Value
+	}
+	return out
+}
+
+// result is both the driver.Result of a write and the driver.Rows of a read
+type result struct {
+	lastID   int64
+	affected int64
+	cols     []string
+	rows     [][]driver.Value
+	pos      int
+}
+
+func (r *result) LastInsertId() (int64, error) { return r.lastID, nil }
+func (r *result) RowsAffected() (int64, error) { return r.affected, nil }
+func (r *result) Columns() []string            { return r.cols }
+func (r *result) Close() error                 { return nil }
+func (r *result) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
+
+var (
+	qualified    = regexp.MustCompile(`\b[A-Za-z_]\w*\.([A-Za-z_]\w*)\b`)
+	insertRe     = regexp.MustCompile(`(?is)^INSERT INTO (\w+) \(([^)]*)\) VALUES (.*?)(?: ON DUPLICATE KEY UPDATE .*)?$`)
+	selectRe     = regexp.MustCompile(`(?is)^SELECT (.*?) FROM (\w+)(?: WHERE (.*?))?(?: ORDER BY (.*?))?(?: LIMIT (\d+|\?))?(?: OFFSET (\d+|\?))?(?: FOR UPDATE)?$`)
+	updateRe     = regexp.MustCompile(`(?is)^UPDATE (\w+) SET (.*)$`)
+	deleteRe     = regexp.MustCompile(`(?is)^DELETE FROM (\w+) WHERE (.*)$`)
+	sumRe        = regexp.MustCompile(`(?i)^COALESCE\(SUM\((\w+)\), ?0\)$`)
+	columnExprRe = regexp.MustCompile(`^(\w+) ?([+-]|=|<>) ?\?$`)
+	unsupported  = regexp.MustCompile(`(?i)\b(JOIN|GROUP BY|HAVING|UNION)\b`)
+	savepointRe  = regexp.MustCompile(`(?i)^(SAVEPOINT|ROLLBACK TO SAVEPOINT|RELEASE SAVEPOINT) (\w+)$`)
+)
+
+// exec runs one statement; the caller holds s.mu
+func (s *Store) exec(query string, args []driver.Value) (*result, error) {
+	q := strings.TrimSpace(strings.ReplaceAll(query, "`", ""))
+	q = qualified.ReplaceAllString(q, "$1")
+	if unsupported.MatchString(q) {
+		return nil, fmt.Errorf("testutil: unsupported statement %q", query)
+	}
+	in := &argList{args: args}
+
+	var (
+		res *result
+		err error
+	)
+	switch {
+	case insertRe.MatchString(q):
+		res, err = s.insert(insertRe.FindStringSubmatch(q), in)
+	case selectRe.MatchString(q):
+		res, err = s.selectRows(selectRe.FindStringSubmatch(q), in)
+	case updateRe.MatchString(q):
+		res, err = s.update(updateRe.FindStringSubmatch(q), in)
+	case deleteRe.MatchString(q):
+		res, err = s.delete(deleteRe.FindStringSubmatch(q), in)
+	default:
+		err = fmt.Errorf("testutil: unsupported statement %q", query)
+	}
+	if err == nil && in.pos != len(args) {
+		err = fmt.Errorf("testutil: %d of %d arguments used by %q", in.pos, len(args), query)
+	}
+	return res, err
+}
+
+type argList struct {
+	args []driver.Value
+	pos  int
+}
+
+func (a *argList) next() (driver.Value, error) {
+	if a.pos >= len(a.args) {
+		return nil, errors.New("testutil: not enough arguments")
+	}
+	a.pos++
+	return a.args[a.pos-1], nil
+}
+
+func (s *Store) insert(m []string, in *argList) (*result, error) {
+	t := s.table(m[1])
+	cols := splitList(m[2])
+	tuples := strings.Count(m[3], "(")
+	res := &result{}
+	for i := 0; i < tuples; i++ {
+		row := map[string]driver.Value{}
+		for _, col := range cols {
+			v, err := in.next()
+			if err != nil {
+				return nil, err
+			}
+			row[col] = v
+			t.addColumn(col)
+		}
+		for _, col := range t.unique {
+			for _, other := range t.rows {
+				if c, ok := compare(row[col], other[col]); ok && c == 0 {
+					return nil, fmt.Errorf("duplicate entry '%v' for key '%s': %w", row[col], col, gorm.ErrDuplicatedKey)
+				}
+			}
+		}
+		if id, ok := row["id"]; ok && id != nil && toFloat(id) != 0 {
+			row["id"] = int64(toFloat(id))
+			if n := row["id"].(int64); n >= t.nextID {
+				t.nextID = n + 1
+			}
+		} else {
+			row["id"] = t.nextID
+			t.nextID++
+		}
+		if i == 0 {
+			res.lastID = row["id"].(int64)
+		}
+		t.rows = append(t.rows, row)
+		s.record(func() { t.rows = removeRows(t.rows, []map[string]driver.Value{row}) })
+		res.affected++
+	}
+	return res, nil
+}
+
+func (s *Store) selectRows(m []string, in *argList) (*result, error) {
+	t := s.table(m[2])
+	matches, err := s.where(t, m[3], in)
+	if err != nil {
+		return nil, err
+	}
+	if m[4] != "" {
+		sortRows(matches, m[4])
+	}
+	limit, offset := -1, 0
+	if m[5] != "" {
+		if limit, err = intArg(m[5], in); err != nil {
+			return nil, err
+		}
+	}
+	if m[6] != "" {
+		if offset, err = intArg(m[6], in); err != nil {
+			return nil, err
+		}
+	}
+	if offset > len(matches) {
+		offset = len(matches)
+	}
+	matches = matches[offset:]
+	if limit >= 0 && limit < len(matches) {
+		matches = matches[:limit]
+	}
+
+	sel := strings.TrimSpace(m[1])
+	distinct := false
+	if len(sel) > len("DISTINCT ") && strings.EqualFold(sel[:len("DISTINCT ")], "DISTINCT ") {
+		distinct, sel = true, strings.TrimSpace(sel[len("DISTINCT "):])
+	}
+	switch {
+	case sel == "*":
+		res := &result{cols: t.cols}
+		for _, row := range matches {
+			vals := make([]driver.Value, len(t.cols))
+			for i, col := range t.cols {
+				vals[i] = row[col]
+			}
+			res.rows = append(res.rows, vals)
+		}
+		return res, nil
+	case strings.EqualFold(sel, "count(*)"):
+		return &result{cols: []string{"count(*)"}, rows: [][]driver.Value{{int64(len(matches))}}}, nil
+	case sumRe.MatchString(sel):
+		col := sumRe.FindStringSubmatch(sel)[1]
+		var sum float64
+		for _, row := range matches {
+			sum += toFloat(row[col])
+		}
+		return &result{cols: []string{sel}, rows: [][]driver.Value{{sum}}}, nil
+	}
+	cols := splitList(sel)
+	res := &result{cols: cols}
+	seen := map[string]bool{}
+	for _, row := range matches {
+		vals := make([]driver.Value, len(cols))
+		for i, col := range cols {
+			vals[i] = row[col]
+		}
+		if distinct {
+			key := fmt.Sprintf("%#v", vals)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		res.rows = append(res.rows, vals)
+	}
+	return res, nil
+}
+
+func (s *Store) update(m []string, in *argList) (*result, error) {
+	t := s.table(m[1])
+	assignments, cond, ok := cutTopLevel(m[2], " WHERE ")
+	if !ok {
+		return nil, errors.New("testutil: UPDATE without WHERE")
+	}
+	type assignment struct {
+		col   string
+		value func(row map[string]driver.Value) driver.Value
+	}
+	var sets []assignment
+	for _, part := range splitTopLevel(assignments) {
+		col, expr, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("testutil: bad assignment %q", part)
+		}
+		col, expr = strings.TrimSpace(col), strings.TrimSpace(expr)
+		t.addColumn(col)
+		switch {
+		case expr == "?":
+			v, err := in.next()
+			if err != nil {
+				return nil, err
+			}
+			sets = append(sets, assignment{col, func(map[string]driver.Value) driver.Value { return v }})
+		case strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")"):
+			sub := selectRe.FindStringSubmatch(strings.TrimSpace(expr[1 : len(expr)-1]))
+			if sub == nil {
+				return nil, fmt.Errorf("testutil: unsupported subquery %q", expr)
+			}
+			res, err := s.selectRows(sub, in)
+			if err != nil {
+				return nil, err
+			}
+			var v driver.Value
+			if len(res.rows) > 0 {
+				v = res.rows[0][0]
+			}
+			sets = append(sets, assignment{col, func(map[string]driver.Value) driver.Value { return v }})
+		case columnExprRe.MatchString(expr):
+			am := columnExprRe.FindStringSubmatch(expr)
+			v, err := in.next()
+			if err != nil {
+				return nil, err
+			}
+			src, op := am[1], am[2]
+			sets = append(sets, assignment{col, func(row map[string]driver.Value) driver.Value {
+				switch op {
+				case "+":
+					return toFloat(row[src]) + toFloat(v)
+				case "-":
+					return toFloat(row[src]) - toFloat(v)
+				}
+				c, ok := compare(row[src], v)
+				return ok && (c == 0) == (op == "=")
+			}})
+		default:
+			return nil, fmt.Errorf("testutil: unsupported expression %q", expr)
+		}
+	}
+
+	matches, err := s.where(t, cond, in)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range matches {
+		computed := make([]driver.Value, len(sets))
+		for i, set := range sets {
+			computed[i] = set.value(row)
+		}
+		old := make(map[string]driver.Value, len(sets))
+		for i, set := range sets {
+			old[set.col] = row[set.col]
+			row[set.col] = computed[i]
+		}
+		s.record(func() {
+			for col, v := range old {
+				row[col] = v
+			}
+		})
+	}
+	return &result{affected: int64(len(matches))}, nil
+}
+
+func (s *Store) delete(m []string, in *argList) (*result, error) {
+	t := s.table(m[1])
+	matches, err := s.where(t, m[2], in)
+	if err != nil {
+		return nil, err
+	}
+	t.rows = removeRows(t.rows, matches)
+	s.record(func() {
+		t.rows = append(t.rows, matches...)
+		sort.SliceStable(t.rows, func(i, j int) bool { return toFloat(t.rows[i]["id"]) < toFloat(t.rows[j]["id"]) })
+	})
+	return &result{affected: int64(len(matches))}, nil
+}
+
+// removeRows returns rows without the ones in drop, leaving rows itself untouched
+func removeRows(rows, drop []map[string]driver.Value) []map[string]driver.Value {
+	var kept []map[string]driver.Value
+	for _, row := range rows {
+		if !containsRow(drop, row) {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
+
+func containsRow(rows []map[string]driver.Value, row map[string]driver.Value) bool {
+	for _, r := range rows {
+		if r["id"] == row["id"] {
+			return true
+		}
+	}
+	return false
+}
+
+// where returns the rows of t matching the condition; rows are shared, not copied
+func (s *Store) where(t *table, cond string, in *argList) ([]map[string]driver.Value, error) {
+	match := func(map[string]driver.Value) bool { return true }
+	if strings.TrimSpace(cond) != "" {
+		p := &condParser{tokens: tokenize(cond), in: in}
+		var err error
+		if match, err = p.or(); err != nil {
+			return nil, err
+		}
+		if p.pos != len(p.tokens) {
+			return nil, fmt.Errorf("testutil: cannot parse condition %q", cond)
+		}
+	}
+	var out []map[string]driver.Value
+	for _, row := range t.rows {
+		if match(row) {
+			out = append(out, row)
+		}
+	}
+	return out, nil
+}
+
+var tokenRe = regexp.MustCompile(`'(?:[^']|'')*'|<>|!=|<=|>=|[=<>(),?]|[\w.]+`)
+
+func tokenize(s string) []string {
+	return tokenRe.FindAllString(s, -1)
+}
+
+type predicate func(row map[string]driver.Value) bool
+
+type condParser struct {
+	tokens []string
+	pos    int
+	in     *argList
+}
+
+func (p *condParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *condParser) take() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *condParser) or() (predicate, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "OR") {
+		p.take()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(row map[string]driver.Value) bool { return l(row) || right(row) }
+	}
+	return left, nil
+}
+
+func (p *condParser) and() (predicate, error) {
+	left, err := p.atom()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "AND") {
+		p.take()
+		right, err := p.atom()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(row map[string]driver.Value) bool { return l(row) && right(row) }
+	}
+	return left, nil
+}
+
+func (p *condParser) atom() (predicate, error) {
+	if p.peek() == "(" {
+		p.take()
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.take() != ")" {
+			return nil, errors.New("testutil: unbalanced parentheses")
+		}
+		return inner, nil
+	}
+	if strings.EqualFold(p.peek(), "NOT") {
+		p.take()
+		inner, err := p.atom()
+		if err != nil {
+			return nil, err
+		}
+		return func(row map[string]driver.Value) bool { return !inner(row) }, nil
+	}
+
+	col := p.take()
+	switch op := strings.ToUpper(p.take()); op {
+	case "IS":
+		negate := false
+		if strings.EqualFold(p.peek(), "NOT") {
+			p.take()
+			negate = true
+		}
+		if !strings.EqualFold(p.take(), "NULL") {
+			return nil, errors.New("testutil: expected NULL")
+		}
+		return func(row map[string]driver.Value) bool { return (row[col] == nil) != negate }, nil
+	case "IN":
+		if p.take() != "(" {
+			return nil, errors.New("testutil: expected ( after IN")
+		}
+		var set []driver.Value
+		for {
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			set = append(set, v)
+			if tok := p.take(); tok == ")" {
+				break
+			} else if tok != "," {
+				return nil, errors.New("testutil: bad IN list")
+			}
+		}
+		return func(row map[string]driver.Value) bool {
+			for _, v := range set {
+				if c, ok := compare(row[col], v); ok && c == 0 {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "=", "<>", "!=", "<", ">", "<=", ">=":
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		return func(row map[string]driver.Value) bool {
+			c, ok := compare(row[col], v)
+			if !ok {
+				return false
+			}
+			switch op {
+			case "=":
+				return c == 0
+			case "<>", "!=":
+				return c != 0
+			case "<":
+				return c < 0
+			case ">":
+				return c > 0
+			case "<=":
+				return c <= 0
+			}
+			return c >= 0
+		}, nil
+	default:
+		return nil, fmt.Errorf("testutil: unsupported operator %q", op)
+	}
+}
+
+func (p *condParser) value() (driver.Value, error) {
+	tok := p.take()
+	switch {
+	case tok == "?":
+		return p.in.next()
+	case strings.HasPrefix(tok, "'"):
+		return strings.ReplaceAll(tok[1:len(tok)-1], "''", "'"), nil
+	case strings.EqualFold(tok, "true"):
+		return true, nil
+	case strings.EqualFold(tok, "false"):
+		return false, nil
+	case strings.EqualFold(tok, "NULL"):
+		return nil, nil
+	}
+	if f, err := strconv.ParseFloat(tok, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("testutil: unsupported value %q", tok)
+}
+
+func intArg(tok string, in *argList) (int, error) {
+	if tok != "?" {
+		return strconv.Atoi(tok)
+	}
+	v, err := in.next()
+	if err != nil {
+		return 0, err
+	}
+	return int(toFloat(v)), nil
+}
+
+func sortRows(rows []map[string]driver.Value, order string) {
+	keys := splitList(order)
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, key := range keys {
+			fields := strings.Fields(key)
+			c, _ := compare(rows[i][fields[0]], rows[j][fields[0]])
+			if c == 0 {
+				continue
+			}
+			if len(fields) > 1 && strings.EqualFold(fields[1], "DESC") {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// cutTopLevel is strings.Cut ignoring matches inside parentheses
+func cutTopLevel(s, sep string) (before, after string, found bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth == 0 && strings.HasPrefix(s[i:], sep) {
+			return s[:i], s[i+len(sep):], true
+		}
+	}
+	return s, "", false
+}
+
+// splitTopLevel splits on commas outside parentheses
+func splitTopLevel(s string) []string {
+	var out []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(out, strings.TrimSpace(s[start:]))
+}
+
+// compare orders two values the way MySQL would for the types gorm sends.
+// ok is false when either side is NULL.
+func compare(a, b driver.Value) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb), true
+		}
+	}
+	if isNumber(a) || isNumber(b) {
+		fa, fb := toFloat(a), toFloat(b)
+		switch {
+		case fa < fb:
+			return -1, true
+		case fa > fb:
+			return 1, true
+		}
+		return 0, true
+	}
+	return strings.Compare(toString(a), toString(b)), true
+}
+
+func isNumber(v driver.Value) bool {
+	switch v.(type) {
+	case int64, float64, bool:
+		return true
+	}
+	return false
+}
+
+func toFloat(v driver.Value) float64 {
+	switch x := v.(type) {
+	case int64:
+		return float64(x)
+	case float64:
+		return x
+	case bool:
+		if x {
+			return 1
+		}
+		return 0
+	case string:
+		f, _ := strconv.ParseFloat(x, 64)
+		return f
+	case []byte:
+		f, _ := strconv.ParseFloat(string(x), 64)
+		return f
+	}
+	return 0
+}
+
+func toString(v driver.Value) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	}
+	return fmt.Sprint(v)
+}
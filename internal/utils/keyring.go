@@ -0,0 +1,285 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"ecommerce-gin/internal/config"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// hmacKeyID is the kid of the shared JWT_SECRET key. Tokens issued before key ids
+// existed carry no kid at all and are verified against it as well.
+const hmacKeyID = "hs256"
+
+type jwtKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	private interface{} // nil for retired keys that can only verify
+	public  interface{}
+}
+
+// Keyring holds every key tokens may be verified with, and the single active key
+// new tokens are signed with. Keys that are not active are "retired": they keep
+// verifying tokens issued before a rotation until those tokens expire.
+type Keyring struct {
+	active *jwtKey
+	keys   map[string]*jwtKey
+}
+
+// Keys is the process wide keyring, set up by InitKeyring
+var Keys *Keyring
+
+// InitKeyring loads the signing keys described by the config.
+//
+// With JWT_ALGORITHM=HS256 (the default) tokens are signed with JWT_SECRET.
+// With RS256 or EdDSA, private keys are read from JWT_KEYS_DIR as <kid>.pem and
+// public-only retired keys as <kid>.pub.pem. JWT_ACTIVE_KID selects the signing
+// key (defaults to the highest kid). To rotate, add a new key file and point
+// JWT_ACTIVE_KID at it; keep the old file until its tokens have expired.
+func InitKeyring() {
+	kr, err := loadKeyring(config.Cfg)
+	if err != nil {
+		log.Fatal("Failed to load JWT keys: ", err)
+	}
+	Keys = kr
+	log.Println("JWT keyring loaded, active key:", kr.active.ID)
+}
+
+func loadKeyring(cfg config.Config) (*Keyring, error) {
+	kr := &Keyring{keys: map[string]*jwtKey{}}
+	alg := strings.ToUpper(cfg.JWTAlgorithm)
+
+	if alg == "HS256" || cfg.JWTLegacyHS256 {
+		kr.keys[hmacKeyID] = &jwtKey{
+			ID:      hmacKeyID,
+			Method:  jwt.SigningMethodHS256,
+			private: []byte(cfg.JWTSecret),
+			public:  []byte(cfg.JWTSecret),
+		}
+	}
+
+	if cfg.JWTKeysDir != "" {
+		if err := kr.loadDir(cfg.JWTKeysDir); err != nil {
+			return nil, err
+		}
+	}
+
+	switch alg {
+	case "HS256":
+		kr.active = kr.keys[hmacKeyID]
+		return kr, nil
+	case "RS256", "EDDSA":
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALGORITHM %q", cfg.JWTAlgorithm)
+	}
+
+	kid := cfg.JWTActiveKID
+	if kid == "" {
+		kid = kr.newestPrivateKeyID()
+	}
+	if kid != "" {
+		key, ok := kr.keys[kid]
+		if !ok || key.private == nil {
+			return nil, fmt.Errorf("no private key found for JWT_ACTIVE_KID %q", kid)
+		}
+		kr.active = key
+		return kr, nil
+	}
+
+	// nothing configured yet: create a key so development works out of the box
+	if cfg.AppEnv == "production" {
+		return nil, errors.New("JWT_KEYS_DIR has no signing key")
+	}
+	key, err := generateKey(alg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.JWTKeysDir != "" {
+		if err := writePrivateKey(cfg.JWTKeysDir, key); err != nil {
+			return nil, err
+		}
+		log.Println("Generated JWT signing key", key.ID, "in", cfg.JWTKeysDir)
+	} else {
+		log.Println("Generated ephemeral JWT signing key; tokens will not survive a restart")
+	}
+	kr.keys[key.ID] = key
+	kr.active = key
+	return kr, nil
+}
+
+func (k *Keyring) loadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		name := filepath.Base(f)
+		var key *jwtKey
+		if strings.HasSuffix(name, ".pub.pem") {
+			key, err = parsePublicKey(strings.TrimSuffix(name, ".pub.pem"), data)
+		} else {
+			key, err = parsePrivateKey(strings.TrimSuffix(name, ".pem"), data)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if existing, ok := k.keys[key.ID]; ok && existing.private != nil {
+			continue // private key wins over a leftover public file
+		}
+		k.keys[key.ID] = key
+	}
+	return nil
+}
+
+func (k *Keyring) newestPrivateKeyID() string {
+	newest := ""
+	for id, key := range k.keys {
+		if key.private != nil && id != hmacKeyID && id > newest {
+			newest = id
+		}
+	}
+	return newest
+}
+
+func parsePrivateKey(kid string, data []byte) (*jwtKey, error) {
+	if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		return &jwtKey{ID: kid, Method: jwt.SigningMethodRS256, private: rsaKey, public: &rsaKey.PublicKey}, nil
+	}
+	edKey, err := jwt.ParseEdPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, errors.New("not an RSA or Ed25519 private key")
+	}
+	signer := edKey.(crypto.Signer)
+	return &jwtKey{ID: kid, Method: jwt.SigningMethodEdDSA, private: edKey, public: signer.Public()}, nil
+}
+
+func parsePublicKey(kid string, data []byte) (*jwtKey, error) {
+	if rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return &jwtKey{ID: kid, Method: jwt.SigningMethodRS256, public: rsaKey}, nil
+	}
+	edKey, err := jwt.ParseEdPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, errors.New("not an RSA or Ed25519 public key")
+	}
+	return &jwtKey{ID: kid, Method: jwt.SigningMethodEdDSA, public: edKey}, nil
+}
+
+func generateKey(alg string) (*jwtKey, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	kid := time.Now().UTC().Format("20060102150405") + "-" + hex.EncodeToString(suffix)
+
+	if alg == "EDDSA" {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return &jwtKey{ID: kid, Method: jwt.SigningMethodEdDSA, private: priv, public: pub}, nil
+	}
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &jwtKey{ID: kid, Method: jwt.SigningMethodRS256, private: priv, public: &priv.PublicKey}, nil
+}
+
+func writePrivateKey(dir string, key *jwtKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key.private)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	block := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	return os.WriteFile(filepath.Join(dir, key.ID+".pem"), block, 0o600)
+}
+
+// Sign signs claims with the active key and stamps its kid in the header
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.Method, claims)
+	token.Header["kid"] = k.active.ID
+	return token.SignedString(k.active.private)
+}
+
+// Keyfunc picks the verification key by the token's kid, for use with jwt.Parse
+func (k *Keyring) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		kid = hmacKeyID
+	}
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, jwt.ErrTokenUnverifiable
+	}
+	// never let the token choose a different algorithm than the key was made for
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return key.public, nil
+}
+
+// JWK is a public key in RFC 7517 format
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS returns the public half of every asymmetric key, active and retired.
+// The shared HMAC secret is never published.
+func (k *Keyring) JWKS() []JWK {
+	keys := []JWK{}
+	for _, key := range k.keys {
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "RSA",
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				Kid: key.ID,
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "OKP",
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				Kid: key.ID,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Kid < keys[j].Kid })
+	return keys
+}
@@ -1,14 +1,118 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
 
-// HashPassword hashes a plain-text password
+	"ecommerce-gin/internal/config"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashes are self-describing, so several formats can live side by side:
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>   (PHC string, base64 without padding)
+//	$2a$10$...                                    (bcrypt)
+//
+// New hashes use the configured hasher; NeedsRehash tells when a stored hash is
+// older than the current settings.
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+func currentArgon2Params() argon2Params {
+	return argon2Params{
+		memory:      uint32(config.Cfg.Argon2Memory),
+		iterations:  uint32(config.Cfg.Argon2Iterations),
+		parallelism: uint8(config.Cfg.Argon2Parallelism),
+	}
+}
+
+// HashPassword hashes a plain-text password with the configured hasher
 func HashPassword(pw string) (string, error) {
-	b, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.DefaultCost)
-	return string(b), err
+	if config.Cfg.PasswordHasher == "bcrypt" {
+		b, err := bcrypt.GenerateFromPassword([]byte(pw), bcryptCost())
+		return string(b), err
+	}
+
+	p := currentArgon2Params()
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(pw), salt, p.iterations, p.memory, p.parallelism, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
 }
 
 // CheckPassword compares hashed password and plain text
 func CheckPassword(hashed, pw string) bool {
+	if strings.HasPrefix(hashed, "$argon2id$") {
+		p, salt, key, err := parseArgon2Hash(hashed)
+		if err != nil {
+			return false
+		}
+		got := argon2.IDKey([]byte(pw), salt, p.iterations, p.memory, p.parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(got, key) == 1
+	}
 	return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(pw)) == nil
 }
+
+// NeedsRehash reports whether a stored hash was made with another hasher or weaker
+// settings than the current ones
+func NeedsRehash(hashed string) bool {
+	if config.Cfg.PasswordHasher == "bcrypt" {
+		cost, err := bcrypt.Cost([]byte(hashed))
+		return err != nil || cost < bcryptCost()
+	}
+
+	p, _, key, err := parseArgon2Hash(hashed)
+	return err != nil || p != currentArgon2Params() || len(key) != argon2KeyLen
+}
+
+func bcryptCost() int {
+	if c := config.Cfg.BcryptCost; c >= bcrypt.MinCost && c <= bcrypt.MaxCost {
+		return c
+	}
+	return bcrypt.DefaultCost
+}
+
+func parseArgon2Hash(hashed string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, fmt.Errorf("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return p, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, fmt.Errorf("invalid argon2 key")
+	}
+	return p, salt, key, nil
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// stripMarks splits letters from their accents and drops the accents ("é" -> "e")
+var stripMarks = transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)))
+
+// transliterations covers letters that have no plain ASCII base after stripMarks
+var transliterations = map[rune]string{
+	// Latin
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th", 'ł': "l", 'ı': "i", 'ŀ': "l", 'ħ': "h",
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l",
+	'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f",
+	'χ': "ch", 'ψ': "ps", 'ω': "o",
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ж': "zh", 'з': "z", 'и': "i",
+	'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f",
+	'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'є': "ye", 'ґ': "g",
+}
+
+// Slugify turns a name into a lowercase ASCII slug for URLs: accents are dropped,
+// Greek and Cyrillic are transliterated, and everything else that is not a
+// letter or digit collapses into single dashes ("Café Mug!" -> "cafe-mug",
+// "Men's T-Shirts" -> "men-s-t-shirts"). Scripts without a transliteration give
+// an empty slug.
+func Slugify(s string) string {
+	plain, _, err := transform.String(stripMarks, s)
+	if err != nil {
+		plain = s
+	}
+	plain = strings.ToLower(plain)
+
+	var b strings.Builder
+	dash := false
+	write := func(part string) {
+		if part == "" {
+			return
+		}
+		if dash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		b.WriteString(part)
+		dash = false
+	}
+	for _, r := range plain {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			write(string(r))
+		case transliterations[r] != "":
+			write(transliterations[r])
+		default:
+			if _, ok := transliterations[r]; !ok {
+				dash = true
+			}
+		}
+	}
+	return b.String()
+}
@@ -3,6 +3,8 @@ package utils
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"time"
 
 	"ecommerce-gin/internal/config"
@@ -10,18 +12,85 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
-// GenerateAccessToken creates a signed JWT with short expiry
-func GenerateAccessToken(userID uint, role string) (string, error) {
+// AccessClaims are the app specific claims of an access token
+type AccessClaims struct {
+	UserID    uint
+	Role      string
+	SessionID uint
+	MFA       bool // the session was established with a second factor
+
+	// impersonation: the staff member acting as UserID, and a shorter lifetime
+	ActorID uint
+	TTL     time.Duration
+}
+
+// GenerateAccessToken creates a signed JWT with short expiry, bound to the session it was issued for
+func GenerateAccessToken(ac AccessClaims) (string, error) {
+	ttl := time.Minute * time.Duration(config.Cfg.AccessTokenMinutes)
+	if ac.TTL > 0 && ac.TTL < ttl {
+		ttl = ac.TTL
+	}
+
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"sub":  userID,
-		"role": role,
-		"exp":  time.Now().Add(time.Minute * time.Duration(config.Cfg.AccessTokenMinutes)).Unix(),
-		"iat":  time.Now().Unix(),
-		"iss":  "ecommerce-gin",
+		"sub":    ac.UserID,
+		"role":   ac.Role,
+		"mfa":    ac.MFA,
+		"jti":    newTokenID(),
+		"exp":    now.Add(ttl).Unix(),
+		"iat":    now.Unix(),
+		"iat_ms": now.UnixMilli(), // revocation watermarks need more than whole seconds
+		"iss":    "ecommerce-gin",
 	}
+	if ac.SessionID != 0 {
+		claims["sid"] = ac.SessionID
+	}
+	// RFC 8693 actor claim
+	if ac.ActorID != 0 {
+		claims["act"] = map[string]interface{}{"sub": ac.ActorID}
+	}
+
+	return Keys.Sign(claims)
+}
+
+// ErrInvalidToken is returned for purpose tokens that fail verification
+var ErrInvalidToken = errors.New("invalid token")
+
+// GenerateSignedToken creates a signed, expiring token meant for a single purpose
+// (e.g. "verify_email"). The JWT middleware rejects anything carrying a purpose
+// claim, so these can never be used as access tokens.
+func GenerateSignedToken(purpose string, userID uint, extra map[string]interface{}, ttl time.Duration) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":     userID,
+		"purpose": purpose,
+		"exp":     time.Now().Add(ttl).Unix(),
+		"iat":     time.Now().Unix(),
+		"iss":     "ecommerce-gin",
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	return Keys.Sign(claims)
+}
+
+// ParseSignedToken verifies a token created by GenerateSignedToken for the same purpose
+func ParseSignedToken(purpose, tokenStr string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenStr, Keys.Keyfunc)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != purpose {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.Cfg.JWTSecret))
+// newTokenID returns a random identifier for the jti claim
+func newTokenID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
 }
 
 // GenerateRefreshToken creates a cryptographically random string (not JWT)
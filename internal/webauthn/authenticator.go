@@ -0,0 +1,93 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+)
+
+// SoftwareAuthenticator is an in-memory ES256 authenticator. It produces the
+// same payloads a browser would send, which makes the passkey endpoints usable
+// from scripts and tests without real hardware.
+type SoftwareAuthenticator struct {
+	RP   RelyingParty
+	keys map[string]*softwareCredential
+}
+
+type softwareCredential struct {
+	key        *ecdsa.PrivateKey
+	userHandle []byte
+	signCount  uint32
+}
+
+// NewSoftwareAuthenticator creates an authenticator bound to one relying party
+func NewSoftwareAuthenticator(rp RelyingParty) *SoftwareAuthenticator {
+	return &SoftwareAuthenticator{RP: rp, keys: map[string]*softwareCredential{}}
+}
+
+// Register creates a credential for the challenge and returns its id,
+// clientDataJSON and attestationObject
+func (a *SoftwareAuthenticator) Register(challenge, userHandle []byte) (credentialID, clientDataJSON, attestationObject []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	credentialID = make([]byte, 32)
+	if _, err := rand.Read(credentialID); err != nil {
+		return nil, nil, nil, err
+	}
+
+	pub := encodeCOSEKey(&key.PublicKey)
+	attested := make([]byte, 16, 18+len(credentialID)+len(pub)) // zero AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(credentialID)))
+	attested = append(attested, credentialID...)
+	attested = append(attested, pub...)
+
+	authData := a.authData(flagUserPresent|flagUserVerified|flagAttested, 0)
+	authData = append(authData, attested...)
+
+	attestationObject = encodeCBOR([]cborPair{
+		{"fmt", "none"},
+		{"attStmt", []cborPair{}},
+		{"authData", authData},
+	})
+
+	a.keys[string(credentialID)] = &softwareCredential{key: key, userHandle: userHandle}
+	return credentialID, a.clientData("webauthn.create", challenge), attestationObject, nil
+}
+
+// Assert signs the challenge with a registered credential and returns
+// clientDataJSON, authenticatorData, the signature and the user handle
+func (a *SoftwareAuthenticator) Assert(credentialID, challenge []byte) (clientDataJSON, authenticatorData, signature, userHandle []byte, err error) {
+	cred, ok := a.keys[string(credentialID)]
+	if !ok {
+		return nil, nil, nil, nil, errors.New("webauthn: unknown credential")
+	}
+	cred.signCount++
+
+	clientDataJSON = a.clientData("webauthn.get", challenge)
+	authenticatorData = a.authData(flagUserPresent|flagUserVerified, cred.signCount)
+
+	clientHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authenticatorData...), clientHash[:]...))
+	signature, err = ecdsa.SignASN1(rand.Reader, cred.key, digest[:])
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return clientDataJSON, authenticatorData, signature, cred.userHandle, nil
+}
+
+func (a *SoftwareAuthenticator) authData(flags byte, signCount uint32) []byte {
+	rpHash := sha256.Sum256([]byte(a.RP.ID))
+	out := append(rpHash[:], flags)
+	return binary.BigEndian.AppendUint32(out, signCount)
+}
+
+func (a *SoftwareAuthenticator) clientData(typ string, challenge []byte) []byte {
+	b, _ := json.Marshal(ClientData{Type: typ, Challenge: EncodeBase64(challenge), Origin: a.RP.Origin})
+	return b
+}
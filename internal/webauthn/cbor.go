@@ -0,0 +1,187 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Just enough CBOR (RFC 8949) for WebAuthn: attestation objects and COSE keys.
+// Decoded values are uint64/int64, []byte, string, []interface{},
+// map[interface{}]interface{} (integer keys as int64), bool or nil.
+// Floats, tags and indefinite lengths are rejected.
+
+var errCBOR = errors.New("webauthn: malformed cbor")
+
+const cborMaxDepth = 16
+
+// decodeCBOR decodes the first item in data and returns it with the remaining bytes
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeItem(data, 0)
+}
+
+func decodeItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > cborMaxDepth || len(data) == 0 {
+		return nil, nil, errCBOR
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	data = data[1:]
+
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22, 23:
+			return nil, data, nil
+		}
+		return nil, nil, fmt.Errorf("%w: unsupported simple value %d", errCBOR, info)
+	}
+
+	n, data, err := readArgument(info, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0:
+		return n, data, nil
+	case 1:
+		if n > math.MaxInt64 {
+			return nil, nil, errCBOR
+		}
+		return -1 - int64(n), data, nil
+	case 2, 3:
+		if uint64(len(data)) < n {
+			return nil, nil, errCBOR
+		}
+		b := append([]byte(nil), data[:n]...)
+		if major == 3 {
+			return string(b), data[n:], nil
+		}
+		return b, data[n:], nil
+	case 4:
+		if n > uint64(len(data)) {
+			return nil, nil, errCBOR
+		}
+		arr := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			var v interface{}
+			if v, data, err = decodeItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, data, nil
+	case 5:
+		if n > uint64(len(data)) {
+			return nil, nil, errCBOR
+		}
+		m := make(map[interface{}]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			var k, v interface{}
+			if k, data, err = decodeItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			if u, ok := k.(uint64); ok {
+				if u > math.MaxInt64 {
+					return nil, nil, errCBOR
+				}
+				k = int64(u)
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, nil, fmt.Errorf("%w: unsupported map key", errCBOR)
+			}
+			if v, data, err = decodeItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			m[k] = v
+		}
+		return m, data, nil
+	}
+	return nil, nil, fmt.Errorf("%w: unsupported major type %d", errCBOR, major)
+}
+
+func readArgument(info byte, data []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24 && len(data) >= 1:
+		return uint64(data[0]), data[1:], nil
+	case info == 25 && len(data) >= 2:
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26 && len(data) >= 4:
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case info == 27 && len(data) >= 8:
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	}
+	return 0, nil, errCBOR
+}
+
+// cborPair is one entry of an encoded map; maps are encoded in the given order
+type cborPair struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// encodeCBOR encodes int, int64, uint64, string, []byte, bool, []cborPair
+// (as a map) and []interface{} (as an array)
+func encodeCBOR(v interface{}) []byte {
+	switch x := v.(type) {
+	case int:
+		return encodeInt(int64(x))
+	case int64:
+		return encodeInt(x)
+	case uint64:
+		return encodeHead(0, x)
+	case bool:
+		if x {
+			return []byte{0xf5}
+		}
+		return []byte{0xf4}
+	case string:
+		return append(encodeHead(3, uint64(len(x))), x...)
+	case []byte:
+		return append(encodeHead(2, uint64(len(x))), x...)
+	case []interface{}:
+		out := encodeHead(4, uint64(len(x)))
+		for _, e := range x {
+			out = append(out, encodeCBOR(e)...)
+		}
+		return out
+	case []cborPair:
+		out := encodeHead(5, uint64(len(x)))
+		for _, p := range x {
+			out = append(out, encodeCBOR(p.Key)...)
+			out = append(out, encodeCBOR(p.Value)...)
+		}
+		return out
+	}
+	panic(fmt.Sprintf("webauthn: cannot encode %T as cbor", v))
+}
+
+func encodeInt(n int64) []byte {
+	if n < 0 {
+		return encodeHead(1, uint64(-1-n))
+	}
+	return encodeHead(0, uint64(n))
+}
+
+func encodeHead(major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return []byte{m | byte(n)}
+	case n <= math.MaxUint8:
+		return []byte{m | 24, byte(n)}
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16([]byte{m | 25}, uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32([]byte{m | 26}, uint32(n))
+	}
+	return binary.BigEndian.AppendUint64([]byte{m | 27}, n)
+}
@@ -0,0 +1,87 @@
+package webauthn
+
+// CredentialDescriptor identifies a credential in allow and exclude lists
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// CredentialParameter is a key type and algorithm the server accepts
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// CreationOptions is the publicKey argument of navigator.credentials.create,
+// with binary fields base64url encoded
+type CreationOptions struct {
+	Challenge string `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout"`
+	Attestation            string                 `json:"attestation"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		ResidentKey      string `json:"residentKey"`
+		UserVerification string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+}
+
+// RequestOptions is the publicKey argument of navigator.credentials.get
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int                    `json:"timeout"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// timeout in milliseconds suggested to the browser
+const defaultTimeout = 5 * 60 * 1000
+
+// CreationOptions builds registration options for a user; existing credential
+// ids are excluded so the same authenticator is not registered twice
+func (rp RelyingParty) CreationOptions(challenge, userHandle []byte, name, displayName string, exclude [][]byte) CreationOptions {
+	var o CreationOptions
+	o.Challenge = EncodeBase64(challenge)
+	o.RP.ID = rp.ID
+	o.RP.Name = rp.Name
+	o.User.ID = EncodeBase64(userHandle)
+	o.User.Name = name
+	o.User.DisplayName = displayName
+	o.PubKeyCredParams = []CredentialParameter{{Type: "public-key", Alg: AlgES256}}
+	o.Timeout = defaultTimeout
+	o.Attestation = "none"
+	o.ExcludeCredentials = descriptors(exclude)
+	o.AuthenticatorSelection.ResidentKey = "preferred"
+	o.AuthenticatorSelection.UserVerification = "preferred"
+	return o
+}
+
+// RequestOptions builds login options; an empty allow list lets the browser
+// offer any discoverable passkey for this site
+func (rp RelyingParty) RequestOptions(challenge []byte, allow [][]byte) RequestOptions {
+	return RequestOptions{
+		Challenge:        EncodeBase64(challenge),
+		RPID:             rp.ID,
+		Timeout:          defaultTimeout,
+		AllowCredentials: descriptors(allow),
+		UserVerification: "preferred",
+	}
+}
+
+func descriptors(ids [][]byte) []CredentialDescriptor {
+	out := make([]CredentialDescriptor, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, CredentialDescriptor{Type: "public-key", ID: EncodeBase64(id)})
+	}
+	return out
+}
@@ -0,0 +1,300 @@
+// Package webauthn implements the relying-party side of WebAuthn (passkeys):
+// registration with "none" attestation and assertion verification for ES256
+// credentials, which is what platform authenticators and security keys offer
+// by default.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// AlgES256 is the COSE identifier of ECDSA P-256 with SHA-256
+const AlgES256 = -7
+
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+var (
+	ErrInvalidClientData    = errors.New("webauthn: invalid client data")
+	ErrChallengeMismatch    = errors.New("webauthn: challenge mismatch")
+	ErrOriginMismatch       = errors.New("webauthn: origin mismatch")
+	ErrRPIDMismatch         = errors.New("webauthn: rp id mismatch")
+	ErrUserNotPresent       = errors.New("webauthn: user presence flag not set")
+	ErrUnsupportedKey       = errors.New("webauthn: unsupported credential public key")
+	ErrUnsupportedFormat    = errors.New("webauthn: unsupported attestation format")
+	ErrInvalidAuthData      = errors.New("webauthn: invalid authenticator data")
+	ErrInvalidSignature     = errors.New("webauthn: invalid signature")
+	ErrSignCountNotIncrease = errors.New("webauthn: signature counter did not increase")
+)
+
+// RelyingParty is the site credentials are scoped to
+type RelyingParty struct {
+	ID     string // effective domain, e.g. "shop.example.com"
+	Name   string
+	Origin string // e.g. "https://shop.example.com"
+}
+
+// Credential is a verified, newly registered credential
+type Credential struct {
+	ID           []byte
+	PublicKey    []byte // COSE_Key as sent by the authenticator
+	SignCount    uint32
+	AAGUID       []byte
+	UserVerified bool
+}
+
+// Assertion is the result of a verified login
+type Assertion struct {
+	SignCount    uint32
+	UserVerified bool
+}
+
+// ClientData is the subset of CollectedClientData the server checks
+type ClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// NewChallenge returns 32 random bytes
+func NewChallenge() ([]byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// EncodeBase64 encodes binary fields the way browsers expect them (base64url, no padding)
+func EncodeBase64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeBase64 accepts base64url with or without padding
+func DecodeBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// ParseClientData decodes clientDataJSON without verifying it, so the caller
+// can look up the challenge it was issued for
+func ParseClientData(clientDataJSON []byte) (*ClientData, error) {
+	var cd ClientData
+	if err := json.Unmarshal(clientDataJSON, &cd); err != nil {
+		return nil, ErrInvalidClientData
+	}
+	return &cd, nil
+}
+
+func (rp RelyingParty) verifyClientData(clientDataJSON []byte, typ string, challenge []byte) error {
+	cd, err := ParseClientData(clientDataJSON)
+	if err != nil {
+		return err
+	}
+	if cd.Type != typ {
+		return ErrInvalidClientData
+	}
+	got, err := DecodeBase64(cd.Challenge)
+	if err != nil || !bytes.Equal(got, challenge) {
+		return ErrChallengeMismatch
+	}
+	if cd.Origin != rp.Origin {
+		return ErrOriginMismatch
+	}
+	return nil
+}
+
+// VerifyRegistration checks the response to navigator.credentials.create
+// against the challenge that was issued for it
+func (rp RelyingParty) VerifyRegistration(challenge, clientDataJSON, attestationObject []byte) (*Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	obj, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := obj.(map[interface{}]interface{})
+	if !ok {
+		return nil, errCBOR
+	}
+	// we ask for no attestation; anything else would need a trust store to be meaningful
+	if f, _ := m["fmt"].(string); f != "none" {
+		return nil, ErrUnsupportedFormat
+	}
+	authData, ok := m["authData"].([]byte)
+	if !ok {
+		return nil, ErrInvalidAuthData
+	}
+
+	ad, err := rp.parseAuthData(authData)
+	if err != nil {
+		return nil, err
+	}
+	if ad.flags&flagAttested == 0 {
+		return nil, ErrInvalidAuthData
+	}
+	if _, err := parseCOSEKey(ad.publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:           ad.credentialID,
+		PublicKey:    ad.publicKey,
+		SignCount:    ad.signCount,
+		AAGUID:       ad.aaguid,
+		UserVerified: ad.flags&flagUserVerified != 0,
+	}, nil
+}
+
+// VerifyAssertion checks the response to navigator.credentials.get for a stored
+// credential. storedSignCount is the last counter seen; authenticators that do
+// not implement a counter always report zero.
+func (rp RelyingParty) VerifyAssertion(challenge, publicKey []byte, storedSignCount uint32, clientDataJSON, authenticatorData, signature []byte) (*Assertion, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return nil, err
+	}
+
+	ad, err := rp.parseAuthData(authenticatorData)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	clientHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authenticatorData...), clientHash[:]...))
+	if !ecdsa.VerifyASN1(key, digest[:], signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	// a counter that goes backwards points at a cloned authenticator
+	if (ad.signCount != 0 || storedSignCount != 0) && ad.signCount <= storedSignCount {
+		return nil, ErrSignCountNotIncrease
+	}
+
+	return &Assertion{
+		SignCount:    ad.signCount,
+		UserVerified: ad.flags&flagUserVerified != 0,
+	}, nil
+}
+
+type authData struct {
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+// parseAuthData decodes authenticator data and checks the rp id hash and user presence
+func (rp RelyingParty) parseAuthData(data []byte) (*authData, error) {
+	if len(data) < 37 {
+		return nil, ErrInvalidAuthData
+	}
+	rpHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(data[:32], rpHash[:]) {
+		return nil, ErrRPIDMismatch
+	}
+	ad := &authData{
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if ad.flags&flagUserPresent == 0 {
+		return nil, ErrUserNotPresent
+	}
+
+	if ad.flags&flagAttested != 0 {
+		rest := data[37:]
+		if len(rest) < 18 {
+			return nil, ErrInvalidAuthData
+		}
+		ad.aaguid = append([]byte(nil), rest[:16]...)
+		n := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if n == 0 || len(rest) < n {
+			return nil, ErrInvalidAuthData
+		}
+		ad.credentialID = append([]byte(nil), rest[:n]...)
+		rest = rest[n:]
+
+		// the key is followed by optional extensions; keep just its bytes
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, ErrInvalidAuthData
+		}
+		ad.publicKey = append([]byte(nil), rest[:len(rest)-len(after)]...)
+	}
+	return ad, nil
+}
+
+// COSE_Key labels (RFC 9052)
+const (
+	coseKty     = 1
+	coseAlg     = 3
+	coseCrv     = -1
+	coseX       = -2
+	coseY       = -3
+	coseKtyEC2  = 2
+	coseCrvP256 = 1
+)
+
+func parseCOSEKey(data []byte) (*ecdsa.PublicKey, error) {
+	v, _, err := decodeCBOR(data)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+	if coseInt(m[int64(coseKty)]) != coseKtyEC2 || coseInt(m[int64(coseAlg)]) != AlgES256 || coseInt(m[int64(coseCrv)]) != coseCrvP256 {
+		return nil, ErrUnsupportedKey
+	}
+	x, _ := m[int64(coseX)].([]byte)
+	y, _ := m[int64(coseY)].([]byte)
+	if len(x) != 32 || len(y) != 32 {
+		return nil, ErrUnsupportedKey
+	}
+
+	key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if !key.Curve.IsOnCurve(key.X, key.Y) {
+		return nil, ErrUnsupportedKey
+	}
+	return key, nil
+}
+
+func coseInt(v interface{}) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case uint64:
+		return int64(n)
+	}
+	return 0
+}
+
+func encodeCOSEKey(key *ecdsa.PublicKey) []byte {
+	return encodeCBOR([]cborPair{
+		{coseKty, coseKtyEC2},
+		{coseAlg, AlgES256},
+		{coseCrv, coseCrvP256},
+		{coseX, key.X.FillBytes(make([]byte, 32))},
+		{coseY, key.Y.FillBytes(make([]byte, 32))},
+	})
+}
@@ -0,0 +1,173 @@
+package webauthn
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var testRP = RelyingParty{ID: "shop.example.com", Name: "Shop", Origin: "https://shop.example.com"}
+
+func mustChallenge(t *testing.T) []byte {
+	t.Helper()
+	c, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// register creates a credential on auth and verifies it against testRP
+func register(t *testing.T, auth *SoftwareAuthenticator) (credentialID []byte, cred *Credential) {
+	t.Helper()
+	challenge := mustChallenge(t)
+	id, clientData, attestation, err := auth.Register(challenge, []byte("user-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err = testRP.VerifyRegistration(challenge, clientData, attestation)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	return id, cred
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	auth := NewSoftwareAuthenticator(testRP)
+	id, cred := register(t, auth)
+
+	if !bytes.Equal(cred.ID, id) {
+		t.Errorf("credential id = %x, want %x", cred.ID, id)
+	}
+	if cred.SignCount != 0 || !cred.UserVerified {
+		t.Errorf("credential = %+v, want sign count 0 and user verified", cred)
+	}
+
+	stored := cred.SignCount
+	for i := 1; i <= 2; i++ {
+		challenge := mustChallenge(t)
+		clientData, authData, sig, userHandle, err := auth.Assert(id, challenge)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(userHandle) != "user-1" {
+			t.Errorf("user handle = %q, want user-1", userHandle)
+		}
+		a, err := testRP.VerifyAssertion(challenge, cred.PublicKey, stored, clientData, authData, sig)
+		if err != nil {
+			t.Fatalf("assertion %d: %v", i, err)
+		}
+		if a.SignCount != uint32(i) {
+			t.Errorf("assertion %d: sign count = %d, want %d", i, a.SignCount, i)
+		}
+		stored = a.SignCount
+	}
+}
+
+func TestVerifyRegistrationRejects(t *testing.T) {
+	wrongOrigin := testRP
+	wrongOrigin.Origin = "https://evil.example.com"
+	wrongRPID := testRP
+	wrongRPID.ID = "evil.example.com"
+
+	tests := []struct {
+		name     string
+		authRP   RelyingParty
+		otherChl bool
+		want     error
+	}{
+		{"wrong origin", wrongOrigin, false, ErrOriginMismatch},
+		{"wrong challenge", testRP, true, ErrChallengeMismatch},
+		{"wrong rp id hash", wrongRPID, false, ErrRPIDMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge := mustChallenge(t)
+			_, clientData, attestation, err := NewSoftwareAuthenticator(tt.authRP).Register(challenge, []byte("user-1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.otherChl {
+				challenge = mustChallenge(t)
+			}
+			if _, err := testRP.VerifyRegistration(challenge, clientData, attestation); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionRejects(t *testing.T) {
+	auth := NewSoftwareAuthenticator(testRP)
+	id, cred := register(t, auth)
+
+	// assertions made with the same key but presented to the wrong origin or rp id
+	foreign := func(rp RelyingParty) *SoftwareAuthenticator {
+		a := NewSoftwareAuthenticator(rp)
+		a.keys = auth.keys
+		return a
+	}
+	wrongOrigin := testRP
+	wrongOrigin.Origin = "https://evil.example.com"
+	wrongRPID := testRP
+	wrongRPID.ID = "evil.example.com"
+
+	tests := []struct {
+		name   string
+		auth   *SoftwareAuthenticator
+		mutate func(challenge, sig []byte) (verifyChallenge, signature []byte)
+		stored uint32
+		want   error
+	}{
+		{"wrong origin", foreign(wrongOrigin), nil, 0, ErrOriginMismatch},
+		{"wrong rp id hash", foreign(wrongRPID), nil, 0, ErrRPIDMismatch},
+		{"wrong challenge", auth, func(_, sig []byte) ([]byte, []byte) {
+			return mustChallenge(t), sig
+		}, 0, ErrChallengeMismatch},
+		{"bad signature", auth, func(challenge, sig []byte) ([]byte, []byte) {
+			bad := append([]byte(nil), sig...)
+			bad[len(bad)-1] ^= 0xff
+			return challenge, bad
+		}, 0, ErrInvalidSignature},
+		// the authenticator counts 1, 2, 3...; a stored count this high means a clone
+		{"sign count not increasing", auth, nil, 1000, ErrSignCountNotIncrease},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge := mustChallenge(t)
+			clientData, authData, sig, _, err := tt.auth.Assert(id, challenge)
+			if err != nil {
+				t.Fatal(err)
+			}
+			verifyChallenge := challenge
+			if tt.mutate != nil {
+				verifyChallenge, sig = tt.mutate(challenge, sig)
+			}
+			_, err = testRP.VerifyAssertion(verifyChallenge, cred.PublicKey, tt.stored, clientData, authData, sig)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionRejectsReplay(t *testing.T) {
+	auth := NewSoftwareAuthenticator(testRP)
+	id, cred := register(t, auth)
+
+	challenge := mustChallenge(t)
+	clientData, authData, sig, _, err := auth.Assert(id, challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := testRP.VerifyAssertion(challenge, cred.PublicKey, cred.SignCount, clientData, authData, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same response again, checked against the counter the first one stored
+	_, err = testRP.VerifyAssertion(challenge, cred.PublicKey, a.SignCount, clientData, authData, sig)
+	if !errors.Is(err, ErrSignCountNotIncrease) {
+		t.Errorf("err = %v, want %v", err, ErrSignCountNotIncrease)
+	}
+}